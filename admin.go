@@ -0,0 +1,483 @@
+package core
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/tuannguyensn2001/aurora-go/auroratype"
+	"github.com/tuannguyensn2001/aurora-go/core/evaluator"
+	yamlv2 "gopkg.in/yaml.v2"
+	"gopkg.in/yaml.v3"
+)
+
+// ErrUnauthorized is returned by AdminBearerToken for requests without the
+// expected token.
+var ErrUnauthorized = errors.New("aurora: unauthorized")
+
+// AdminOptions configures the handler returned by NewAdminHandler.
+type AdminOptions struct {
+	// Prefix is the path the handler is mounted at. Defaults to
+	// "/debug/aurora".
+	Prefix string
+	// Auth is called for every request. A request it returns an error for
+	// is rejected with 401 Unauthorized. Without Auth every request is
+	// served, so the handler must not be exposed publicly.
+	Auth func(r *http.Request) error
+}
+
+// AdminBearerToken returns an AdminOptions.Auth function accepting requests
+// with an "Authorization: Bearer <token>" header.
+func AdminBearerToken(token string) func(r *http.Request) error {
+	return func(r *http.Request) error {
+		presented, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(presented), []byte(token)) != 1 {
+			return ErrUnauthorized
+		}
+		return nil
+	}
+}
+
+// NewAdminHandler returns a handler to inspect client at runtime, to be
+// mounted at opts.Prefix:
+//
+//	mux.Handle("/debug/aurora/", aurora.NewAdminHandler(client, aurora.AdminOptions{}))
+//
+// It serves an HTML overview at the prefix and JSON at
+//
+//	/status              sync status and snapshot version
+//	/parameters          parameter definitions
+//	/parameters/{name}   one parameter definition
+//	/experiments         experiment definitions
+//	/operators           registered operators
+//	/evaluate            evaluation of a parameter for attributes
+//
+// /evaluate takes the parameter and attributes, a JSON object, as query
+// parameters, or as a JSON body {"parameter": ..., "attributes": {...}}
+// when posted. Evaluations do not emit exposures. Values of encrypted
+// parameters are redacted everywhere.
+func NewAdminHandler(client *Client, opts AdminOptions) http.Handler {
+	prefix := strings.TrimSuffix(opts.Prefix, "/")
+	if prefix == "" {
+		prefix = "/debug/aurora"
+	}
+	a := &admin{client: client, prefix: prefix}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+prefix+"/{$}", a.serveIndex)
+	mux.HandleFunc("GET "+prefix+"/status", a.serveStatus)
+	mux.HandleFunc("GET "+prefix+"/parameters", a.serveParameters)
+	mux.HandleFunc("GET "+prefix+"/parameters/{name}", a.serveParameter)
+	mux.HandleFunc("GET "+prefix+"/experiments", a.serveExperiments)
+	mux.HandleFunc("GET "+prefix+"/operators", a.serveOperators)
+	mux.HandleFunc("GET "+prefix+"/evaluate", a.serveEvaluate)
+	mux.HandleFunc("POST "+prefix+"/evaluate", a.serveEvaluate)
+
+	if opts.Auth == nil {
+		return mux
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := opts.Auth(r); err != nil {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+type admin struct {
+	client *Client
+	prefix string
+}
+
+type adminStatus struct {
+	Ready               bool          `json:"ready"`
+	Stale               bool          `json:"stale"`
+	Closed              bool          `json:"closed"`
+	Version             string        `json:"version"`
+	Environment         string        `json:"environment,omitempty"`
+	Source              string        `json:"source,omitempty"`
+	FetchedAt           time.Time     `json:"fetchedAt,omitzero"`
+	Parameters          int           `json:"parameters"`
+	Experiments         int           `json:"experiments"`
+	LastSuccess         time.Time     `json:"lastSuccess,omitzero"`
+	LastFailure         time.Time     `json:"lastFailure,omitzero"`
+	LastError           string        `json:"lastError,omitempty"`
+	ConsecutiveFailures int           `json:"consecutiveFailures"`
+	LastSync            adminSyncInfo `json:"lastSync"`
+}
+
+type adminSyncInfo struct {
+	Time        time.Time `json:"time,omitzero"`
+	Version     string    `json:"version,omitempty"`
+	Error       string    `json:"error,omitempty"`
+	Rejected    bool      `json:"rejected"`
+	NotModified bool      `json:"notModified"`
+}
+
+type adminParameter struct {
+	Definition any    `json:"definition"`
+	Source     string `json:"source,omitempty"`
+	Encrypted  bool   `json:"encrypted,omitempty"`
+}
+
+type adminOperator struct {
+	Name string `json:"name"`
+	// Builtin operators are available to experiment constraints as well.
+	Builtin bool `json:"builtin"`
+}
+
+type adminEvaluation struct {
+	Parameter    string             `json:"parameter"`
+	Value        any                `json:"value"`
+	Matched      bool               `json:"matched"`
+	Reason       Reason             `json:"reason"`
+	ExperimentID string             `json:"experimentId,omitempty"`
+	VariantKey   string             `json:"variantKey,omitempty"`
+	Weights      map[string]float64 `json:"weights,omitempty"`
+	Source       string             `json:"source,omitempty"`
+	Encrypted    bool               `json:"encrypted,omitempty"`
+}
+
+func (a *admin) status(r *http.Request) adminStatus {
+	status := a.client.Status()
+	sync := a.client.LastSync()
+	s := adminStatus{
+		Ready:               status.Ready,
+		Stale:               status.Stale,
+		Closed:              status.Closed,
+		Version:             status.Version,
+		Environment:         a.client.storage.environment,
+		LastSuccess:         status.LastSuccess,
+		LastFailure:         status.LastFailure,
+		ConsecutiveFailures: status.ConsecutiveFailures,
+		LastSync: adminSyncInfo{
+			Time:        sync.Time,
+			Version:     sync.Version,
+			Rejected:    sync.Rejected,
+			NotModified: sync.NotModified,
+		},
+	}
+	if status.LastError != nil {
+		s.LastError = status.LastError.Error()
+	}
+	if sync.Err != nil {
+		s.LastSync.Error = sync.Err.Error()
+	}
+	if snapshot := a.snapshot(r); snapshot != nil {
+		s.Source = snapshot.Source
+		s.FetchedAt = snapshot.FetchedAt
+		s.Parameters = len(snapshot.Parameters)
+		s.Experiments = len(snapshot.Experiments)
+	}
+	return s
+}
+
+func (a *admin) snapshot(r *http.Request) *auroratype.Snapshot {
+	snapshot, err := a.client.Snapshot(r.Context())
+	if err != nil {
+		return nil
+	}
+	return snapshot
+}
+
+func (a *admin) serveStatus(w http.ResponseWriter, r *http.Request) {
+	writeAdminJSON(w, http.StatusOK, a.status(r))
+}
+
+func (a *admin) serveParameters(w http.ResponseWriter, r *http.Request) {
+	snapshot := a.snapshot(r)
+	if snapshot == nil {
+		http.Error(w, "no configuration loaded", http.StatusServiceUnavailable)
+		return
+	}
+	parameters := make(map[string]adminParameter, len(snapshot.Parameters))
+	for name := range snapshot.Parameters {
+		param, err := adminParameterOf(snapshot, name)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		parameters[name] = param
+	}
+	writeAdminJSON(w, http.StatusOK, map[string]any{"version": snapshot.Version, "parameters": parameters})
+}
+
+func (a *admin) serveParameter(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	snapshot := a.snapshot(r)
+	if _, ok := snapshot.Parameter(name); !ok {
+		http.Error(w, fmt.Sprintf("parameter %q not found", name), http.StatusNotFound)
+		return
+	}
+	param, err := adminParameterOf(snapshot, name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeAdminJSON(w, http.StatusOK, param)
+}
+
+func (a *admin) serveExperiments(w http.ResponseWriter, r *http.Request) {
+	snapshot := a.snapshot(r)
+	if snapshot == nil {
+		http.Error(w, "no configuration loaded", http.StatusServiceUnavailable)
+		return
+	}
+	experiments := make([]any, 0, len(snapshot.Experiments))
+	for _, exp := range snapshot.Experiments {
+		definition, err := definitionOf(redactExperiment(exp, snapshot.Encrypted))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		experiments = append(experiments, definition)
+	}
+	writeAdminJSON(w, http.StatusOK, map[string]any{"version": snapshot.Version, "experiments": experiments})
+}
+
+func (a *admin) serveOperators(w http.ResponseWriter, r *http.Request) {
+	var operators []adminOperator
+	for _, name := range a.client.engine.operatorNames() {
+		_, builtin := evaluator.DefaultOperators[evaluator.Operator(name)]
+		operators = append(operators, adminOperator{Name: name, Builtin: builtin})
+	}
+	writeAdminJSON(w, http.StatusOK, map[string]any{"operators": operators})
+}
+
+func (a *admin) serveEvaluate(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Parameter  string          `json:"parameter"`
+		Attributes json.RawMessage `json:"attributes"`
+	}
+	if r.Method == http.MethodPost {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	} else {
+		req.Parameter = r.URL.Query().Get("parameter")
+		if attributes := strings.TrimSpace(r.URL.Query().Get("attributes")); attributes != "" {
+			req.Attributes = json.RawMessage(attributes)
+		}
+	}
+	if req.Parameter == "" {
+		http.Error(w, "parameter is required", http.StatusBadRequest)
+		return
+	}
+	attr, err := parseAttributes(req.Attributes)
+	if err != nil {
+		http.Error(w, "attributes: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	value := a.client.GetParameter(r.Context(), req.Parameter, attr, withoutTracking())
+	details := value.Details()
+	evaluation := adminEvaluation{
+		Parameter:    req.Parameter,
+		Value:        plainValue(value.Value()),
+		Matched:      value.Matched(),
+		Reason:       details.Reason,
+		ExperimentID: details.ExperimentID,
+		VariantKey:   details.VariantKey,
+		Weights:      details.Weights,
+		Source:       details.Source,
+		Encrypted:    details.Encrypted,
+	}
+	if details.Encrypted {
+		evaluation.Value = redacted
+	}
+	writeAdminJSON(w, http.StatusOK, evaluation)
+}
+
+// parseAttributes decodes a JSON object of attributes. Integral numbers
+// become ints, like the attributes a Go service sets.
+func parseAttributes(data json.RawMessage) (*attribute, error) {
+	attr := NewAttribute()
+	if len(data) == 0 || string(data) == "null" {
+		return attr, nil
+	}
+	decoder := json.NewDecoder(strings.NewReader(string(data)))
+	decoder.UseNumber()
+	var values map[string]any
+	if err := decoder.Decode(&values); err != nil {
+		return nil, err
+	}
+	for k, v := range values {
+		if n, ok := v.(json.Number); ok {
+			if i, err := n.Int64(); err == nil {
+				v = int(i)
+			} else {
+				v, _ = n.Float64()
+			}
+		}
+		attr.Set(k, v)
+	}
+	return attr, nil
+}
+
+func adminParameterOf(snapshot *auroratype.Snapshot, name string) (adminParameter, error) {
+	param := snapshot.Parameters[name]
+	encrypted := snapshot.Encrypted[name]
+	if encrypted {
+		param = redactParameter(param)
+	}
+	definition, err := definitionOf(param)
+	if err != nil {
+		return adminParameter{}, fmt.Errorf("parameter %q: %w", name, err)
+	}
+	return adminParameter{Definition: definition, Source: snapshot.Sources[name], Encrypted: encrypted}, nil
+}
+
+// redactParameter returns param with every value that may have been
+// encrypted replaced.
+func redactParameter(param auroratype.Parameter) auroratype.Parameter {
+	param.DefaultValue = redacted
+	param.Rules = redactRules(param.Rules)
+	if param.Environments != nil {
+		environments := make(map[string]auroratype.ParameterOverlay, len(param.Environments))
+		for env, overlay := range param.Environments {
+			if overlay.DefaultValue != nil {
+				overlay.DefaultValue = redacted
+			}
+			if overlay.Rules != nil {
+				rules := redactRules(*overlay.Rules)
+				overlay.Rules = &rules
+			}
+			environments[env] = overlay
+		}
+		param.Environments = environments
+	}
+	return param
+}
+
+func redactRules(rules []auroratype.Rule) []auroratype.Rule {
+	if rules == nil {
+		return nil
+	}
+	copied := append([]auroratype.Rule(nil), rules...)
+	for i := range copied {
+		copied[i].RolloutValue = redacted
+	}
+	return copied
+}
+
+// redactExperiment returns exp with the variant values of encrypted
+// parameters replaced.
+func redactExperiment(exp auroratype.Experiment, encrypted map[string]bool) auroratype.Experiment {
+	if len(encrypted) == 0 {
+		return exp
+	}
+	variants := make([]auroratype.Variant, len(exp.Variants))
+	for i, variant := range exp.Variants {
+		if variant.Values != nil {
+			values := make(map[string]interface{}, len(variant.Values))
+			for name, v := range variant.Values {
+				if encrypted[name] {
+					v = redacted
+				}
+				values[name] = v
+			}
+			variant.Values = values
+		}
+		variants[i] = variant
+	}
+	exp.Variants = variants
+	return exp
+}
+
+// definitionOf returns v as a tree keyed like the configuration files,
+// ready to be encoded as JSON.
+func definitionOf(v any) (any, error) {
+	data, err := yamlv2.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var tree any
+	if err := yaml.Unmarshal(data, &tree); err != nil {
+		return nil, err
+	}
+	return plainValue(tree), nil
+}
+
+// plainValue converts maps with non-string keys, as decoded from YAML, so
+// that v can be encoded as JSON.
+func plainValue(v any) any {
+	switch v := v.(type) {
+	case map[any]any:
+		values := make(map[string]any, len(v))
+		for k, e := range v {
+			values[fmt.Sprint(k)] = plainValue(e)
+		}
+		return values
+	case map[string]any:
+		values := make(map[string]any, len(v))
+		for k, e := range v {
+			values[k] = plainValue(e)
+		}
+		return values
+	case []any:
+		values := make([]any, len(v))
+		for i, e := range v {
+			values[i] = plainValue(e)
+		}
+		return values
+	}
+	return v
+}
+
+func writeAdminJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(v)
+}
+
+func (a *admin) serveIndex(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	adminIndex.Execute(w, map[string]any{
+		"Prefix":    a.prefix,
+		"Status":    a.status(r),
+		"Operators": a.client.engine.operatorNames(),
+	})
+}
+
+var adminIndex = template.Must(template.New("index").Parse(`<!DOCTYPE html>
+<html>
+<head><title>Aurora</title></head>
+<body>
+<h1>Aurora</h1>
+{{with .Status}}
+<table>
+<tr><th align="left">Ready</th><td>{{.Ready}}{{if .Stale}} (stale){{end}}{{if .Closed}} (closed){{end}}</td></tr>
+<tr><th align="left">Version</th><td>{{.Version}}</td></tr>
+{{if .Environment}}<tr><th align="left">Environment</th><td>{{.Environment}}</td></tr>{{end}}
+{{if .Source}}<tr><th align="left">Source</th><td>{{.Source}}</td></tr>{{end}}
+<tr><th align="left">Last success</th><td>{{if not .LastSuccess.IsZero}}{{.LastSuccess.Format "2006-01-02T15:04:05Z07:00"}}{{end}}</td></tr>
+<tr><th align="left">Last error</th><td>{{.LastError}}{{if .ConsecutiveFailures}} ({{.ConsecutiveFailures}} consecutive failures){{end}}</td></tr>
+<tr><th align="left">Last sync</th><td>{{if not .LastSync.Time.IsZero}}{{.LastSync.Time.Format "2006-01-02T15:04:05Z07:00"}}{{end}}{{if .LastSync.Rejected}} rejected: {{.LastSync.Error}}{{end}}</td></tr>
+</table>
+<ul>
+<li><a href="{{$.Prefix}}/status">status</a></li>
+<li><a href="{{$.Prefix}}/parameters">{{.Parameters}} parameters</a></li>
+<li><a href="{{$.Prefix}}/experiments">{{.Experiments}} experiments</a></li>
+<li><a href="{{$.Prefix}}/operators">operators</a>: {{range $i, $op := $.Operators}}{{if $i}}, {{end}}{{$op}}{{end}}</li>
+</ul>
+{{end}}
+<h2>Evaluate</h2>
+<form action="{{.Prefix}}/evaluate" method="get">
+<p><label>Parameter <input name="parameter" required></label></p>
+<p><label>Attributes<br><textarea name="attributes" rows="6" cols="60">{"user_id": "123"}</textarea></label></p>
+<p><button type="submit">Evaluate</button></p>
+</form>
+</body>
+</html>
+`))
@@ -0,0 +1,158 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tuannguyensn2001/aurora-go/auroratype"
+	mocks "github.com/tuannguyensn2001/aurora-go/mocks"
+)
+
+func newAdminClient(t *testing.T) *Client {
+	t.Helper()
+	ctx := context.Background()
+	dir := t.TempDir()
+	key := writeKey(t, dir, "main")
+	secret, err := Encrypt(key, "main", "https://partner.example.com")
+	require.NoError(t, err)
+
+	mockFetcher := new(mocks.MockFetcher)
+	mockFetcher.On("IsStatic").Return(true)
+	mockFetcher.On("Fetch", ctx).Return(map[string]auroratype.Parameter{
+		"limit": {
+			DefaultValue: 10,
+			Rules: []auroratype.Rule{{
+				RolloutValue: 100,
+				Constraints:  []auroratype.Constraint{{Field: "age", Operator: "greaterThan", Value: 18}},
+			}},
+		},
+		"partnerURL": {DefaultValue: map[string]any{auroratype.EncryptedValueKey: secret}},
+	}, nil)
+	mockFetcher.On("FetchExperiments", ctx).Return([]auroratype.Experiment{{
+		ID:             "exp-1",
+		Name:           "Partner",
+		Parameters:     []string{"partnerURL"},
+		Status:         auroratype.StatusRunning,
+		HashAttribute:  "user_id",
+		PopulationSize: 100,
+		Variants: []auroratype.Variant{{
+			Key:     "control",
+			Rollout: 100,
+			Values:  map[string]interface{}{"partnerURL": "https://other.example.com"},
+		}},
+	}}, nil)
+
+	client := NewClient(NewFetcherStorage(mockFetcher, WithKeyProvider(NewFileKeyProvider(dir))), ClientOptions{})
+	client.RegisterOperator("startsWith", func(a, b any) bool { return false })
+	require.NoError(t, client.Start(ctx))
+	return client
+}
+
+func getAdmin(t *testing.T, handler http.Handler, path string, v any) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	if v != nil {
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), v))
+	}
+	return rec
+}
+
+func TestAdminHandler(t *testing.T) {
+	client := newAdminClient(t)
+	handler := NewAdminHandler(client, AdminOptions{})
+
+	var status adminStatus
+	getAdmin(t, handler, "/debug/aurora/status", &status)
+	assert.True(t, status.Ready)
+	assert.NotEmpty(t, status.Version)
+	assert.Equal(t, 2, status.Parameters)
+	assert.Equal(t, 1, status.Experiments)
+
+	var parameters struct {
+		Version    string                    `json:"version"`
+		Parameters map[string]adminParameter `json:"parameters"`
+	}
+	getAdmin(t, handler, "/debug/aurora/parameters", &parameters)
+	assert.Equal(t, status.Version, parameters.Version)
+	limit := parameters.Parameters["limit"].Definition.(map[string]any)
+	assert.Equal(t, float64(10), limit["defaultValue"])
+	assert.Equal(t, "greaterThan", limit["rules"].([]any)[0].(map[string]any)["constraints"].([]any)[0].(map[string]any)["operator"])
+	partner := parameters.Parameters["partnerURL"]
+	assert.True(t, partner.Encrypted)
+	assert.Equal(t, redacted, partner.Definition.(map[string]any)["defaultValue"])
+
+	var param adminParameter
+	getAdmin(t, handler, "/debug/aurora/parameters/limit", &param)
+	assert.Equal(t, float64(10), param.Definition.(map[string]any)["defaultValue"])
+	assert.Equal(t, http.StatusNotFound, getAdmin(t, handler, "/debug/aurora/parameters/missing", nil).Code)
+
+	var experiments struct {
+		Experiments []map[string]any `json:"experiments"`
+	}
+	getAdmin(t, handler, "/debug/aurora/experiments", &experiments)
+	require.Len(t, experiments.Experiments, 1)
+	variant := experiments.Experiments[0]["variants"].([]any)[0].(map[string]any)
+	assert.Equal(t, redacted, variant["values"].(map[string]any)["partnerURL"])
+
+	var operators struct {
+		Operators []adminOperator `json:"operators"`
+	}
+	getAdmin(t, handler, "/debug/aurora/operators", &operators)
+	assert.Contains(t, operators.Operators, adminOperator{Name: "startsWith", Builtin: false})
+	assert.Contains(t, operators.Operators, adminOperator{Name: "equal", Builtin: true})
+
+	index := getAdmin(t, handler, "/debug/aurora/", nil)
+	assert.Equal(t, http.StatusOK, index.Code)
+	assert.Contains(t, index.Body.String(), status.Version)
+	assert.Contains(t, index.Body.String(), `action="/debug/aurora/evaluate"`)
+}
+
+func TestAdminEvaluate(t *testing.T) {
+	client := newAdminClient(t)
+	var events bytes.Buffer
+	client.sink = NewJSONLSink(&events)
+	handler := NewAdminHandler(client, AdminOptions{Prefix: "/admin/", Auth: AdminBearerToken("secret")})
+
+	rec := getAdmin(t, handler, "/admin/evaluate?parameter=limit", nil)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	query := url.Values{"parameter": {"limit"}, "attributes": {`{"age": 21}`}}
+	req := httptest.NewRequest(http.MethodGet, "/admin/evaluate?"+query.Encode(), nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var evaluation adminEvaluation
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &evaluation))
+	assert.Equal(t, float64(100), evaluation.Value)
+	assert.Equal(t, ReasonRuleMatch, evaluation.Reason)
+
+	req = httptest.NewRequest(http.MethodPost, "/admin/evaluate", strings.NewReader(`{"parameter": "partnerURL", "attributes": {"user_id": 7}}`))
+	req.Header.Set("Authorization", "Bearer secret")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	evaluation = adminEvaluation{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &evaluation))
+	assert.Equal(t, redacted, evaluation.Value)
+	assert.Equal(t, ReasonExperiment, evaluation.Reason)
+	assert.Equal(t, "control", evaluation.VariantKey)
+	assert.Empty(t, events.String(), "admin evaluations must not emit exposures")
+
+	req = httptest.NewRequest(http.MethodPost, "/admin/evaluate", strings.NewReader(`{"attributes": {}}`))
+	req.Header.Set("Authorization", "Bearer secret")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
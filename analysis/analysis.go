@@ -0,0 +1,316 @@
+package analysis
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"time"
+
+	core "github.com/tuannguyensn2001/aurora-go"
+	"github.com/tuannguyensn2001/aurora-go/auroratype"
+)
+
+// DefaultSRMThreshold is the p-value below which the sample ratio check
+// reports a mismatch.
+const DefaultSRMThreshold = 0.001
+
+type Options struct {
+	// EventName selects the conversion events to analyze.
+	EventName string
+	// Control is the variant every other variant is compared against.
+	// Defaults to the first variant of the experiment.
+	Control string
+	// Confidence is the confidence level for intervals. Defaults to 0.95.
+	Confidence float64
+	// SRMThreshold overrides DefaultSRMThreshold.
+	SRMThreshold float64
+}
+
+type Interval struct {
+	Lower float64
+	Upper float64
+}
+
+type VariantResult struct {
+	Key         string
+	Exposures   int
+	Conversions int
+
+	ConversionRate   float64
+	ConversionRateCI Interval
+	// ConversionPValue is the two-proportion z-test p-value against the
+	// control. It is NaN for the control itself.
+	ConversionPValue float64
+
+	// Mean is the average summed conversion value per exposed unit.
+	Mean     float64
+	Variance float64
+	MeanCI   Interval
+	// MeanPValue is the Welch t-test p-value against the control. It is NaN
+	// for the control itself.
+	MeanPValue float64
+}
+
+type SampleRatioResult struct {
+	Observed  map[string]int
+	Expected  map[string]float64
+	ChiSquare float64
+	PValue    float64
+	Mismatch  bool
+	// Skipped is set for bandit experiments, whose traffic follows adaptive
+	// weights rather than the configured rollouts.
+	Skipped bool
+}
+
+type Report struct {
+	ExperimentID string
+	EventName    string
+	Control      string
+	Variants     []VariantResult
+	SampleRatio  SampleRatioResult
+}
+
+type unit struct {
+	variant   string
+	exposedAt time.Time
+	converted bool
+	value     float64
+}
+
+// Analyze reads exposure and conversion events in JSONL form from sources and
+// computes per-variant results for exp. Units are identified by the value of
+// the experiment's hash attribute; each unit is attributed to the first
+// variant it was exposed to and only conversions at or after that exposure
+// are counted.
+func Analyze(exp auroratype.Experiment, opts Options, sources ...io.Reader) (*Report, error) {
+	if len(exp.Variants) == 0 {
+		return nil, errors.New("experiment has no variants")
+	}
+	if exp.HashAttribute == "" {
+		return nil, errors.New("experiment has no hash attribute")
+	}
+
+	confidence := opts.Confidence
+	if confidence <= 0 || confidence >= 1 {
+		confidence = 0.95
+	}
+	srmThreshold := opts.SRMThreshold
+	if srmThreshold <= 0 {
+		srmThreshold = DefaultSRMThreshold
+	}
+	control := opts.Control
+	if control == "" {
+		control = exp.Variants[0].Key
+	}
+
+	known := make(map[string]bool, len(exp.Variants))
+	for _, v := range exp.Variants {
+		known[v.Key] = true
+	}
+	if !known[control] {
+		return nil, fmt.Errorf("control variant %q not found in experiment", control)
+	}
+
+	var exposures, conversions []core.Event
+	for _, src := range sources {
+		scanner := bufio.NewScanner(src)
+		scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+		line := 0
+		for scanner.Scan() {
+			line++
+			if len(scanner.Bytes()) == 0 {
+				continue
+			}
+			var event core.Event
+			if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+			switch event.Type {
+			case core.EventExposure:
+				// forced assignments are not randomized and would bias results
+				if event.Reason == core.ReasonForced {
+					continue
+				}
+				if event.ExperimentID == exp.ID && known[event.VariantKey] {
+					exposures = append(exposures, event)
+				}
+			case core.EventConversion:
+				if opts.EventName == "" || event.Name == opts.EventName {
+					conversions = append(conversions, event)
+				}
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	sort.SliceStable(exposures, func(i, j int) bool {
+		return exposures[i].Timestamp.Before(exposures[j].Timestamp)
+	})
+
+	units := make(map[string]*unit)
+	for _, event := range exposures {
+		id, ok := unitID(event, exp.HashAttribute)
+		if !ok {
+			continue
+		}
+		if _, seen := units[id]; seen {
+			continue
+		}
+		units[id] = &unit{variant: event.VariantKey, exposedAt: event.Timestamp}
+	}
+
+	for _, event := range conversions {
+		id, ok := unitID(event, exp.HashAttribute)
+		if !ok {
+			continue
+		}
+		u, exposed := units[id]
+		if !exposed || event.Timestamp.Before(u.exposedAt) {
+			continue
+		}
+		u.converted = true
+		u.value += event.Value
+	}
+
+	values := make(map[string][]float64, len(exp.Variants))
+	converted := make(map[string]int, len(exp.Variants))
+	for _, u := range units {
+		values[u.variant] = append(values[u.variant], u.value)
+		if u.converted {
+			converted[u.variant]++
+		}
+	}
+
+	z := normalQuantile(1 - (1-confidence)/2)
+	report := &Report{
+		ExperimentID: exp.ID,
+		EventName:    opts.EventName,
+		Control:      control,
+	}
+
+	for _, v := range exp.Variants {
+		n := len(values[v.Key])
+		result := VariantResult{
+			Key:              v.Key,
+			Exposures:        n,
+			Conversions:      converted[v.Key],
+			ConversionPValue: math.NaN(),
+			MeanPValue:       math.NaN(),
+		}
+		if n > 0 {
+			rate := float64(result.Conversions) / float64(n)
+			margin := z * math.Sqrt(rate*(1-rate)/float64(n))
+			result.ConversionRate = rate
+			result.ConversionRateCI = Interval{Lower: math.Max(0, rate-margin), Upper: math.Min(1, rate+margin)}
+
+			result.Mean, result.Variance = meanVariance(values[v.Key])
+			margin = z * math.Sqrt(result.Variance/float64(n))
+			result.MeanCI = Interval{Lower: result.Mean - margin, Upper: result.Mean + margin}
+		}
+		report.Variants = append(report.Variants, result)
+	}
+
+	var base VariantResult
+	for _, r := range report.Variants {
+		if r.Key == control {
+			base = r
+		}
+	}
+	for i, r := range report.Variants {
+		if r.Key == control {
+			continue
+		}
+		report.Variants[i].ConversionPValue = twoProportionZTest(r.Conversions, r.Exposures, base.Conversions, base.Exposures)
+		report.Variants[i].MeanPValue = welchTTest(r.Mean, r.Variance, r.Exposures, base.Mean, base.Variance, base.Exposures)
+	}
+
+	if exp.IsBandit() {
+		report.SampleRatio = SampleRatioResult{Observed: make(map[string]int, len(report.Variants)), PValue: 1, Skipped: true}
+		for _, r := range report.Variants {
+			report.SampleRatio.Observed[r.Key] = r.Exposures
+		}
+	} else {
+		report.SampleRatio = sampleRatio(exp.Variants, report.Variants, srmThreshold)
+	}
+	return report, nil
+}
+
+// sampleRatio runs a chi-square goodness-of-fit test of the observed exposure
+// counts against the configured variant rollouts.
+func sampleRatio(variants []auroratype.Variant, results []VariantResult, threshold float64) SampleRatioResult {
+	srm := SampleRatioResult{
+		Observed: make(map[string]int, len(results)),
+		Expected: make(map[string]float64, len(results)),
+		PValue:   1,
+	}
+
+	total := 0
+	totalRollout := 0
+	for i, r := range results {
+		srm.Observed[r.Key] = r.Exposures
+		total += r.Exposures
+		totalRollout += variants[i].Rollout
+	}
+	if total == 0 || totalRollout == 0 {
+		return srm
+	}
+
+	df := -1
+	for i, r := range results {
+		expected := float64(total) * float64(variants[i].Rollout) / float64(totalRollout)
+		srm.Expected[r.Key] = expected
+		if expected == 0 {
+			if r.Exposures > 0 {
+				srm.ChiSquare = math.Inf(1)
+			}
+			continue
+		}
+		diff := float64(r.Exposures) - expected
+		srm.ChiSquare += diff * diff / expected
+		df++
+	}
+
+	if df <= 0 {
+		return srm
+	}
+	if math.IsInf(srm.ChiSquare, 1) {
+		srm.PValue = 0
+	} else {
+		srm.PValue = chiSquareSF(srm.ChiSquare, float64(df))
+	}
+	srm.Mismatch = srm.PValue < threshold
+	return srm
+}
+
+func unitID(event core.Event, hashAttribute string) (string, bool) {
+	v, ok := event.Attributes[hashAttribute]
+	if !ok || v == nil {
+		return "", false
+	}
+	return fmt.Sprintf("%v", v), true
+}
+
+func meanVariance(xs []float64) (float64, float64) {
+	if len(xs) == 0 {
+		return 0, 0
+	}
+	sum := 0.0
+	for _, x := range xs {
+		sum += x
+	}
+	mean := sum / float64(len(xs))
+	if len(xs) < 2 {
+		return mean, 0
+	}
+	ss := 0.0
+	for _, x := range xs {
+		ss += (x - mean) * (x - mean)
+	}
+	return mean, ss / float64(len(xs)-1)
+}
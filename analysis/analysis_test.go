@@ -0,0 +1,195 @@
+package analysis
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	core "github.com/tuannguyensn2001/aurora-go"
+	"github.com/tuannguyensn2001/aurora-go/auroratype"
+)
+
+func TestStatsDistributions(t *testing.T) {
+	assert.InDelta(t, 0.975, normalCDF(1.959964), 1e-6)
+	assert.InDelta(t, 1.959964, normalQuantile(0.975), 1e-6)
+	assert.InDelta(t, 0.975, studentTCDF(2.228139, 10), 1e-6)
+	assert.InDelta(t, 0.025, studentTCDF(-2.228139, 10), 1e-6)
+	assert.InDelta(t, 0.05, chiSquareSF(3.841459, 1), 1e-6)
+	assert.InDelta(t, 0.05, chiSquareSF(5.991465, 2), 1e-6)
+	assert.InDelta(t, 0.01, chiSquareSF(21.665994, 9), 1e-6)
+}
+
+func TestTwoProportionZTest(t *testing.T) {
+	// 200/1000 vs 250/1000: z = -2.68
+	p := twoProportionZTest(200, 1000, 250, 1000)
+	assert.InDelta(t, 0.0074, p, 1e-4)
+
+	assert.Equal(t, 1.0, twoProportionZTest(0, 10, 0, 10))
+	assert.True(t, math.IsNaN(twoProportionZTest(1, 0, 1, 10)))
+}
+
+func TestWelchTTest(t *testing.T) {
+	p := welchTTest(10, 4, 30, 11, 9, 30)
+	assert.InDelta(t, 0.135, p, 1e-3)
+	assert.True(t, math.IsNaN(welchTTest(1, 1, 1, 1, 1, 10)))
+}
+
+func testExperiment() auroratype.Experiment {
+	return auroratype.Experiment{
+		ID:            "exp_001",
+		HashAttribute: "userID",
+		Variants: []auroratype.Variant{
+			{Key: "control", Rollout: 50},
+			{Key: "treatment", Rollout: 50},
+		},
+	}
+}
+
+func writeEvents(t *testing.T, events []core.Event) *bytes.Buffer {
+	var buf bytes.Buffer
+	sink := core.NewJSONLSink(&buf)
+	for _, e := range events {
+		require.NoError(t, sink.Emit(context.Background(), e))
+	}
+	return &buf
+}
+
+func TestAnalyze(t *testing.T) {
+	base := time.Unix(1700000000, 0)
+	var events []core.Event
+	for i := 0; i < 100; i++ {
+		variant := "control"
+		if i%2 == 1 {
+			variant = "treatment"
+		}
+		attrs := map[string]any{"userID": fmt.Sprintf("user_%d", i)}
+		events = append(events, core.Event{
+			Type:         core.EventExposure,
+			Timestamp:    base,
+			ExperimentID: "exp_001",
+			VariantKey:   variant,
+			Attributes:   attrs,
+		})
+		// treatment converts every unit, control every fifth
+		if variant == "treatment" || i%10 == 0 {
+			events = append(events, core.Event{
+				Type:       core.EventConversion,
+				Timestamp:  base.Add(time.Minute),
+				Name:       "purchase",
+				Value:      10,
+				Attributes: attrs,
+			})
+		}
+	}
+
+	// exposures for other experiments and unrelated conversions are ignored
+	events = append(events,
+		core.Event{Type: core.EventExposure, Timestamp: base, ExperimentID: "exp_002", VariantKey: "control", Attributes: map[string]any{"userID": "other"}},
+		core.Event{Type: core.EventConversion, Timestamp: base, Name: "signup", Attributes: map[string]any{"userID": "user_0"}},
+		core.Event{Type: core.EventConversion, Timestamp: base.Add(-time.Hour), Name: "purchase", Value: 100, Attributes: map[string]any{"userID": "user_2"}},
+	)
+
+	report, err := Analyze(testExperiment(), Options{EventName: "purchase"}, writeEvents(t, events))
+	require.NoError(t, err)
+
+	require.Len(t, report.Variants, 2)
+	control, treatment := report.Variants[0], report.Variants[1]
+
+	assert.Equal(t, "control", report.Control)
+	assert.Equal(t, 50, control.Exposures)
+	assert.Equal(t, 10, control.Conversions)
+	assert.InDelta(t, 0.2, control.ConversionRate, 1e-9)
+	assert.InDelta(t, 2.0, control.Mean, 1e-9)
+	assert.True(t, math.IsNaN(control.ConversionPValue))
+
+	assert.Equal(t, 50, treatment.Exposures)
+	assert.Equal(t, 50, treatment.Conversions)
+	assert.InDelta(t, 1.0, treatment.ConversionRate, 1e-9)
+	assert.InDelta(t, 10.0, treatment.Mean, 1e-9)
+	assert.Less(t, treatment.ConversionPValue, 0.001)
+	assert.Less(t, treatment.MeanPValue, 0.001)
+	assert.LessOrEqual(t, treatment.ConversionRateCI.Upper, 1.0)
+
+	assert.False(t, report.SampleRatio.Mismatch)
+	assert.InDelta(t, 0, report.SampleRatio.ChiSquare, 1e-9)
+}
+
+func TestAnalyzeSampleRatioMismatch(t *testing.T) {
+	var events []core.Event
+	for i := 0; i < 1000; i++ {
+		variant := "control"
+		if i%4 == 0 {
+			variant = "treatment"
+		}
+		events = append(events, core.Event{
+			Type:         core.EventExposure,
+			ExperimentID: "exp_001",
+			VariantKey:   variant,
+			Attributes:   map[string]any{"userID": i},
+		})
+	}
+
+	report, err := Analyze(testExperiment(), Options{}, writeEvents(t, events))
+	require.NoError(t, err)
+
+	assert.True(t, report.SampleRatio.Mismatch)
+	assert.Equal(t, 750, report.SampleRatio.Observed["control"])
+	assert.InDelta(t, 500, report.SampleRatio.Expected["treatment"], 1e-9)
+	assert.InDelta(t, 250, report.SampleRatio.ChiSquare, 1e-9)
+}
+
+func TestAnalyzeSkipsSampleRatioForBandits(t *testing.T) {
+	var events []core.Event
+	for i := 0; i < 1000; i++ {
+		variant := "control"
+		if i%10 == 0 {
+			variant = "treatment"
+		}
+		events = append(events, core.Event{
+			Type:         core.EventExposure,
+			ExperimentID: "exp_001",
+			VariantKey:   variant,
+			Attributes:   map[string]any{"userID": i},
+		})
+	}
+
+	exp := testExperiment()
+	exp.Type = auroratype.TypeBandit
+	report, err := Analyze(exp, Options{}, writeEvents(t, events))
+	require.NoError(t, err)
+
+	assert.True(t, report.SampleRatio.Skipped)
+	assert.False(t, report.SampleRatio.Mismatch)
+	assert.Equal(t, 900, report.SampleRatio.Observed["control"])
+}
+
+func TestAnalyzeErrors(t *testing.T) {
+	_, err := Analyze(auroratype.Experiment{ID: "exp"}, Options{})
+	assert.Error(t, err)
+
+	_, err = Analyze(testExperiment(), Options{Control: "missing"})
+	assert.Error(t, err)
+
+	_, err = Analyze(testExperiment(), Options{}, strings.NewReader("{not json}\n"))
+	assert.Error(t, err)
+}
+
+func TestAnalyzeExcludesForcedExposures(t *testing.T) {
+	events := []core.Event{
+		{Type: core.EventExposure, ExperimentID: "exp_001", VariantKey: "control", Attributes: map[string]any{"userID": "u1"}},
+		{Type: core.EventExposure, ExperimentID: "exp_001", VariantKey: "treatment", Reason: core.ReasonForced, Attributes: map[string]any{"userID": "qa"}},
+	}
+
+	report, err := Analyze(testExperiment(), Options{}, writeEvents(t, events))
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, report.Variants[0].Exposures)
+	assert.Equal(t, 0, report.Variants[1].Exposures)
+}
@@ -0,0 +1,207 @@
+package analysis
+
+import "math"
+
+// normalCDF returns P(Z <= z) for a standard normal variable.
+func normalCDF(z float64) float64 {
+	return 0.5 * math.Erfc(-z/math.Sqrt2)
+}
+
+// normalQuantile returns z such that P(Z <= z) = p.
+func normalQuantile(p float64) float64 {
+	return math.Sqrt2 * math.Erfinv(2*p-1)
+}
+
+// studentTCDF returns P(T <= t) for a Student's t distribution with df
+// degrees of freedom.
+func studentTCDF(t, df float64) float64 {
+	if math.IsInf(df, 1) || df <= 0 {
+		return normalCDF(t)
+	}
+	x := df / (df + t*t)
+	tail := 0.5 * regularizedIncompleteBeta(df/2, 0.5, x)
+	if t > 0 {
+		return 1 - tail
+	}
+	return tail
+}
+
+// chiSquareSF returns P(X >= x) for a chi-square distribution with df
+// degrees of freedom.
+func chiSquareSF(x, df float64) float64 {
+	if x <= 0 {
+		return 1
+	}
+	return regularizedUpperGamma(df/2, x/2)
+}
+
+// regularizedIncompleteBeta evaluates I_x(a, b) using the continued fraction
+// from Numerical Recipes.
+func regularizedIncompleteBeta(a, b, x float64) float64 {
+	if x <= 0 {
+		return 0
+	}
+	if x >= 1 {
+		return 1
+	}
+
+	lga, _ := math.Lgamma(a)
+	lgb, _ := math.Lgamma(b)
+	lgab, _ := math.Lgamma(a + b)
+	front := math.Exp(lgab - lga - lgb + a*math.Log(x) + b*math.Log(1-x))
+
+	if x < (a+1)/(a+b+2) {
+		return front * betaContinuedFraction(a, b, x) / a
+	}
+	return 1 - front*betaContinuedFraction(b, a, 1-x)/b
+}
+
+func betaContinuedFraction(a, b, x float64) float64 {
+	const (
+		maxIterations = 300
+		eps           = 1e-14
+		tiny          = 1e-300
+	)
+
+	qab := a + b
+	qap := a + 1
+	qam := a - 1
+	c := 1.0
+	d := 1 - qab*x/qap
+	if math.Abs(d) < tiny {
+		d = tiny
+	}
+	d = 1 / d
+	h := d
+
+	for m := 1; m <= maxIterations; m++ {
+		fm := float64(m)
+		m2 := 2 * fm
+
+		aa := fm * (b - fm) * x / ((qam + m2) * (a + m2))
+		d = 1 + aa*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = 1 + aa/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		h *= d * c
+
+		aa = -(a + fm) * (qab + fm) * x / ((a + m2) * (qap + m2))
+		d = 1 + aa*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = 1 + aa/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		del := d * c
+		h *= del
+
+		if math.Abs(del-1) < eps {
+			break
+		}
+	}
+
+	return h
+}
+
+// regularizedUpperGamma evaluates Q(a, x) = 1 - P(a, x), using the series
+// expansion below a+1 and the continued fraction above it.
+func regularizedUpperGamma(a, x float64) float64 {
+	const (
+		maxIterations = 300
+		eps           = 1e-14
+		tiny          = 1e-300
+	)
+
+	lga, _ := math.Lgamma(a)
+
+	if x < a+1 {
+		sum := 1 / a
+		del := sum
+		ap := a
+		for n := 0; n < maxIterations; n++ {
+			ap++
+			del *= x / ap
+			sum += del
+			if math.Abs(del) < math.Abs(sum)*eps {
+				break
+			}
+		}
+		return 1 - sum*math.Exp(-x+a*math.Log(x)-lga)
+	}
+
+	b := x + 1 - a
+	c := 1 / tiny
+	d := 1 / b
+	h := d
+	for i := 1; i <= maxIterations; i++ {
+		an := -float64(i) * (float64(i) - a)
+		b += 2
+		d = an*d + b
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = b + an/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		del := d * c
+		h *= del
+		if math.Abs(del-1) < eps {
+			break
+		}
+	}
+	return math.Exp(-x+a*math.Log(x)-lga) * h
+}
+
+// twoProportionZTest returns the two-sided p-value for the difference between
+// two conversion rates using the pooled standard error.
+func twoProportionZTest(x1, n1, x2, n2 int) float64 {
+	if n1 == 0 || n2 == 0 {
+		return math.NaN()
+	}
+
+	p1 := float64(x1) / float64(n1)
+	p2 := float64(x2) / float64(n2)
+	pooled := float64(x1+x2) / float64(n1+n2)
+	se := math.Sqrt(pooled * (1 - pooled) * (1/float64(n1) + 1/float64(n2)))
+	if se == 0 {
+		if p1 == p2 {
+			return 1
+		}
+		return 0
+	}
+
+	z := (p1 - p2) / se
+	return 2 * (1 - normalCDF(math.Abs(z)))
+}
+
+// welchTTest returns the two-sided p-value for the difference between two
+// means without assuming equal variances.
+func welchTTest(m1, v1 float64, n1 int, m2, v2 float64, n2 int) float64 {
+	if n1 < 2 || n2 < 2 {
+		return math.NaN()
+	}
+
+	s1 := v1 / float64(n1)
+	s2 := v2 / float64(n2)
+	se := math.Sqrt(s1 + s2)
+	if se == 0 {
+		if m1 == m2 {
+			return 1
+		}
+		return 0
+	}
+
+	t := (m1 - m2) / se
+	df := (s1 + s2) * (s1 + s2) / (s1*s1/float64(n1-1) + s2*s2/float64(n2-1))
+	return 2 * (1 - studentTCDF(math.Abs(t), df))
+}
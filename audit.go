@@ -0,0 +1,129 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"reflect"
+	"slices"
+	"sync"
+	"time"
+)
+
+type AuditAction string
+
+const (
+	AuditCreate AuditAction = "create"
+	AuditUpdate AuditAction = "update"
+	AuditDelete AuditAction = "delete"
+	AuditToggle AuditAction = "toggle"
+	AuditStatus AuditAction = "status"
+)
+
+// AuditEntry records one change made through a Manager.
+type AuditEntry struct {
+	Time   time.Time   `json:"time"`
+	Actor  string      `json:"actor"`
+	Action AuditAction `json:"action"`
+	// Kind is "parameter" or "experiment", and Name the parameter name or
+	// experiment ID.
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+	// Revision is the revision of the source after the change.
+	Revision string        `json:"revision,omitempty"`
+	Changes  []FieldChange `json:"changes"`
+}
+
+// FieldChange is one changed field of a definition. Path follows the yaml
+// field names, e.g. "rules[0].rolloutValue", and is empty when the whole
+// definition was created or deleted.
+type FieldChange struct {
+	Path   string `json:"path,omitempty"`
+	Before any    `json:"before,omitempty"`
+	After  any    `json:"after,omitempty"`
+}
+
+// AuditLog receives an entry for every change a Manager makes.
+// Implementations must be safe for concurrent use.
+type AuditLog interface {
+	Record(ctx context.Context, entry AuditEntry) error
+}
+
+type noopAuditLog struct{}
+
+func (n noopAuditLog) Record(ctx context.Context, entry AuditEntry) error { return nil }
+
+// JSONLAuditLog writes one JSON encoded entry per line. To keep an
+// append-only file, pass one opened with os.O_APPEND.
+type JSONLAuditLog struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+func NewJSONLAuditLog(w io.Writer) *JSONLAuditLog {
+	return &JSONLAuditLog{
+		enc: json.NewEncoder(w),
+	}
+}
+
+func (l *JSONLAuditLog) Record(ctx context.Context, entry AuditEntry) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.enc.Encode(entry)
+}
+
+// diffDefinitions returns the fields that differ between two parameters or
+// experiments, either of which is nil if it does not exist.
+func diffDefinitions(before, after any) ([]FieldChange, error) {
+	var err error
+	if before != nil {
+		if before, err = definitionOf(before); err != nil {
+			return nil, err
+		}
+	}
+	if after != nil {
+		if after, err = definitionOf(after); err != nil {
+			return nil, err
+		}
+	}
+	var changes []FieldChange
+	diffTree("", before, after, &changes)
+	return changes, nil
+}
+
+func diffTree(path string, before, after any, changes *[]FieldChange) {
+	switch b := before.(type) {
+	case map[string]any:
+		if a, ok := after.(map[string]any); ok {
+			keys := maps.Clone(b)
+			maps.Copy(keys, a)
+			for _, key := range slices.Sorted(maps.Keys(keys)) {
+				field := key
+				if path != "" {
+					field = path + "." + key
+				}
+				diffTree(field, b[key], a[key], changes)
+			}
+			return
+		}
+	case []any:
+		if a, ok := after.([]any); ok {
+			for i := range max(len(a), len(b)) {
+				var x, y any
+				if i < len(b) {
+					x = b[i]
+				}
+				if i < len(a) {
+					y = a[i]
+				}
+				diffTree(fmt.Sprintf("%s[%d]", path, i), x, y, changes)
+			}
+			return
+		}
+	}
+	if !reflect.DeepEqual(before, after) {
+		*changes = append(*changes, FieldChange{Path: path, Before: before, After: after})
+	}
+}
@@ -0,0 +1,442 @@
+package auroratype
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+)
+
+// BinarySnapshotExtension is the conventional file extension of binary
+// snapshots.
+const BinarySnapshotExtension = ".aurora"
+
+// binaryMagic starts every binary snapshot, followed by the format version.
+var binaryMagic = []byte("AURB")
+
+const binaryVersion = 1
+
+var ErrBinaryVersion = errors.New("unsupported binary snapshot version")
+
+// value tags of the binary encoding
+const (
+	tagNil byte = iota
+	tagFalse
+	tagTrue
+	tagInt
+	tagUint
+	tagFloat
+	tagString
+	tagList
+	// tagMap is a map with string keys, tagAnyMap one with any keys. They
+	// are kept apart so that decoded values have the type they were
+	// encoded with.
+	tagMap
+	tagAnyMap
+	// tagStruct is a list of named fields. Unknown fields are skipped
+	// when decoding, so fields can be added without a new version.
+	tagStruct
+)
+
+type binarySnapshot struct {
+	Parameters  map[string]Parameter
+	Experiments []Experiment
+}
+
+// IsBinarySnapshot reports whether data is a binary snapshot.
+func IsBinarySnapshot(data []byte) bool {
+	return bytes.HasPrefix(data, binaryMagic)
+}
+
+// MarshalBinarySnapshot encodes parameters and experiments in the compact
+// binary snapshot format. Unlike YAML or JSON it needs no parsing, and
+// unlike gob it keeps nil apart from zero values, such as a percentage of
+// 0 or an empty list of rules.
+func MarshalBinarySnapshot(parameters map[string]Parameter, experiments []Experiment) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.Write(binaryMagic)
+	buf.WriteByte(binaryVersion)
+	if err := encodeValue(&buf, reflect.ValueOf(binarySnapshot{Parameters: parameters, Experiments: experiments})); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinarySnapshot decodes a binary snapshot.
+func UnmarshalBinarySnapshot(data []byte) (map[string]Parameter, []Experiment, error) {
+	if !IsBinarySnapshot(data) {
+		return nil, nil, errors.New("not a binary snapshot")
+	}
+	if len(data) <= len(binaryMagic) || data[len(binaryMagic)] != binaryVersion {
+		return nil, nil, ErrBinaryVersion
+	}
+
+	d := &binaryDecoder{data: data[len(binaryMagic)+1:]}
+	var snapshot binarySnapshot
+	if err := d.decode(reflect.ValueOf(&snapshot).Elem()); err != nil {
+		return nil, nil, fmt.Errorf("binary snapshot: %w", err)
+	}
+	if len(d.data) > 0 {
+		return nil, nil, errors.New("binary snapshot: trailing data")
+	}
+	return snapshot.Parameters, snapshot.Experiments, nil
+}
+
+func encodeValue(buf *bytes.Buffer, v reflect.Value) error {
+	if !v.IsValid() {
+		buf.WriteByte(tagNil)
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Interface, reflect.Pointer:
+		if v.IsNil() {
+			buf.WriteByte(tagNil)
+			return nil
+		}
+		return encodeValue(buf, v.Elem())
+	case reflect.Bool:
+		if v.Bool() {
+			buf.WriteByte(tagTrue)
+		} else {
+			buf.WriteByte(tagFalse)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		buf.WriteByte(tagInt)
+		buf.Write(binary.AppendVarint(nil, v.Int()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		buf.WriteByte(tagUint)
+		buf.Write(binary.AppendUvarint(nil, v.Uint()))
+	case reflect.Float32, reflect.Float64:
+		buf.WriteByte(tagFloat)
+		buf.Write(binary.LittleEndian.AppendUint64(nil, math.Float64bits(v.Float())))
+	case reflect.String:
+		buf.WriteByte(tagString)
+		writeString(buf, v.String())
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			buf.WriteByte(tagNil)
+			return nil
+		}
+		buf.WriteByte(tagList)
+		buf.Write(binary.AppendUvarint(nil, uint64(v.Len())))
+		for i := 0; i < v.Len(); i++ {
+			if err := encodeValue(buf, v.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if v.IsNil() {
+			buf.WriteByte(tagNil)
+			return nil
+		}
+		if v.Type().Key().Kind() == reflect.String {
+			buf.WriteByte(tagMap)
+		} else {
+			buf.WriteByte(tagAnyMap)
+		}
+		buf.Write(binary.AppendUvarint(nil, uint64(v.Len())))
+		iter := v.MapRange()
+		for iter.Next() {
+			if err := encodeValue(buf, iter.Key()); err != nil {
+				return err
+			}
+			if err := encodeValue(buf, iter.Value()); err != nil {
+				return err
+			}
+		}
+	case reflect.Struct:
+		buf.WriteByte(tagStruct)
+		t := v.Type()
+		buf.Write(binary.AppendUvarint(nil, uint64(t.NumField())))
+		for i := 0; i < t.NumField(); i++ {
+			writeString(buf, t.Field(i).Name)
+			if err := encodeValue(buf, v.Field(i)); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("cannot encode %s", v.Type())
+	}
+	return nil
+}
+
+func writeString(buf *bytes.Buffer, s string) {
+	buf.Write(binary.AppendUvarint(nil, uint64(len(s))))
+	buf.WriteString(s)
+}
+
+type binaryDecoder struct {
+	data []byte
+}
+
+var errTruncated = errors.New("truncated data")
+
+func (d *binaryDecoder) byte() (byte, error) {
+	if len(d.data) == 0 {
+		return 0, errTruncated
+	}
+	b := d.data[0]
+	d.data = d.data[1:]
+	return b, nil
+}
+
+func (d *binaryDecoder) uvarint() (uint64, error) {
+	n, size := binary.Uvarint(d.data)
+	if size <= 0 {
+		return 0, errTruncated
+	}
+	d.data = d.data[size:]
+	return n, nil
+}
+
+func (d *binaryDecoder) length() (int, error) {
+	n, err := d.uvarint()
+	if err != nil {
+		return 0, err
+	}
+	// every element takes at least one byte
+	if n > uint64(len(d.data)) {
+		return 0, errTruncated
+	}
+	return int(n), nil
+}
+
+func (d *binaryDecoder) string() (string, error) {
+	n, err := d.length()
+	if err != nil {
+		return "", err
+	}
+	s := string(d.data[:n])
+	d.data = d.data[n:]
+	return s, nil
+}
+
+// decode decodes the next value into v, which must be settable.
+func (d *binaryDecoder) decode(v reflect.Value) error {
+	tag, err := d.byte()
+	if err != nil {
+		return err
+	}
+	return d.decodeTagged(tag, v)
+}
+
+func (d *binaryDecoder) decodeTagged(tag byte, v reflect.Value) error {
+	if tag == tagNil {
+		v.SetZero()
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Interface:
+		value, err := d.decodeAny(tag)
+		if err != nil {
+			return err
+		}
+		if value == nil {
+			v.SetZero()
+		} else {
+			v.Set(reflect.ValueOf(value))
+		}
+		return nil
+	case reflect.Pointer:
+		elem := reflect.New(v.Type().Elem())
+		if err := d.decodeTagged(tag, elem.Elem()); err != nil {
+			return err
+		}
+		v.Set(elem)
+		return nil
+	}
+
+	switch tag {
+	case tagFalse, tagTrue:
+		if v.Kind() != reflect.Bool {
+			return mismatch(tag, v)
+		}
+		v.SetBool(tag == tagTrue)
+	case tagInt:
+		n, size := binary.Varint(d.data)
+		if size <= 0 {
+			return errTruncated
+		}
+		d.data = d.data[size:]
+		switch v.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			v.SetInt(n)
+		case reflect.Float32, reflect.Float64:
+			v.SetFloat(float64(n))
+		default:
+			return mismatch(tag, v)
+		}
+	case tagUint:
+		n, err := d.uvarint()
+		if err != nil {
+			return err
+		}
+		switch v.Kind() {
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			v.SetUint(n)
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			v.SetInt(int64(n))
+		default:
+			return mismatch(tag, v)
+		}
+	case tagFloat:
+		if len(d.data) < 8 {
+			return errTruncated
+		}
+		f := math.Float64frombits(binary.LittleEndian.Uint64(d.data))
+		d.data = d.data[8:]
+		if v.Kind() != reflect.Float32 && v.Kind() != reflect.Float64 {
+			return mismatch(tag, v)
+		}
+		v.SetFloat(f)
+	case tagString:
+		s, err := d.string()
+		if err != nil {
+			return err
+		}
+		if v.Kind() != reflect.String {
+			return mismatch(tag, v)
+		}
+		v.SetString(s)
+	case tagList:
+		n, err := d.length()
+		if err != nil {
+			return err
+		}
+		switch v.Kind() {
+		case reflect.Slice:
+			v.Set(reflect.MakeSlice(v.Type(), n, n))
+		case reflect.Array:
+			if n != v.Len() {
+				return mismatch(tag, v)
+			}
+		default:
+			return mismatch(tag, v)
+		}
+		for i := 0; i < n; i++ {
+			if err := d.decode(v.Index(i)); err != nil {
+				return err
+			}
+		}
+	case tagMap, tagAnyMap:
+		n, err := d.length()
+		if err != nil {
+			return err
+		}
+		if v.Kind() != reflect.Map {
+			return mismatch(tag, v)
+		}
+		m := reflect.MakeMapWithSize(v.Type(), n)
+		for i := 0; i < n; i++ {
+			key := reflect.New(v.Type().Key()).Elem()
+			if err := d.decode(key); err != nil {
+				return err
+			}
+			value := reflect.New(v.Type().Elem()).Elem()
+			if err := d.decode(value); err != nil {
+				return err
+			}
+			m.SetMapIndex(key, value)
+		}
+		v.Set(m)
+	case tagStruct:
+		n, err := d.length()
+		if err != nil {
+			return err
+		}
+		if v.Kind() != reflect.Struct {
+			return mismatch(tag, v)
+		}
+		v.SetZero()
+		for i := 0; i < n; i++ {
+			name, err := d.string()
+			if err != nil {
+				return err
+			}
+			field := v.FieldByName(name)
+			if !field.IsValid() {
+				// a field added by a newer writer
+				if _, err := d.decodeAnyNext(); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := d.decode(field); err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+		}
+	default:
+		return fmt.Errorf("unknown tag %d", tag)
+	}
+	return nil
+}
+
+func (d *binaryDecoder) decodeAnyNext() (any, error) {
+	tag, err := d.byte()
+	if err != nil {
+		return nil, err
+	}
+	return d.decodeAny(tag)
+}
+
+// decodeAny decodes a value of dynamic type: integers become int, floats
+// float64, lists []any, and maps map[string]any or map[any]any.
+func (d *binaryDecoder) decodeAny(tag byte) (any, error) {
+	var target reflect.Value
+	switch tag {
+	case tagNil:
+		return nil, nil
+	case tagFalse, tagTrue:
+		return tag == tagTrue, nil
+	case tagInt:
+		target = reflect.New(reflect.TypeFor[int]()).Elem()
+	case tagUint:
+		target = reflect.New(reflect.TypeFor[uint64]()).Elem()
+	case tagFloat:
+		target = reflect.New(reflect.TypeFor[float64]()).Elem()
+	case tagString:
+		return d.string()
+	case tagList:
+		target = reflect.New(reflect.TypeFor[[]any]()).Elem()
+	case tagMap, tagStruct:
+		target = reflect.New(reflect.TypeFor[map[string]any]()).Elem()
+	case tagAnyMap:
+		target = reflect.New(reflect.TypeFor[map[any]any]()).Elem()
+	default:
+		return nil, fmt.Errorf("unknown tag %d", tag)
+	}
+
+	if tag == tagStruct {
+		return d.decodeStructAsMap()
+	}
+	if err := d.decodeTagged(tag, target); err != nil {
+		return nil, err
+	}
+	return target.Interface(), nil
+}
+
+func (d *binaryDecoder) decodeStructAsMap() (map[string]any, error) {
+	n, err := d.length()
+	if err != nil {
+		return nil, err
+	}
+	m := make(map[string]any, n)
+	for i := 0; i < n; i++ {
+		name, err := d.string()
+		if err != nil {
+			return nil, err
+		}
+		if m[name], err = d.decodeAnyNext(); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+func mismatch(tag byte, v reflect.Value) error {
+	return fmt.Errorf("cannot decode tag %d into %s", tag, v.Type())
+}
@@ -0,0 +1,103 @@
+package auroratype
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+const binaryFixture = `
+checkout:
+  defaultValue: 10
+  rules:
+    - rolloutValue: 20
+      percentage: 0
+      constraints:
+        - field: country
+          operator: in
+          value: [VN, US]
+  environments:
+    prod:
+      rules: []
+theme:
+  defaultValue:
+    colors: {primary: blue}
+    ratio: 1.5
+    enabled: true
+    missing: null
+`
+
+func TestBinarySnapshotRoundTrip(t *testing.T) {
+	var parameters map[string]Parameter
+	require.NoError(t, yaml.Unmarshal([]byte(binaryFixture), &parameters))
+	start := int64(1700000000)
+	experiments := []Experiment{{
+		ID:             "exp-1",
+		Parameters:     []string{"checkout"},
+		Status:         StatusRunning,
+		StartTime:      &start,
+		Variants:       []Variant{{Key: "a", Rollout: 100, Values: map[string]interface{}{"checkout": 30}}},
+		ForcedVariants: map[string]string{"qa": "a"},
+	}}
+
+	data, err := MarshalBinarySnapshot(parameters, experiments)
+	require.NoError(t, err)
+	assert.True(t, IsBinarySnapshot(data))
+
+	gotParameters, gotExperiments, err := UnmarshalBinarySnapshot(data)
+	require.NoError(t, err)
+	assert.Equal(t, parameters, gotParameters)
+	assert.Equal(t, experiments, gotExperiments)
+	assert.Equal(t, ContentVersion(parameters, experiments), ContentVersion(gotParameters, gotExperiments))
+
+	// nil and zero are kept apart
+	rule := gotParameters["checkout"].Rules[0]
+	require.NotNil(t, rule.Percentage)
+	assert.Equal(t, 0, *rule.Percentage)
+	overlay := gotParameters["checkout"].Environments["prod"]
+	require.NotNil(t, overlay.Rules)
+	assert.Empty(t, *overlay.Rules)
+}
+
+func TestUnmarshalBinarySnapshotRejectsBadInput(t *testing.T) {
+	data, err := MarshalBinarySnapshot(map[string]Parameter{"a": {DefaultValue: "x"}}, nil)
+	require.NoError(t, err)
+
+	_, _, err = UnmarshalBinarySnapshot([]byte("a: 1"))
+	assert.Error(t, err)
+
+	newer := append([]byte{}, data...)
+	newer[len(binaryMagic)] = binaryVersion + 1
+	_, _, err = UnmarshalBinarySnapshot(newer)
+	assert.ErrorIs(t, err, ErrBinaryVersion)
+
+	for i := len(binaryMagic) + 1; i < len(data); i++ {
+		_, _, err = UnmarshalBinarySnapshot(data[:i])
+		assert.Error(t, err, "truncated at %d", i)
+	}
+}
+
+func TestCompression(t *testing.T) {
+	data := []byte("checkout:\n  defaultValue: 10\n")
+	for _, encoding := range []string{"", EncodingGzip, EncodingZstd} {
+		compressed, err := Compress(data, encoding)
+		require.NoError(t, err)
+		got, err := Decompress(compressed, encoding)
+		require.NoError(t, err)
+		assert.Equal(t, data, got, encoding)
+	}
+
+	_, err := Decompress(data, "br")
+	assert.Error(t, err)
+	_, err = Decompress(data, EncodingGzip)
+	assert.Error(t, err)
+
+	base, encoding := SplitCompression("dir/parameters.yaml.zst")
+	assert.Equal(t, "dir/parameters.yaml", base)
+	assert.Equal(t, EncodingZstd, encoding)
+	base, encoding = SplitCompression("parameters.yaml")
+	assert.Equal(t, "parameters.yaml", base)
+	assert.Empty(t, encoding)
+}
@@ -0,0 +1,301 @@
+package auroratype
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v2"
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// FormatBinary is the format of binary snapshots. It is not a codec:
+// snapshots are recognized by their content and hold both parameters and
+// experiments.
+const FormatBinary = "binary"
+
+// Codec decodes, and optionally encodes, one configuration format. Every
+// fetcher picks the codec of a payload from its content type or extension,
+// so registering a codec makes the format available everywhere.
+type Codec struct {
+	// Name identifies the format, e.g. "yaml". It is the Format of
+	// payloads decoded with the codec.
+	Name string
+	// Extensions are the file extensions of the format, e.g. ".yaml".
+	Extensions []string
+	// MediaTypes are the content types of the format. A type starting with
+	// "+" matches structured syntax suffixes, e.g. "+json".
+	MediaTypes []string
+	// Unmarshal decodes data into v, a pointer to a map of parameters or
+	// to a struct with an experiments field, following the yaml field
+	// names of the configuration types. Codecs producing generic values
+	// can use UnmarshalTree.
+	Unmarshal func(data []byte, v any) error
+	// Marshal encodes v, following the yaml field names of the
+	// configuration types. It is optional: sources in a format without
+	// Marshal cannot be written back. Codecs producing generic values can
+	// use MarshalTree.
+	Marshal func(v any) ([]byte, error)
+}
+
+var codecs = struct {
+	mu     sync.RWMutex
+	byName map[string]Codec
+	order  []string
+}{byName: make(map[string]Codec)}
+
+func init() {
+	RegisterCodec(Codec{
+		Name:       "yaml",
+		Extensions: []string{".yaml", ".yml"},
+		MediaTypes: []string{"application/yaml", "application/x-yaml", "text/yaml", "text/x-yaml", "+yaml"},
+		Unmarshal:  unmarshalYAML,
+		Marshal:    yaml.Marshal,
+	})
+	RegisterCodec(Codec{
+		Name:       "json",
+		Extensions: []string{".json"},
+		MediaTypes: []string{"application/json", "text/json", "+json"},
+		Unmarshal:  json.Unmarshal,
+		Marshal:    marshalJSON,
+	})
+	RegisterCodec(Codec{
+		Name:       "toml",
+		Extensions: []string{".toml"},
+		MediaTypes: []string{"application/toml", "text/toml", "+toml"},
+		Unmarshal:  unmarshalTOML,
+	})
+}
+
+// RegisterCodec makes codec available to every fetcher, replacing a codec
+// of the same name. Extensions and media types are matched case
+// insensitively; when two codecs claim one, the later registration wins.
+func RegisterCodec(codec Codec) {
+	codecs.mu.Lock()
+	defer codecs.mu.Unlock()
+	if _, ok := codecs.byName[codec.Name]; !ok {
+		codecs.order = append(codecs.order, codec.Name)
+	}
+	codecs.byName[codec.Name] = codec
+}
+
+// CodecByName returns the codec registered as name.
+func CodecByName(name string) (Codec, bool) {
+	codecs.mu.RLock()
+	defer codecs.mu.RUnlock()
+	codec, ok := codecs.byName[name]
+	return codec, ok
+}
+
+// CodecByExtension returns the codec for the extension of name, a path,
+// key or URL path. A compression extension is ignored, so
+// "parameters.toml.gz" is TOML.
+func CodecByExtension(name string) (Codec, bool) {
+	name, _ = SplitCompression(name)
+	ext := strings.ToLower(path.Ext(name))
+	if ext == "" {
+		return Codec{}, false
+	}
+	return findCodec(func(codec Codec) bool {
+		for _, e := range codec.Extensions {
+			if strings.ToLower(e) == ext {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// CodecByMediaType returns the codec for a Content-Type header value.
+func CodecByMediaType(contentType string) (Codec, bool) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return Codec{}, false
+	}
+	return findCodec(func(codec Codec) bool {
+		for _, t := range codec.MediaTypes {
+			t = strings.ToLower(t)
+			if t == mediaType || strings.HasPrefix(t, "+") && strings.HasSuffix(mediaType, t) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// findCodec returns the most recently registered codec matching match.
+func findCodec(match func(Codec) bool) (Codec, bool) {
+	codecs.mu.RLock()
+	defer codecs.mu.RUnlock()
+	for i := len(codecs.order) - 1; i >= 0; i-- {
+		if codec := codecs.byName[codecs.order[i]]; match(codec) {
+			return codec, true
+		}
+	}
+	return Codec{}, false
+}
+
+// Extensions returns the file extensions of every registered codec.
+func Extensions() []string {
+	codecs.mu.RLock()
+	defer codecs.mu.RUnlock()
+	var extensions []string
+	for _, name := range codecs.order {
+		extensions = append(extensions, codecs.byName[name].Extensions...)
+	}
+	return extensions
+}
+
+// FormatOf returns the format of a payload named name with the given
+// content type, either of which may be empty. Binary snapshots are
+// recognized by their content; otherwise the content type is tried, then
+// the extension of name, and content that is neither falls back to JSON if
+// it looks like a JSON object and to YAML otherwise.
+func FormatOf(name, contentType string, data []byte) string {
+	if IsBinarySnapshot(data) {
+		return FormatBinary
+	}
+	if codec, ok := CodecByMediaType(contentType); ok {
+		return codec.Name
+	}
+	if codec, ok := CodecByExtension(name); ok {
+		return codec.Name
+	}
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		return "json"
+	}
+	return "yaml"
+}
+
+// DecodeParameters decodes parameters in format, the name of a registered
+// codec or FormatBinary. Empty content decodes to no parameters.
+func DecodeParameters(data []byte, format string) (map[string]Parameter, error) {
+	if format == FormatBinary {
+		parameters, _, err := UnmarshalBinarySnapshot(data)
+		return parameters, err
+	}
+	var parameters map[string]Parameter
+	err := decode(data, format, &parameters)
+	return parameters, err
+}
+
+// DecodeExperiments decodes experiments in format, listed under an
+// "experiments" key. Empty content decodes to no experiments.
+func DecodeExperiments(data []byte, format string) ([]Experiment, error) {
+	if format == FormatBinary {
+		_, experiments, err := UnmarshalBinarySnapshot(data)
+		return experiments, err
+	}
+	var config struct {
+		Experiments []Experiment `yaml:"experiments" json:"experiments"`
+	}
+	err := decode(data, format, &config)
+	return config.Experiments, err
+}
+
+// Decode decodes any other document, such as environment overlays, into v
+// with the codec of format.
+func Decode(data []byte, format string, v any) error {
+	if format == FormatBinary {
+		return errors.New("unexpected binary snapshot")
+	}
+	return decode(data, format, v)
+}
+
+func decode(data []byte, format string, v any) error {
+	codec, ok := CodecByName(format)
+	if !ok {
+		return fmt.Errorf("unsupported format %q", format)
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil
+	}
+	return codec.Unmarshal(data, v)
+}
+
+// Encode encodes v, parameters or another configuration document, with
+// the codec of format.
+func Encode(v any, format string) ([]byte, error) {
+	codec, ok := CodecByName(format)
+	if !ok {
+		return nil, fmt.Errorf("unsupported format %q", format)
+	}
+	if codec.Marshal == nil {
+		return nil, fmt.Errorf("format %q cannot be encoded", format)
+	}
+	return codec.Marshal(v)
+}
+
+// EncodeExperiments encodes experiments in format, listed under an
+// "experiments" key as DecodeExperiments expects.
+func EncodeExperiments(experiments []Experiment, format string) ([]byte, error) {
+	if experiments == nil {
+		experiments = []Experiment{}
+	}
+	return Encode(struct {
+		Experiments []Experiment `yaml:"experiments"`
+	}{experiments}, format)
+}
+
+// MarshalTree converts v to a generic value, maps keyed by the yaml field
+// names of the configuration types, for codecs that encode such values.
+func MarshalTree(v any) (any, error) {
+	data, err := yaml.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var tree any
+	if err := yamlv3.Unmarshal(data, &tree); err != nil {
+		return nil, err
+	}
+	return tree, nil
+}
+
+// UnmarshalTree stores tree, a generic value such as a map[string]any, in
+// v as if tree had been decoded from YAML, so that the yaml field names of
+// the configuration types apply.
+func UnmarshalTree(tree any, v any) error {
+	data, err := yaml.Marshal(tree)
+	if err != nil {
+		return err
+	}
+	return yaml.Unmarshal(data, v)
+}
+
+// unmarshalYAML decodes YAML, keeping !encrypted values for the client to
+// decrypt.
+func unmarshalYAML(data []byte, v any) error {
+	data, err := ExpandEncryptedTags(data)
+	if err != nil {
+		return err
+	}
+	return yaml.Unmarshal(data, v)
+}
+
+// marshalJSON encodes v as indented JSON. The configuration types only
+// carry yaml field names, so v is converted to a tree first.
+func marshalJSON(v any) ([]byte, error) {
+	tree, err := MarshalTree(v)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.MarshalIndent(tree, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+func unmarshalTOML(data []byte, v any) error {
+	var tree map[string]any
+	if err := toml.Unmarshal(data, &tree); err != nil {
+		return err
+	}
+	return UnmarshalTree(tree, v)
+}
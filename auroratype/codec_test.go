@@ -0,0 +1,174 @@
+package auroratype
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeFormats(t *testing.T) {
+	documents := map[string]string{
+		"yaml": `
+checkout:
+  defaultValue: 10
+  rules:
+    - rolloutValue: 20
+      percentage: 50
+      constraints:
+        - field: country
+          operator: in
+          value: [VN]
+secret:
+  defaultValue: !encrypted v1:main:abc
+`,
+		"toml": `
+[checkout]
+defaultValue = 10
+
+[[checkout.rules]]
+rolloutValue = 20
+percentage = 50
+
+[[checkout.rules.constraints]]
+field = "country"
+operator = "in"
+value = ["VN"]
+
+[secret]
+defaultValue = { "$enc" = "v1:main:abc" }
+`,
+	}
+
+	for format, document := range documents {
+		t.Run(format, func(t *testing.T) {
+			parameters, err := DecodeParameters([]byte(document), format)
+			require.NoError(t, err)
+
+			checkout := parameters["checkout"]
+			assert.Equal(t, 10, checkout.DefaultValue)
+			require.Len(t, checkout.Rules, 1)
+			assert.Equal(t, 20, checkout.Rules[0].RolloutValue)
+			require.NotNil(t, checkout.Rules[0].Percentage)
+			assert.Equal(t, 50, *checkout.Rules[0].Percentage)
+			assert.Equal(t, "country", checkout.Rules[0].Constraints[0].Field)
+
+			ciphertext, ok := EncryptedValue(parameters["secret"].DefaultValue)
+			assert.True(t, ok)
+			assert.Equal(t, "v1:main:abc", ciphertext)
+		})
+	}
+}
+
+func TestDecodeExperimentsFormats(t *testing.T) {
+	documents := map[string]string{
+		"yaml": "experiments:\n  - id: exp-1\n    hashAttribute: userId\n",
+		"json": `{"experiments": [{"id": "exp-1", "hashAttribute": "userId"}]}`,
+		"toml": "[[experiments]]\nid = \"exp-1\"\nhashAttribute = \"userId\"\n",
+	}
+	for format, document := range documents {
+		experiments, err := DecodeExperiments([]byte(document), format)
+		require.NoError(t, err, format)
+		require.Len(t, experiments, 1, format)
+		assert.Equal(t, "exp-1", experiments[0].ID, format)
+		assert.Equal(t, "userId", experiments[0].HashAttribute, format)
+	}
+
+	experiments, err := DecodeExperiments([]byte("  \n"), "json")
+	assert.NoError(t, err)
+	assert.Nil(t, experiments)
+
+	_, err = DecodeExperiments([]byte("a = 1"), "ini")
+	assert.Error(t, err)
+}
+
+func TestEncodeRoundTrip(t *testing.T) {
+	percentage := 50
+	parameters := map[string]Parameter{
+		"checkout": {
+			DefaultValue: 10,
+			Rules: []Rule{{
+				RolloutValue: 20,
+				Percentage:   &percentage,
+				Constraints:  []Constraint{{Field: "country", Operator: "in", Value: []any{"VN"}}},
+			}},
+		},
+		"secret": {DefaultValue: map[string]any{EncryptedValueKey: "v1:main:abc"}},
+	}
+	experiments := []Experiment{{ID: "exp-1", HashAttribute: "userId", Status: StatusRunning}}
+
+	for _, format := range []string{"yaml", "json"} {
+		data, err := Encode(parameters, format)
+		require.NoError(t, err, format)
+		decoded, err := DecodeParameters(data, format)
+		require.NoError(t, err, format)
+		assert.EqualValues(t, 10, decoded["checkout"].DefaultValue, format)
+		assert.Equal(t, "country", decoded["checkout"].Rules[0].Constraints[0].Field, format)
+		assert.Equal(t, 50, *decoded["checkout"].Rules[0].Percentage, format)
+		ciphertext, ok := EncryptedValue(decoded["secret"].DefaultValue)
+		assert.True(t, ok, format)
+		assert.Equal(t, "v1:main:abc", ciphertext, format)
+
+		data, err = EncodeExperiments(experiments, format)
+		require.NoError(t, err, format)
+		decodedExperiments, err := DecodeExperiments(data, format)
+		require.NoError(t, err, format)
+		assert.Equal(t, experiments[0].ID, decodedExperiments[0].ID, format)
+		assert.Equal(t, StatusRunning, decodedExperiments[0].Status, format)
+	}
+
+	_, err := Encode(parameters, "toml")
+	assert.Error(t, err)
+}
+
+func TestFormatOf(t *testing.T) {
+	snapshot, err := MarshalBinarySnapshot(nil, nil)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name        string
+		contentType string
+		data        string
+		want        string
+	}{
+		{"parameters.yaml", "", "a: 1", "yaml"},
+		{"config/parameters.TOML", "", "", "toml"},
+		{"parameters.json.gz", "", "", "json"},
+		{"parameters", "application/json; charset=utf-8", "", "json"},
+		{"parameters", "application/vnd.aurora+toml", "", "toml"},
+		{"parameters.json", "application/x-yaml", "", "yaml"},
+		{"parameters.json", "application/octet-stream", "", "json"},
+		{"parameters", "", ` {"a": 1}`, "json"},
+		{"parameters", "", "a: 1", "yaml"},
+		{"parameters.yaml", "", string(snapshot), FormatBinary},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, FormatOf(tt.name, tt.contentType, []byte(tt.data)), "%s %s", tt.name, tt.contentType)
+	}
+}
+
+func TestRegisterCodec(t *testing.T) {
+	// a line based format: name=defaultValue
+	RegisterCodec(Codec{
+		Name:       "test-lines",
+		Extensions: []string{".lines"},
+		MediaTypes: []string{"text/x-test-lines"},
+		Unmarshal: func(data []byte, v any) error {
+			tree := make(map[string]any)
+			for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+				name, value, _ := strings.Cut(line, "=")
+				tree[name] = map[string]any{"defaultValue": value}
+			}
+			return UnmarshalTree(tree, v)
+		},
+	})
+
+	assert.Equal(t, "test-lines", FormatOf("parameters.lines", "", nil))
+	assert.Equal(t, "test-lines", FormatOf("parameters", "text/x-test-lines", nil))
+	assert.Contains(t, Extensions(), ".lines")
+
+	parameters, err := DecodeParameters([]byte("theme=dark\n"), "test-lines")
+	require.NoError(t, err)
+	assert.Equal(t, "dark", parameters["theme"].DefaultValue)
+}
@@ -0,0 +1,92 @@
+package auroratype
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression encodings, as named by the Content-Encoding header.
+const (
+	EncodingGzip = "gzip"
+	EncodingZstd = "zstd"
+)
+
+// SplitCompression returns the compression encoding named by the extension
+// of name and name without that extension, e.g. "gzip" and
+// "parameters.yaml" for "parameters.yaml.gz". Names without a compression
+// extension are returned unchanged with an empty encoding.
+func SplitCompression(name string) (base, encoding string) {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".gz", ".gzip":
+		encoding = EncodingGzip
+	case ".zst", ".zstd":
+		encoding = EncodingZstd
+	default:
+		return name, ""
+	}
+	return strings.TrimSuffix(name, path.Ext(name)), encoding
+}
+
+// Decompress decodes data compressed with encoding. An empty or "identity"
+// encoding returns data unchanged.
+func Decompress(data []byte, encoding string) ([]byte, error) {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "", "identity":
+		return data, nil
+	case EncodingGzip, "x-gzip":
+		r, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("gzip: %w", err)
+		}
+		defer r.Close()
+		out, err := io.ReadAll(r)
+		if err != nil {
+			return nil, fmt.Errorf("gzip: %w", err)
+		}
+		return out, nil
+	case EncodingZstd:
+		r, err := zstd.NewReader(nil, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		out, err := r.DecodeAll(data, nil)
+		if err != nil {
+			return nil, fmt.Errorf("zstd: %w", err)
+		}
+		return out, nil
+	}
+	return nil, fmt.Errorf("unsupported content encoding %q", encoding)
+}
+
+// Compress encodes data with encoding, the inverse of Decompress.
+func Compress(data []byte, encoding string) ([]byte, error) {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "", "identity":
+		return data, nil
+	case EncodingGzip, "x-gzip":
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		if _, err := w.Write(data); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case EncodingZstd:
+		w, err := zstd.NewWriter(nil)
+		if err != nil {
+			return nil, err
+		}
+		defer w.Close()
+		return w.EncodeAll(data, nil), nil
+	}
+	return nil, fmt.Errorf("unsupported content encoding %q", encoding)
+}
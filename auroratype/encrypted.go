@@ -0,0 +1,77 @@
+package auroratype
+
+import (
+	"bytes"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EncryptedValueKey marks an encrypted value. A value written as
+// {"$enc": "<ciphertext>"}, or as !encrypted <ciphertext> in YAML, is
+// decrypted by the client during sync.
+const EncryptedValueKey = "$enc"
+
+// EncryptedTag is the YAML tag for encrypted values.
+const EncryptedTag = "!encrypted"
+
+// EncryptedValue returns the ciphertext of v if v is an encrypted value.
+func EncryptedValue(v any) (string, bool) {
+	var enc any
+	switch m := v.(type) {
+	case map[string]any:
+		if len(m) != 1 {
+			return "", false
+		}
+		enc = m[EncryptedValueKey]
+	case map[any]any:
+		if len(m) != 1 {
+			return "", false
+		}
+		enc = m[EncryptedValueKey]
+	default:
+		return "", false
+	}
+	ciphertext, ok := enc.(string)
+	return ciphertext, ok
+}
+
+// ExpandEncryptedTags rewrites every "!encrypted <ciphertext>" scalar of a
+// YAML document into the {"$enc": "<ciphertext>"} form, so that any YAML
+// decoder keeps the marker. Documents without the tag are returned as is.
+func ExpandEncryptedTags(data []byte) ([]byte, error) {
+	if !bytes.Contains(data, []byte(EncryptedTag)) {
+		return data, nil
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if !expandEncryptedTags(&doc) {
+		return data, nil
+	}
+	return yaml.Marshal(&doc)
+}
+
+func expandEncryptedTags(node *yaml.Node) bool {
+	if node.Kind == yaml.ScalarNode && node.Tag == EncryptedTag {
+		*node = yaml.Node{
+			Kind:  yaml.MappingNode,
+			Tag:   "!!map",
+			Style: yaml.FlowStyle,
+			Content: []*yaml.Node{
+				{Kind: yaml.ScalarNode, Tag: "!!str", Value: EncryptedValueKey},
+				{Kind: yaml.ScalarNode, Tag: "!!str", Value: node.Value},
+			},
+		}
+		return true
+	}
+
+	expanded := false
+	for _, child := range node.Content {
+		if expandEncryptedTags(child) {
+			expanded = true
+		}
+	}
+	return expanded
+}
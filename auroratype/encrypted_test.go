@@ -0,0 +1,44 @@
+package auroratype
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	yamlv2 "gopkg.in/yaml.v2"
+)
+
+func TestExpandEncryptedTags(t *testing.T) {
+	data := []byte(`
+partnerURL:
+  defaultValue: !encrypted v1:main:abc=
+  rules:
+    - rolloutValue: !encrypted v1:main:def=
+      constraints:
+        - field: enabled
+          operator: equal
+          value: yes
+`)
+	expanded, err := ExpandEncryptedTags(data)
+	require.NoError(t, err)
+
+	var config map[string]Parameter
+	require.NoError(t, yamlv2.Unmarshal(expanded, &config))
+
+	ciphertext, ok := EncryptedValue(config["partnerURL"].DefaultValue)
+	assert.True(t, ok)
+	assert.Equal(t, "v1:main:abc=", ciphertext)
+	ciphertext, ok = EncryptedValue(config["partnerURL"].Rules[0].RolloutValue)
+	assert.True(t, ok)
+	assert.Equal(t, "v1:main:def=", ciphertext)
+	// other scalars keep their meaning for the decoder
+	assert.Equal(t, true, config["partnerURL"].Rules[0].Constraints[0].Value)
+
+	plain := []byte("a:\n  defaultValue: 1\n")
+	expanded, err = ExpandEncryptedTags(plain)
+	require.NoError(t, err)
+	assert.Equal(t, plain, expanded)
+
+	_, ok = EncryptedValue(map[string]any{EncryptedValueKey: "x", "other": 1})
+	assert.False(t, ok)
+}
@@ -0,0 +1,153 @@
+package auroratype
+
+import "sort"
+
+// ParameterOverlay changes a parameter in one environment. Fields that are
+// not set keep the base value.
+type ParameterOverlay struct {
+	DefaultValue interface{} `yaml:"defaultValue,omitempty"`
+	// Rules replaces the base rules when set, even to an empty list.
+	Rules *[]Rule `yaml:"rules,omitempty"`
+}
+
+// ExperimentOverlay changes an experiment in one environment.
+type ExperimentOverlay struct {
+	// Disabled removes the experiment from the environment.
+	Disabled bool `yaml:"disabled,omitempty"`
+}
+
+// EnvironmentContent is the configuration resolved for one environment.
+type EnvironmentContent struct {
+	Parameters  map[string]Parameter
+	Experiments []Experiment
+}
+
+// EnvironmentResolver is implemented by fetchers that resolve their
+// environment themselves, so that what they return has no sections left.
+// ResolvedEnvironments returns the configuration of the most recent fetch
+// resolved for every environment it defines, the active one included, so
+// that each can be validated before any is served. It returns nil when the
+// fetcher has no environment.
+type EnvironmentResolver interface {
+	ResolvedEnvironments() map[string]EnvironmentContent
+}
+
+// ResolveEnvironments resolves parameters and experiments for every
+// environment with a section or with overlays, which are keyed by
+// environment and applied after the sections.
+func ResolveEnvironments(parameters map[string]Parameter, experiments []Experiment, parameterOverlays map[string]map[string]ParameterOverlay, experimentOverlays map[string]map[string]ExperimentOverlay) map[string]EnvironmentContent {
+	envs := Environments(parameters, experiments)
+	for env := range parameterOverlays {
+		envs = append(envs, env)
+	}
+	for env := range experimentOverlays {
+		envs = append(envs, env)
+	}
+
+	resolved := make(map[string]EnvironmentContent, len(envs))
+	for _, env := range envs {
+		if _, ok := resolved[env]; ok {
+			continue
+		}
+		resolved[env] = EnvironmentContent{
+			Parameters:  ApplyParameterOverlays(ResolveParameters(parameters, env), parameterOverlays[env]),
+			Experiments: ApplyExperimentOverlays(ResolveExperiments(experiments, env), experimentOverlays[env]),
+		}
+	}
+	return resolved
+}
+
+// Environments returns the sorted names of every environment with a
+// section in parameters or experiments.
+func Environments(parameters map[string]Parameter, experiments []Experiment) []string {
+	seen := make(map[string]bool)
+	for _, param := range parameters {
+		for env := range param.Environments {
+			seen[env] = true
+		}
+	}
+	for _, exp := range experiments {
+		for env := range exp.Environments {
+			seen[env] = true
+		}
+	}
+
+	envs := make([]string, 0, len(seen))
+	for env := range seen {
+		envs = append(envs, env)
+	}
+	sort.Strings(envs)
+	return envs
+}
+
+// ResolveParameters applies the environment sections for env and removes
+// all sections, returning a new map. An empty env only removes them.
+func ResolveParameters(parameters map[string]Parameter, env string) map[string]Parameter {
+	if parameters == nil {
+		return nil
+	}
+	resolved := make(map[string]Parameter, len(parameters))
+	for name, param := range parameters {
+		overlay, ok := param.Environments[env]
+		param.Environments = nil
+		if ok && env != "" {
+			param = overlay.apply(param)
+		}
+		resolved[name] = param
+	}
+	return resolved
+}
+
+// ResolveExperiments applies the environment sections for env and removes
+// all sections, returning a new slice. An empty env only removes them.
+func ResolveExperiments(experiments []Experiment, env string) []Experiment {
+	if experiments == nil {
+		return nil
+	}
+	resolved := make([]Experiment, 0, len(experiments))
+	for _, exp := range experiments {
+		overlay, ok := exp.Environments[env]
+		exp.Environments = nil
+		if ok && env != "" && overlay.Disabled {
+			continue
+		}
+		resolved = append(resolved, exp)
+	}
+	return resolved
+}
+
+// ApplyParameterOverlays applies overlays to parameters, returning a new
+// map. An overlay for a parameter that does not exist adds it.
+func ApplyParameterOverlays(parameters map[string]Parameter, overlays map[string]ParameterOverlay) map[string]Parameter {
+	resolved := make(map[string]Parameter, len(parameters)+len(overlays))
+	for name, param := range parameters {
+		resolved[name] = param
+	}
+	for name, overlay := range overlays {
+		resolved[name] = overlay.apply(resolved[name])
+	}
+	return resolved
+}
+
+// ApplyExperimentOverlays applies overlays to experiments, returning a new
+// slice. Overlays for unknown experiments are ignored.
+func ApplyExperimentOverlays(experiments []Experiment, overlays map[string]ExperimentOverlay) []Experiment {
+	resolved := make([]Experiment, 0, len(experiments))
+	for _, exp := range experiments {
+		if overlays[exp.ID].Disabled {
+			continue
+		}
+		resolved = append(resolved, exp)
+	}
+	return resolved
+}
+
+func (o ParameterOverlay) apply(param Parameter) Parameter {
+	if o.DefaultValue != nil {
+		param.DefaultValue = o.DefaultValue
+	}
+	if o.Rules != nil {
+		param.Rules = *o.Rules
+	}
+	return param
+}
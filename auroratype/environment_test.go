@@ -0,0 +1,86 @@
+package auroratype
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveParameters(t *testing.T) {
+	empty := []Rule{}
+	parameters := map[string]Parameter{
+		"limit": {
+			DefaultValue: 10,
+			Rules:        []Rule{{RolloutValue: 20}},
+			Environments: map[string]ParameterOverlay{
+				"prod":    {DefaultValue: 100},
+				"staging": {Rules: &empty},
+			},
+		},
+		"debug": {DefaultValue: true},
+	}
+
+	prod := ResolveParameters(parameters, "prod")
+	assert.Equal(t, Parameter{DefaultValue: 100, Rules: []Rule{{RolloutValue: 20}}}, prod["limit"])
+	assert.Equal(t, Parameter{DefaultValue: true}, prod["debug"])
+
+	staging := ResolveParameters(parameters, "staging")
+	assert.Equal(t, Parameter{DefaultValue: 10, Rules: []Rule{}}, staging["limit"])
+
+	base := ResolveParameters(parameters, "")
+	assert.Equal(t, Parameter{DefaultValue: 10, Rules: []Rule{{RolloutValue: 20}}}, base["limit"])
+
+	assert.NotNil(t, parameters["limit"].Environments, "input must not be modified")
+	assert.Equal(t, []string{"prod", "staging"}, Environments(parameters, nil))
+}
+
+func TestResolveExperiments(t *testing.T) {
+	experiments := []Experiment{
+		{ID: "a", Environments: map[string]ExperimentOverlay{"prod": {Disabled: true}}},
+		{ID: "b"},
+	}
+
+	prod := ResolveExperiments(experiments, "prod")
+	assert.Equal(t, []Experiment{{ID: "b"}}, prod)
+
+	dev := ResolveExperiments(experiments, "dev")
+	assert.Equal(t, []Experiment{{ID: "a"}, {ID: "b"}}, dev)
+}
+
+func TestApplyOverlays(t *testing.T) {
+	rules := []Rule{{RolloutValue: "x"}}
+	parameters := ApplyParameterOverlays(
+		map[string]Parameter{"a": {DefaultValue: 1}},
+		map[string]ParameterOverlay{"a": {Rules: &rules}, "b": {DefaultValue: 2}},
+	)
+	assert.Equal(t, Parameter{DefaultValue: 1, Rules: rules}, parameters["a"])
+	assert.Equal(t, Parameter{DefaultValue: 2}, parameters["b"])
+
+	experiments := ApplyExperimentOverlays(
+		[]Experiment{{ID: "a"}, {ID: "b"}},
+		map[string]ExperimentOverlay{"b": {Disabled: true}, "missing": {Disabled: true}},
+	)
+	assert.Equal(t, []Experiment{{ID: "a"}}, experiments)
+}
+
+func TestResolveEnvironments(t *testing.T) {
+	parameters := map[string]Parameter{
+		"limit": {
+			DefaultValue: 10,
+			Environments: map[string]ParameterOverlay{"prod": {DefaultValue: 100}},
+		},
+	}
+	experiments := []Experiment{{ID: "a"}}
+
+	resolved := ResolveEnvironments(parameters, experiments,
+		map[string]map[string]ParameterOverlay{"prod": {"debug": {DefaultValue: false}}, "dev": {"limit": {DefaultValue: 1}}},
+		map[string]map[string]ExperimentOverlay{"staging": {"a": {Disabled: true}}},
+	)
+
+	assert.Len(t, resolved, 3)
+	assert.Equal(t, map[string]Parameter{"limit": {DefaultValue: 100}, "debug": {DefaultValue: false}}, resolved["prod"].Parameters)
+	assert.Equal(t, []Experiment{{ID: "a"}}, resolved["prod"].Experiments)
+	assert.Equal(t, map[string]Parameter{"limit": {DefaultValue: 1}}, resolved["dev"].Parameters)
+	assert.Equal(t, map[string]Parameter{"limit": {DefaultValue: 10}}, resolved["staging"].Parameters)
+	assert.Empty(t, resolved["staging"].Experiments)
+}
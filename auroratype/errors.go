@@ -0,0 +1,12 @@
+package auroratype
+
+import "errors"
+
+// ErrNotModified is returned by a fetcher when the source has not changed
+// since its previous successful fetch. The storage keeps serving what it
+// already has without saving or diffing.
+var ErrNotModified = errors.New("not modified")
+
+// ErrConflict is returned by a WritableSource when the stored configuration
+// has changed since the revision a write was based on.
+var ErrConflict = errors.New("conflict: the source was modified concurrently")
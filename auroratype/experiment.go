@@ -1,5 +1,7 @@
 package auroratype
 
+import "time"
+
 type ExperimentStatus string
 
 const (
@@ -9,6 +11,30 @@ const (
 	StatusFinished  ExperimentStatus = "finished"
 )
 
+type ExperimentType string
+
+const (
+	// TypeABTest is a fixed-horizon test that splits traffic by Variant.Rollout.
+	// It is the default when no type is configured.
+	TypeABTest ExperimentType = "abtest"
+	// TypeBandit shifts traffic toward the best performing variant based on
+	// tracked reward events.
+	TypeBandit ExperimentType = "bandit"
+)
+
+type BanditAlgorithm string
+
+const (
+	BanditThompson      BanditAlgorithm = "thompson"
+	BanditEpsilonGreedy BanditAlgorithm = "epsilonGreedy"
+)
+
+type BanditConfig struct {
+	Algorithm   BanditAlgorithm `yaml:"algorithm"`
+	RewardEvent string          `yaml:"rewardEvent"`
+	Epsilon     float64         `yaml:"epsilon,omitempty"`
+}
+
 type Variant struct {
 	Key     string                 `yaml:"key"`
 	Rollout int                    `yaml:"rollout"`
@@ -18,6 +44,7 @@ type Variant struct {
 type Experiment struct {
 	ID             string           `yaml:"id"`
 	Name           string           `yaml:"name"`
+	Type           ExperimentType   `yaml:"type,omitempty"`
 	Parameters     []string         `yaml:"parameters"`
 	HashAttribute  string           `yaml:"hashAttribute"`
 	PopulationSize int              `yaml:"populationSize"`
@@ -27,4 +54,44 @@ type Experiment struct {
 	EndTime        *int64           `yaml:"endTime,omitempty"`
 	Constraints    []Constraint     `yaml:"constraints"`
 	Variants       []Variant        `yaml:"variants"`
+	Bandit         *BanditConfig    `yaml:"bandit,omitempty"`
+	// ForcedVariants maps values of the hash attribute to the variant key
+	// they are always assigned to, e.g. QA accounts.
+	ForcedVariants map[string]string `yaml:"forcedVariants,omitempty"`
+	// ForceBypassChecks lets forced assignments skip the status, time and
+	// population checks. Constraints still apply.
+	ForceBypassChecks bool `yaml:"forceBypassChecks,omitempty"`
+	// Winner is the variant key served to everyone once the experiment has
+	// finished.
+	Winner string `yaml:"winner,omitempty"`
+	// Environments holds per-environment overrides, applied when the
+	// configuration is resolved for an environment.
+	Environments map[string]ExperimentOverlay `yaml:"environments,omitempty"`
+}
+
+func (e Experiment) IsBandit() bool {
+	return e.Type == TypeBandit
+}
+
+// EffectiveStatus derives the status at now from StartTime and EndTime when
+// they are configured. Aborted and finished are terminal and always win; an
+// experiment is scheduled before its start time, finished after its end
+// time and running in between.
+func (e Experiment) EffectiveStatus(now time.Time) ExperimentStatus {
+	if e.Status == StatusAborted || e.Status == StatusFinished {
+		return e.Status
+	}
+
+	unix := now.Unix()
+	if e.EndTime != nil && unix > *e.EndTime {
+		return StatusFinished
+	}
+	if e.StartTime != nil && unix < *e.StartTime {
+		return StatusScheduled
+	}
+	if e.StartTime != nil || e.EndTime != nil {
+		return StatusRunning
+	}
+
+	return e.Status
 }
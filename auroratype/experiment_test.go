@@ -0,0 +1,38 @@
+package auroratype
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExperimentEffectiveStatus(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	before := now.Add(-time.Hour).Unix()
+	after := now.Add(time.Hour).Unix()
+
+	tests := []struct {
+		name     string
+		status   ExperimentStatus
+		start    *int64
+		end      *int64
+		expected ExperimentStatus
+	}{
+		{"no times keeps status", StatusScheduled, nil, nil, StatusScheduled},
+		{"running without times", StatusRunning, nil, nil, StatusRunning},
+		{"scheduled starts on its own", StatusScheduled, &before, &after, StatusRunning},
+		{"running before start", StatusRunning, &after, nil, StatusScheduled},
+		{"running after end", StatusRunning, nil, &before, StatusFinished},
+		{"scheduled after end", StatusScheduled, &before, &before, StatusFinished},
+		{"aborted is terminal", StatusAborted, &before, &after, StatusAborted},
+		{"finished is terminal", StatusFinished, &before, &after, StatusFinished},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exp := Experiment{Status: tt.status, StartTime: tt.start, EndTime: tt.end}
+			assert.Equal(t, tt.expected, exp.EffectiveStatus(now))
+		})
+	}
+}
@@ -3,6 +3,9 @@ package auroratype
 type Parameter struct {
 	DefaultValue interface{} `yaml:"defaultValue"`
 	Rules        []Rule      `yaml:"rules"`
+	// Environments holds per-environment overrides, applied when the
+	// configuration is resolved for an environment.
+	Environments map[string]ParameterOverlay `yaml:"environments,omitempty"`
 }
 
 type Rule struct {
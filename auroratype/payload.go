@@ -0,0 +1,31 @@
+package auroratype
+
+import "context"
+
+// Payload is the undecoded content of a configuration object.
+type Payload struct {
+	// Name identifies the object within its source: a path, key or URL.
+	// Related objects, such as a detached signature, are named after it.
+	Name string
+	Data []byte
+	// Format is the encoding of the content, "yaml", "json" or "binary"
+	// for a binary snapshot.
+	Format string
+	// Encoding is the compression the object was stored or sent with,
+	// "gzip" or "zstd". Data is already decompressed, so that signatures
+	// do not depend on how the object was compressed.
+	Encoding string
+}
+
+// RawFetcher is implemented by fetchers that can return their objects
+// before decoding, so that wrappers can inspect the exact bytes, e.g. to
+// verify a signature. FetchRaw and FetchExperimentsRaw follow the same
+// rules as Fetch and FetchExperiments, including ErrNotModified; a source
+// without experiments returns a Payload without Data.
+type RawFetcher interface {
+	FetchRaw(ctx context.Context) (Payload, error)
+	FetchExperimentsRaw(ctx context.Context) (Payload, error)
+	// ReadObject reads another object of the same source by name,
+	// unconditionally, decompressing it.
+	ReadObject(ctx context.Context, name string) ([]byte, error)
+}
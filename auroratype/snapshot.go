@@ -0,0 +1,108 @@
+package auroratype
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// Snapshot is a consistent view of parameters and experiments taken from a
+// single sync. A snapshot must not be modified once it has been saved;
+// storages swap whole snapshots instead.
+type Snapshot struct {
+	Parameters  map[string]Parameter
+	Experiments []Experiment
+	// Version identifies the content of the snapshot. Two snapshots with the
+	// same parameters and experiments have the same version.
+	Version string
+	// ETag is the opaque version reported by the source, if any.
+	ETag      string
+	FetchedAt time.Time
+	Source    string
+	// Sources maps parameter names to the location they were defined in,
+	// when the fetcher reports one. It is not part of the version.
+	Sources map[string]string
+	// Encrypted names the parameters that held encrypted values, which
+	// must be redacted wherever the configuration is shown.
+	Encrypted map[string]bool
+}
+
+// NewSnapshot builds a snapshot and computes its content version.
+func NewSnapshot(parameters map[string]Parameter, experiments []Experiment, source string, fetchedAt time.Time) *Snapshot {
+	if parameters == nil {
+		parameters = make(map[string]Parameter)
+	}
+	return &Snapshot{
+		Parameters:  parameters,
+		Experiments: experiments,
+		Version:     ContentVersion(parameters, experiments),
+		FetchedAt:   fetchedAt,
+		Source:      source,
+	}
+}
+
+// Parameter looks up a parameter by name.
+func (s *Snapshot) Parameter(name string) (Parameter, bool) {
+	if s == nil {
+		return Parameter{}, false
+	}
+	p, ok := s.Parameters[name]
+	return p, ok
+}
+
+// ContentVersion returns a stable hash of parameters and experiments. Map
+// keys are visited in sorted order so that the result does not depend on
+// iteration order or on the decoder that produced the values.
+func ContentVersion(parameters map[string]Parameter, experiments []Experiment) string {
+	h := sha256.New()
+	writeValue(h, reflect.ValueOf(parameters))
+	writeValue(h, reflect.ValueOf(experiments))
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+func writeValue(h hash.Hash, v reflect.Value) {
+	if !v.IsValid() {
+		h.Write([]byte("nil;"))
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Interface, reflect.Pointer:
+		if v.IsNil() {
+			h.Write([]byte("nil;"))
+			return
+		}
+		writeValue(h, v.Elem())
+	case reflect.Map:
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+		})
+		h.Write([]byte("{"))
+		for _, k := range keys {
+			writeValue(h, k)
+			h.Write([]byte(":"))
+			writeValue(h, v.MapIndex(k))
+		}
+		h.Write([]byte("}"))
+	case reflect.Slice, reflect.Array:
+		h.Write([]byte("["))
+		for i := 0; i < v.Len(); i++ {
+			writeValue(h, v.Index(i))
+		}
+		h.Write([]byte("]"))
+	case reflect.Struct:
+		h.Write([]byte("("))
+		for i := 0; i < v.NumField(); i++ {
+			h.Write([]byte(v.Type().Field(i).Name + "="))
+			writeValue(h, v.Field(i))
+		}
+		h.Write([]byte(")"))
+	default:
+		fmt.Fprintf(h, "%v;", v.Interface())
+	}
+}
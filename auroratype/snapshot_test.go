@@ -0,0 +1,40 @@
+package auroratype
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContentVersion(t *testing.T) {
+	params := map[string]Parameter{
+		"a": {DefaultValue: map[interface{}]interface{}{"x": 1, "y": []interface{}{"z"}}},
+		"b": {DefaultValue: true, Rules: []Rule{{RolloutValue: false}}},
+	}
+	same := map[string]Parameter{
+		"b": {DefaultValue: true, Rules: []Rule{{RolloutValue: false}}},
+		"a": {DefaultValue: map[interface{}]interface{}{"y": []interface{}{"z"}, "x": 1}},
+	}
+	changed := map[string]Parameter{
+		"a": {DefaultValue: map[interface{}]interface{}{"x": 2, "y": []interface{}{"z"}}},
+		"b": {DefaultValue: true, Rules: []Rule{{RolloutValue: false}}},
+	}
+
+	assert.Equal(t, ContentVersion(params, nil), ContentVersion(same, nil))
+	assert.NotEqual(t, ContentVersion(params, nil), ContentVersion(changed, nil))
+	assert.NotEqual(t, ContentVersion(params, nil), ContentVersion(params, []Experiment{{ID: "exp"}}))
+}
+
+func TestNewSnapshot(t *testing.T) {
+	now := time.Now()
+	s := NewSnapshot(nil, nil, "test", now)
+
+	assert.NotNil(t, s.Parameters)
+	assert.Equal(t, "test", s.Source)
+	assert.Equal(t, now, s.FetchedAt)
+	assert.NotEmpty(t, s.Version)
+
+	_, ok := s.Parameter("missing")
+	assert.False(t, ok)
+}
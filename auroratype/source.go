@@ -0,0 +1,27 @@
+package auroratype
+
+import "context"
+
+// SourceContent is the configuration held by a WritableSource, as stored:
+// environment sections are in place and encrypted values are not
+// decrypted.
+type SourceContent struct {
+	Parameters  map[string]Parameter
+	Experiments []Experiment
+	// ParametersRevision and ExperimentsRevision identify the stored
+	// objects, e.g. a content hash or an ETag. They are empty for objects
+	// that do not exist yet.
+	ParametersRevision  string
+	ExperimentsRevision string
+}
+
+// WritableSource is implemented by fetchers whose configuration can be
+// written back, so that changes made at runtime persist. Writes are
+// optimistic: each is based on the revision returned by Load or by the
+// previous write, and fails with ErrConflict if the stored object has
+// changed since. A successful write returns the new revision.
+type WritableSource interface {
+	Load(ctx context.Context) (SourceContent, error)
+	StoreParameters(ctx context.Context, parameters map[string]Parameter, revision string) (string, error)
+	StoreExperiments(ctx context.Context, experiments []Experiment, revision string) (string, error)
+}
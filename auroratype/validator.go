@@ -5,13 +5,19 @@ type ValidationError struct {
 	RuleIndex int
 	Field     string
 	Message   string
+	// Source is where the parameter was defined, if known.
+	Source string
 }
 
 func (e ValidationError) Error() string {
+	msg := "parameter \"" + e.Parameter + "\": " + e.Message
 	if e.RuleIndex >= 0 {
-		return "parameter \"" + e.Parameter + "\"." + e.Field + ": " + e.Message
+		msg = "parameter \"" + e.Parameter + "\"." + e.Field + ": " + e.Message
 	}
-	return "parameter \"" + e.Parameter + "\": " + e.Message
+	if e.Source != "" {
+		msg += " (in " + e.Source + ")"
+	}
+	return msg
 }
 
 type ValidationErrors struct {
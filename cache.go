@@ -0,0 +1,137 @@
+package core
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/tuannguyensn2001/aurora-go/auroratype"
+)
+
+const cacheHeaderPrefix = "aurora-cache v1 sha256="
+
+type CacheOptions struct {
+	// Path of the cache file. The directory must be writable so the file
+	// can be replaced atomically.
+	Path string
+	// MaxAge rejects cached snapshots fetched longer ago than this. Zero
+	// accepts a cache of any age.
+	MaxAge time.Duration
+	// AgeMetric is the histogram the cache age in seconds is reported to
+	// while the client serves from the cache. Defaults to MetricCacheAge.
+	AgeMetric string
+}
+
+var (
+	ErrCacheChecksum = errors.New("cache checksum mismatch")
+	ErrCacheExpired  = errors.New("cache is older than max age")
+)
+
+// cachedSnapshot is the on-disk form of a snapshot. YAML keeps integer and
+// float values apart, which JSON would not.
+type cachedSnapshot struct {
+	Version     string                          `yaml:"version"`
+	ETag        string                          `yaml:"etag,omitempty"`
+	FetchedAt   time.Time                       `yaml:"fetchedAt"`
+	Source      string                          `yaml:"source"`
+	Sources     map[string]string               `yaml:"sources,omitempty"`
+	Parameters  map[string]auroratype.Parameter `yaml:"parameters"`
+	Experiments []auroratype.Experiment         `yaml:"experiments"`
+}
+
+// writeCache persists snapshot to path. The file is written to a temporary
+// file in the same directory and renamed into place, so readers see either
+// the old or the new cache, never a partial one.
+func writeCache(path string, snapshot *auroratype.Snapshot) error {
+	payload, err := yaml.Marshal(cachedSnapshot{
+		Version:     snapshot.Version,
+		ETag:        snapshot.ETag,
+		FetchedAt:   snapshot.FetchedAt,
+		Source:      snapshot.Source,
+		Sources:     snapshot.Sources,
+		Parameters:  snapshot.Parameters,
+		Experiments: snapshot.Experiments,
+	})
+	if err != nil {
+		return err
+	}
+
+	sum := sha256.Sum256(payload)
+	var buf bytes.Buffer
+	buf.WriteString(cacheHeaderPrefix + hex.EncodeToString(sum[:]) + "\n")
+	buf.Write(payload)
+
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(buf.Bytes()); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// readCache loads a snapshot written by writeCache and verifies its checksum
+// and age.
+func readCache(path string, maxAge time.Duration, now time.Time) (*auroratype.Snapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	header, payload, ok := bytes.Cut(data, []byte("\n"))
+	if !ok || !bytes.HasPrefix(header, []byte(cacheHeaderPrefix)) {
+		return nil, fmt.Errorf("invalid cache header in %s", path)
+	}
+
+	sum := sha256.Sum256(payload)
+	if string(header[len(cacheHeaderPrefix):]) != hex.EncodeToString(sum[:]) {
+		return nil, ErrCacheChecksum
+	}
+
+	var cached cachedSnapshot
+	if err := yaml.Unmarshal(payload, &cached); err != nil {
+		return nil, err
+	}
+
+	if maxAge > 0 && now.Sub(cached.FetchedAt) > maxAge {
+		return nil, ErrCacheExpired
+	}
+
+	parameters := cached.Parameters
+	if parameters == nil {
+		parameters = make(map[string]auroratype.Parameter)
+	}
+
+	return &auroratype.Snapshot{
+		Parameters:  parameters,
+		Experiments: cached.Experiments,
+		Version:     cached.Version,
+		ETag:        cached.ETag,
+		FetchedAt:   cached.FetchedAt,
+		Source:      cached.Source,
+		Sources:     cached.Sources,
+	}, nil
+}
@@ -0,0 +1,79 @@
+package core
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tuannguyensn2001/aurora-go/auroratype"
+	mocks "github.com/tuannguyensn2001/aurora-go/mocks"
+)
+
+func TestCacheRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "aurora.cache")
+	snapshot := auroratype.NewSnapshot(map[string]auroratype.Parameter{
+		"limit": {DefaultValue: 5, Rules: []auroratype.Rule{{RolloutValue: 1.5}}},
+	}, []auroratype.Experiment{{ID: "exp_001", Status: auroratype.StatusRunning}}, "test", time.Now())
+
+	require.NoError(t, writeCache(path, snapshot))
+
+	loaded, err := readCache(path, 0, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, snapshot.Version, loaded.Version)
+	assert.Equal(t, 5, loaded.Parameters["limit"].DefaultValue)
+	assert.Equal(t, 1.5, loaded.Parameters["limit"].Rules[0].RolloutValue)
+	assert.Equal(t, "exp_001", loaded.Experiments[0].ID)
+
+	_, err = readCache(path, time.Minute, time.Now().Add(time.Hour))
+	assert.ErrorIs(t, err, ErrCacheExpired)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, append(data, []byte("tampered: true\n")...), 0o644))
+
+	_, err = readCache(path, 0, time.Now())
+	assert.ErrorIs(t, err, ErrCacheChecksum)
+}
+
+func TestFetcherStorageFallsBackToCache(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "aurora.cache")
+	params := map[string]auroratype.Parameter{"limit": {DefaultValue: 5}}
+
+	healthy := new(mocks.MockFetcher)
+	healthy.On("IsStatic").Return(true)
+	healthy.On("Fetch", ctx).Return(params, nil)
+	healthy.On("FetchExperiments", ctx).Return(nil, nil)
+
+	s := NewFetcherStorage(healthy, WithCache(CacheOptions{Path: path}))
+	require.NoError(t, s.Start(ctx))
+	assert.False(t, s.Stale())
+	assert.FileExists(t, path)
+
+	failing := new(mocks.MockFetcher)
+	failing.On("IsStatic").Return(true)
+	failing.On("Fetch", ctx).Return(nil, assert.AnError)
+
+	s = NewFetcherStorage(failing, WithCache(CacheOptions{Path: path}))
+	require.NoError(t, s.Start(ctx))
+	assert.True(t, s.Stale())
+
+	param, err := s.Get(ctx, "limit")
+	require.NoError(t, err)
+	assert.Equal(t, 5, param.DefaultValue)
+
+	client := NewClient(s, ClientOptions{})
+	assert.True(t, client.Stale())
+	assert.Equal(t, 5, client.GetParameter(ctx, "limit", NewAttribute()).Value())
+
+	t.Run("expired cache is not used", func(t *testing.T) {
+		s := NewFetcherStorage(failing, WithCache(CacheOptions{Path: path, MaxAge: time.Nanosecond}))
+		assert.Error(t, s.Start(ctx))
+		assert.False(t, s.Stale())
+	})
+}
@@ -0,0 +1,285 @@
+package core
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"sync"
+
+	"github.com/tuannguyensn2001/aurora-go/auroratype"
+)
+
+// ChangeSet describes the difference between two consecutive snapshots.
+type ChangeSet struct {
+	PreviousVersion string
+	Version         string
+
+	AddedParameters    []string
+	RemovedParameters  []string
+	ModifiedParameters []string
+
+	AddedExperiments    []string
+	RemovedExperiments  []string
+	ModifiedExperiments []string
+}
+
+func (c ChangeSet) Empty() bool {
+	return len(c.AddedParameters) == 0 && len(c.RemovedParameters) == 0 && len(c.ModifiedParameters) == 0 &&
+		len(c.AddedExperiments) == 0 && len(c.RemovedExperiments) == 0 && len(c.ModifiedExperiments) == 0
+}
+
+// ParameterChanged reports whether name was added, removed or modified.
+func (c ChangeSet) ParameterChanged(name string) bool {
+	for _, list := range [][]string{c.AddedParameters, c.RemovedParameters, c.ModifiedParameters} {
+		for _, p := range list {
+			if p == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ExperimentsChanged reports whether any experiment was added, removed or
+// modified.
+func (c ChangeSet) ExperimentsChanged() bool {
+	return len(c.AddedExperiments) > 0 || len(c.RemovedExperiments) > 0 || len(c.ModifiedExperiments) > 0
+}
+
+func diffSnapshots(previous, next *auroratype.Snapshot) ChangeSet {
+	var changes ChangeSet
+	var prevParams map[string]auroratype.Parameter
+	var prevExperiments []auroratype.Experiment
+	if previous != nil {
+		changes.PreviousVersion = previous.Version
+		prevParams = previous.Parameters
+		prevExperiments = previous.Experiments
+	}
+	changes.Version = next.Version
+
+	for name, param := range next.Parameters {
+		old, ok := prevParams[name]
+		if !ok {
+			changes.AddedParameters = append(changes.AddedParameters, name)
+		} else if !reflect.DeepEqual(old, param) {
+			changes.ModifiedParameters = append(changes.ModifiedParameters, name)
+		}
+	}
+	for name := range prevParams {
+		if _, ok := next.Parameters[name]; !ok {
+			changes.RemovedParameters = append(changes.RemovedParameters, name)
+		}
+	}
+
+	prevByID := make(map[string]auroratype.Experiment, len(prevExperiments))
+	for _, exp := range prevExperiments {
+		prevByID[exp.ID] = exp
+	}
+	nextByID := make(map[string]auroratype.Experiment, len(next.Experiments))
+	for _, exp := range next.Experiments {
+		nextByID[exp.ID] = exp
+		old, ok := prevByID[exp.ID]
+		if !ok {
+			changes.AddedExperiments = append(changes.AddedExperiments, exp.ID)
+		} else if !reflect.DeepEqual(old, exp) {
+			changes.ModifiedExperiments = append(changes.ModifiedExperiments, exp.ID)
+		}
+	}
+	for id := range prevByID {
+		if _, ok := nextByID[id]; !ok {
+			changes.RemovedExperiments = append(changes.RemovedExperiments, id)
+		}
+	}
+
+	for _, list := range [][]string{
+		changes.AddedParameters, changes.RemovedParameters, changes.ModifiedParameters,
+		changes.AddedExperiments, changes.RemovedExperiments, changes.ModifiedExperiments,
+	} {
+		sort.Strings(list)
+	}
+
+	return changes
+}
+
+// changeNotifier delivers change sets to listeners. Change sets are queued
+// so that publishing never blocks a sync, and a single goroutine drains the
+// queue so every listener sees them in publish order.
+type changeNotifier struct {
+	mu        sync.Mutex
+	listeners map[int]func(ChangeSet)
+	order     []int
+	nextID    int
+	queue     []ChangeSet
+	draining  bool
+	idle      *sync.Cond
+}
+
+func newChangeNotifier() *changeNotifier {
+	n := &changeNotifier{
+		listeners: make(map[int]func(ChangeSet)),
+	}
+	n.idle = sync.NewCond(&n.mu)
+	return n
+}
+
+func (n *changeNotifier) subscribe(fn func(ChangeSet)) func() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	id := n.nextID
+	n.nextID++
+	n.listeners[id] = fn
+	n.order = append(n.order, id)
+
+	return func() {
+		n.mu.Lock()
+		defer n.mu.Unlock()
+		delete(n.listeners, id)
+	}
+}
+
+func (n *changeNotifier) publish(changes ChangeSet) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.queue = append(n.queue, changes)
+	if !n.draining {
+		n.draining = true
+		go n.drain()
+	}
+}
+
+func (n *changeNotifier) drain() {
+	for {
+		n.mu.Lock()
+		if len(n.queue) == 0 {
+			n.draining = false
+			n.idle.Broadcast()
+			n.mu.Unlock()
+			return
+		}
+		changes := n.queue[0]
+		n.queue = n.queue[1:]
+
+		listeners := make([]func(ChangeSet), 0, len(n.listeners))
+		alive := n.order[:0]
+		for _, id := range n.order {
+			if fn, ok := n.listeners[id]; ok {
+				listeners = append(listeners, fn)
+				alive = append(alive, id)
+			}
+		}
+		n.order = alive
+		n.mu.Unlock()
+
+		for _, fn := range listeners {
+			fn(changes)
+		}
+	}
+}
+
+// wait blocks until every queued change set has been delivered.
+func (n *changeNotifier) wait() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for n.draining {
+		n.idle.Wait()
+	}
+}
+
+// OnChange registers fn to be called after every sync that changes the
+// configuration. Calls are made from a single goroutine in sync order. The
+// returned function removes the callback.
+func (c *Client) OnChange(fn func(ChangeSet)) func() {
+	return c.storage.notifier.subscribe(fn)
+}
+
+// Watch returns a channel that receives the resolved value of parameterName
+// for attribute, first immediately and then whenever it changes: after a
+// configuration change, and after each experiment maintenance tick, which
+// picks up rules becoming effective, experiment lifecycle transitions and
+// new bandit weights. Only the latest value is kept if the receiver falls
+// behind. The channel is closed when ctx is done or the client is closed.
+// Watch does not emit exposures.
+func (c *Client) Watch(ctx context.Context, parameterName string, attribute *attribute) <-chan *resolvedValue {
+	w := &watcher{ch: make(chan *resolvedValue, 1)}
+
+	// subscribe before the first evaluation so that a change landing in
+	// between is not lost; mu holds the listener back until that value is
+	// sent, and each value is evaluated under mu so sends stay in order
+	var mu sync.Mutex
+	var current *resolvedValue
+	reevaluate := func() {
+		mu.Lock()
+		defer mu.Unlock()
+		next := c.GetParameter(ctx, parameterName, attribute, withoutTracking())
+		if next.matched == current.matched && reflect.DeepEqual(next.value, current.value) {
+			return
+		}
+		current = next
+		w.send(next)
+	}
+
+	mu.Lock()
+	unsubscribe := c.OnChange(func(changes ChangeSet) {
+		if changes.ParameterChanged(parameterName) || changes.ExperimentsChanged() {
+			reevaluate()
+		}
+	})
+
+	current = c.GetParameter(ctx, parameterName, attribute, withoutTracking())
+	w.send(current)
+	mu.Unlock()
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		defer w.close()
+		defer unsubscribe()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-c.closed:
+				return
+			case <-c.nextMaintenance():
+				reevaluate()
+			}
+		}
+	}()
+
+	return w.ch
+}
+
+type watcher struct {
+	mu     sync.Mutex
+	ch     chan *resolvedValue
+	closed bool
+}
+
+// send replaces any value the receiver has not consumed yet.
+func (w *watcher) send(value *resolvedValue) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return
+	}
+
+	select {
+	case w.ch <- value:
+		return
+	default:
+	}
+	select {
+	case <-w.ch:
+	default:
+	}
+	w.ch <- value
+}
+
+func (w *watcher) close() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.closed = true
+	close(w.ch)
+}
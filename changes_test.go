@@ -0,0 +1,200 @@
+package core
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tuannguyensn2001/aurora-go/auroratype"
+	mocks "github.com/tuannguyensn2001/aurora-go/mocks"
+)
+
+func TestDiffSnapshots(t *testing.T) {
+	previous := auroratype.NewSnapshot(map[string]auroratype.Parameter{
+		"kept":     {DefaultValue: 1},
+		"modified": {DefaultValue: 1},
+		"removed":  {DefaultValue: 1},
+	}, []auroratype.Experiment{{ID: "exp_kept"}, {ID: "exp_modified"}}, "", time.Now())
+
+	next := auroratype.NewSnapshot(map[string]auroratype.Parameter{
+		"kept":     {DefaultValue: 1},
+		"modified": {DefaultValue: 2},
+		"added":    {DefaultValue: 1},
+	}, []auroratype.Experiment{{ID: "exp_kept"}, {ID: "exp_modified", Priority: 1}, {ID: "exp_added"}}, "", time.Now())
+
+	changes := diffSnapshots(previous, next)
+
+	assert.Equal(t, previous.Version, changes.PreviousVersion)
+	assert.Equal(t, next.Version, changes.Version)
+	assert.Equal(t, []string{"added"}, changes.AddedParameters)
+	assert.Equal(t, []string{"removed"}, changes.RemovedParameters)
+	assert.Equal(t, []string{"modified"}, changes.ModifiedParameters)
+	assert.Equal(t, []string{"exp_added"}, changes.AddedExperiments)
+	assert.Equal(t, []string{"exp_modified"}, changes.ModifiedExperiments)
+	assert.Empty(t, changes.RemovedExperiments)
+
+	assert.True(t, diffSnapshots(next, next).Empty())
+}
+
+func TestChangeNotifierDeliversInOrder(t *testing.T) {
+	n := newChangeNotifier()
+
+	var got []string
+	n.subscribe(func(c ChangeSet) {
+		got = append(got, c.Version)
+	})
+	unsubscribe := n.subscribe(func(c ChangeSet) {})
+	unsubscribe()
+
+	for _, v := range []string{"1", "2", "3", "4", "5"} {
+		n.publish(ChangeSet{Version: v})
+	}
+	n.wait()
+
+	assert.Equal(t, []string{"1", "2", "3", "4", "5"}, got)
+}
+
+func TestClientOnChangeAndWatch(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	v1 := map[string]auroratype.Parameter{
+		"maxConnections": {DefaultValue: 10},
+		"other":          {DefaultValue: "a"},
+	}
+	v2 := map[string]auroratype.Parameter{
+		"maxConnections": {DefaultValue: 10},
+		"other":          {DefaultValue: "b"},
+	}
+	v3 := map[string]auroratype.Parameter{
+		"maxConnections": {DefaultValue: 20},
+		"other":          {DefaultValue: "b"},
+	}
+
+	mockFetcher := new(mocks.MockFetcher)
+	mockFetcher.On("IsStatic").Return(true)
+	mockFetcher.On("Fetch", ctx).Return(v1, nil).Once()
+	mockFetcher.On("FetchExperiments", ctx).Return(nil, nil)
+
+	s := NewFetcherStorage(mockFetcher)
+	require.NoError(t, s.Start(ctx))
+	s.notifier.wait()
+	client := NewClient(s, ClientOptions{})
+
+	var changes []ChangeSet
+	client.OnChange(func(c ChangeSet) {
+		changes = append(changes, c)
+	})
+
+	values := client.Watch(ctx, "maxConnections", NewAttribute())
+	assert.Equal(t, 10, (<-values).Value())
+
+	mockFetcher.On("Fetch", ctx).Return(v2, nil).Once()
+	require.NoError(t, s.sync(ctx))
+	mockFetcher.On("Fetch", ctx).Return(v3, nil).Once()
+	require.NoError(t, s.sync(ctx))
+	mockFetcher.On("Fetch", ctx).Return(v3, nil).Once()
+	require.NoError(t, s.sync(ctx))
+	s.notifier.wait()
+
+	require.Len(t, changes, 2)
+	assert.Equal(t, []string{"other"}, changes[0].ModifiedParameters)
+	assert.Equal(t, []string{"maxConnections"}, changes[1].ModifiedParameters)
+
+	select {
+	case v := <-values:
+		assert.Equal(t, 20, v.Value())
+	case <-time.After(time.Second):
+		t.Fatal("expected watch to emit the new value")
+	}
+
+	select {
+	case v := <-values:
+		t.Fatalf("unexpected value %v", v.Value())
+	default:
+	}
+
+	cancel()
+	_, open := <-values
+	assert.False(t, open)
+}
+
+func TestClientWatchChangeDuringFirstEvaluation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	rule := auroratype.Rule{
+		RolloutValue: 0,
+		Constraints:  []auroratype.Constraint{{Field: "plan", Operator: "syncing", Value: "pro"}},
+	}
+	v1 := map[string]auroratype.Parameter{"maxConnections": {DefaultValue: 10, Rules: []auroratype.Rule{rule}}}
+	v2 := map[string]auroratype.Parameter{"maxConnections": {DefaultValue: 20, Rules: []auroratype.Rule{rule}}}
+
+	mockFetcher := new(mocks.MockFetcher)
+	mockFetcher.On("IsStatic").Return(true)
+	mockFetcher.On("Fetch", ctx).Return(v1, nil).Once()
+	mockFetcher.On("Fetch", ctx).Return(v2, nil).Once()
+	mockFetcher.On("FetchExperiments", ctx).Return(nil, nil)
+
+	s := NewFetcherStorage(mockFetcher)
+	client := NewClient(s, ClientOptions{})
+	// the operator runs while Watch evaluates the first value, and lands a
+	// change in the middle of it that is delivered before it returns
+	delivered := make(chan struct{}, 1)
+	var once sync.Once
+	client.RegisterOperator("syncing", func(a, b any) bool {
+		once.Do(func() {
+			require.NoError(t, s.sync(ctx))
+			<-delivered
+		})
+		return false
+	})
+	require.NoError(t, s.Start(ctx))
+	s.notifier.wait()
+	client.OnChange(func(ChangeSet) { delivered <- struct{}{} })
+
+	attr := NewAttribute()
+	attr.Set("plan", "pro")
+	values := client.Watch(ctx, "maxConnections", attr)
+	assert.Eventually(t, func() bool {
+		select {
+		case v := <-values:
+			return v.Value() == 20
+		default:
+			return false
+		}
+	}, time.Second, time.Millisecond, "the change must reach the watcher")
+}
+
+func TestClientWatchRuleBecomingEffective(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	effectiveAt := time.Now().Unix() + 1
+	params := map[string]auroratype.Parameter{
+		"maxConnections": {DefaultValue: 10, Rules: []auroratype.Rule{{RolloutValue: 20, EffectiveAt: &effectiveAt}}},
+	}
+
+	mockFetcher := new(mocks.MockFetcher)
+	mockFetcher.On("IsStatic").Return(true)
+	mockFetcher.On("Fetch", ctx).Return(params, nil)
+	mockFetcher.On("FetchExperiments", ctx).Return(nil, nil)
+
+	client := NewClient(NewFetcherStorage(mockFetcher), ClientOptions{BanditUpdateInterval: 10 * time.Millisecond})
+	require.NoError(t, client.Start(ctx))
+
+	values := client.Watch(ctx, "maxConnections", NewAttribute())
+	assert.Equal(t, 10, (<-values).Value())
+
+	// no configuration change happens: the maintenance tick picks it up
+	select {
+	case v := <-values:
+		assert.Equal(t, 20, v.Value())
+	case <-time.After(3 * time.Second):
+		t.Fatal("expected watch to emit the value of the rule once effective")
+	}
+}
@@ -2,7 +2,10 @@ package core
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log/slog"
+	"sync"
 	"time"
 
 	"github.com/tuannguyensn2001/aurora-go/auroratype"
@@ -13,12 +16,35 @@ import (
 type ClientOptions struct {
 	Logger          *slog.Logger
 	MetricsRecorder MetricsRecorder
+	EventSink       EventSink
+	// BanditStore persists bandit weights so that all replicas converge.
+	// Defaults to an in-process store.
+	BanditStore experiment.BanditStore
+	// BanditUpdateInterval controls how often bandit weights are recomputed
+	// and experiment lifecycle transitions are checked. Defaults to one
+	// minute.
+	BanditUpdateInterval time.Duration
+	// BanditExposureTTL controls how long a unit's bandit exposure is
+	// remembered, so that its rewards are attributed across update windows
+	// and re-exposures are not counted as new trials. Defaults to one day.
+	BanditExposureTTL time.Duration
+	// AllowOverrides enables the per-call WithOverride, WithForcedVariant and
+	// WithBypassChecks options. They are ignored otherwise.
+	AllowOverrides bool
+	// Environment selects the environment sections of parameters and
+	// experiments to apply, e.g. "prod". Without it only the base
+	// configuration is served. Every environment is validated either way.
+	Environment string
 }
 
 type ParameterOption func(*parameterOptions)
 
 type parameterOptions struct {
-	strategy Storage
+	strategy     Storage
+	override     *any
+	forced       []experiment.EvaluateOption
+	bypassChecks bool
+	silent       bool
 }
 
 func WithStrategy(s Storage) ParameterOption {
@@ -27,20 +53,74 @@ func WithStrategy(s Storage) ParameterOption {
 	}
 }
 
+// WithOverride resolves the parameter to value for this call without
+// evaluating experiments or rules. Requires ClientOptions.AllowOverrides.
+func WithOverride(value any) ParameterOption {
+	return func(o *parameterOptions) {
+		o.override = &value
+	}
+}
+
+// WithForcedVariant assigns variantKey of experimentID for this call.
+// Requires ClientOptions.AllowOverrides.
+func WithForcedVariant(experimentID, variantKey string) ParameterOption {
+	return func(o *parameterOptions) {
+		o.forced = append(o.forced, experiment.WithForcedVariant(experimentID, variantKey))
+	}
+}
+
+// WithBypassChecks lets a variant forced with WithForcedVariant skip the
+// experiment's status, time and population checks. Requires
+// ClientOptions.AllowOverrides.
+func WithBypassChecks() ParameterOption {
+	return func(o *parameterOptions) {
+		o.bypassChecks = true
+	}
+}
+
+// withoutTracking evaluates without emitting exposures or bandit trials, for
+// evaluations that are not caused by a user seeing the value.
+func withoutTracking() ParameterOption {
+	return func(o *parameterOptions) {
+		o.silent = true
+	}
+}
+
 type Client struct {
 	storage          *fetcherStorage
 	engine           *engine
 	experimentEngine *experiment.Engine
+	bandit           *experiment.Bandit
+	banditInterval   time.Duration
+	allowOverrides   bool
 	logger           *slog.Logger
 	recorder         MetricsRecorder
+	sink             EventSink
+
+	closed    chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+
+	// maintained is closed and replaced after every maintenance tick, so
+	// that watchers re-evaluate values that change with time.
+	maintainedMu sync.Mutex
+	maintained   chan struct{}
 }
 
 func NewClient(storage *fetcherStorage, opts ClientOptions) *Client {
 	eng := newEngine()
 	eng.bootstrap()
 
+	bandit := experiment.NewBandit(opts.BanditStore)
+	bandit.SetExposureTTL(opts.BanditExposureTTL)
 	expEngine := experiment.NewEngine()
 	expEngine.Bootstrap()
+	expEngine.SetBandit(bandit)
+
+	banditInterval := opts.BanditUpdateInterval
+	if banditInterval <= 0 {
+		banditInterval = 1 * time.Minute
+	}
 
 	logger := opts.Logger
 	if logger == nil {
@@ -52,22 +132,131 @@ func NewClient(storage *fetcherStorage, opts ClientOptions) *Client {
 		recorder = NewNoopRecorder()
 	}
 
+	sink := opts.EventSink
+	if sink == nil {
+		sink = NewNoopSink()
+	}
+
 	if storage.logger == nil {
 		storage.logger = logger
 	}
+	storage.parameterOperators = eng.hasOperator
+	storage.experimentOperators = expEngine.HasOperator
+	storage.environment = opts.Environment
+
+	expEngine.SetTransitionHandler(func(experimentID string, from, to auroratype.ExperimentStatus) {
+		logger.Info("Experiment status changed", "experiment", experimentID, "from", from, "to", to)
+		recorder.Count(MetricExperimentTransitionTotal, 1, []string{"experiment:" + experimentID, "from:" + string(from), "to:" + string(to)})
+	})
 
 	return &Client{
 		storage:          storage,
 		engine:           eng,
 		experimentEngine: expEngine,
+		bandit:           bandit,
+		banditInterval:   banditInterval,
+		allowOverrides:   opts.AllowOverrides,
 		logger:           logger,
 		recorder:         recorder,
+		sink:             sink,
+		closed:           make(chan struct{}),
+		maintained:       make(chan struct{}),
 	}
 }
 
 func (c *Client) Start(ctx context.Context) error {
 	c.logger.Info("Starting Aurora client")
-	return c.storage.Start(ctx)
+	if err := c.storage.Start(ctx); err != nil {
+		return err
+	}
+
+	if experiments, err := c.storage.GetExperiments(ctx); err == nil {
+		c.experimentEngine.Observe(experiments, time.Now())
+		if err := c.bandit.Refresh(ctx, experiments); err != nil {
+			c.logger.Warn("Failed to load bandit weights", "error", err)
+		}
+	}
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		c.maintainExperiments(ctx)
+	}()
+
+	return nil
+}
+
+// maintainExperiments periodically reports lifecycle transitions and
+// recomputes bandit weights.
+func (c *Client) maintainExperiments(ctx context.Context) {
+	ticker := time.NewTicker(c.banditInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-c.closed:
+			return
+		case <-ticker.C:
+			c.maintain(ctx)
+			c.notifyMaintained()
+		}
+	}
+}
+
+func (c *Client) maintain(ctx context.Context) {
+	experiments, err := c.storage.GetExperiments(ctx)
+	if err != nil {
+		return
+	}
+	c.experimentEngine.Observe(experiments, time.Now())
+	if err := c.bandit.Update(ctx, experiments); err != nil {
+		c.logger.Error("Failed to update bandit weights", "error", err)
+		c.recorder.Count(MetricBanditUpdateTotal, 1, []string{"status:error"})
+		return
+	}
+	c.recorder.Count(MetricBanditUpdateTotal, 1, []string{"status:success"})
+}
+
+// nextMaintenance returns a channel closed after the next maintenance tick.
+func (c *Client) nextMaintenance() <-chan struct{} {
+	c.maintainedMu.Lock()
+	defer c.maintainedMu.Unlock()
+	return c.maintained
+}
+
+func (c *Client) notifyMaintained() {
+	c.maintainedMu.Lock()
+	defer c.maintainedMu.Unlock()
+	close(c.maintained)
+	c.maintained = make(chan struct{})
+}
+
+// Stale reports whether the client serves a cached snapshot because the
+// fetcher has not succeeded since startup.
+func (c *Client) Stale() bool {
+	return c.storage.Stale()
+}
+
+// LastSync returns the outcome of the most recent sync, including why the
+// last snapshot was rejected if it failed validation.
+func (c *Client) LastSync() SyncResult {
+	return c.storage.LastSync()
+}
+
+// Snapshot returns the configuration being served. Encrypted values are
+// decrypted, so parameters named in Snapshot.Encrypted must not be exposed.
+// The snapshot must not be modified.
+func (c *Client) Snapshot(ctx context.Context) (*auroratype.Snapshot, error) {
+	return c.storage.LoadSnapshot(ctx)
+}
+
+// FetchedSnapshot returns the configuration being served as it was fetched,
+// with encrypted values still encrypted, so that it can be passed on to
+// processes holding the keys. It is nil until a snapshot is loaded. The
+// snapshot must not be modified.
+func (c *Client) FetchedSnapshot() *auroratype.Snapshot {
+	return c.storage.LoadFetchedSnapshot()
 }
 
 func (c *Client) GetParameter(ctx context.Context, parameterName string, attribute *attribute, opts ...ParameterOption) *resolvedValue {
@@ -97,9 +286,29 @@ func (c *Client) GetParameter(ctx context.Context, parameterName string, attribu
 		strg = c.storage
 	}
 
+	hasOverrides := paramOpts.override != nil || len(paramOpts.forced) > 0 || paramOpts.bypassChecks
+	if hasOverrides && !c.allowOverrides {
+		c.logger.Warn("Ignoring parameter overrides, overrides are not allowed", "parameter", parameterName)
+		paramOpts.override = nil
+		paramOpts.forced = nil
+		paramOpts.bypassChecks = false
+	}
+
+	if paramOpts.override != nil {
+		c.recorder.Count("get_parameter", 1, []string{"status:override", "storage:" + storageTag})
+		return NewResolvedValue(*paramOpts.override, true).withDetails(EvaluationDetails{Reason: ReasonOverride})
+	}
+
+	evalOpts := paramOpts.forced
+	if paramOpts.bypassChecks {
+		evalOpts = append(evalOpts, experiment.WithBypassChecks())
+	}
+
+	view := loadView(ctx, strg)
+
 	if c.experimentEngine != nil {
-		experiments, err := strg.GetExperiments(ctx)
-		if err == nil && len(experiments) > 0 {
+		experiments := view.experiments
+		if len(experiments) > 0 {
 			attrMap := make(map[string]any)
 			if attribute != nil {
 				for k, v := range attribute.vals {
@@ -107,27 +316,62 @@ func (c *Client) GetParameter(ctx context.Context, parameterName string, attribu
 				}
 			}
 
-			result := c.experimentEngine.Evaluate(ctx, experiments, parameterName, attrMap)
-			if result.Matched {
-				value := result.Values[parameterName]
-				c.recorder.Count("experiment_matched", 1, []string{"experiment:" + result.ExperimentID, "variant:" + result.VariantKey})
-				return NewResolvedValue(value, true)
+			result := c.experimentEngine.Evaluate(ctx, experiments, parameterName, attrMap, evalOpts...)
+			value, hasValue := result.Values[parameterName]
+			if result.Matched && !hasValue {
+				c.logger.Warn("Experiment variant has no value for parameter, falling back to parameter rules",
+					"parameter", parameterName, "experiment", result.ExperimentID, "variant", result.VariantKey)
+				c.recorder.Count(MetricExperimentMissingValue, 1, []string{"experiment:" + result.ExperimentID, "variant:" + result.VariantKey})
+			}
+			if result.Matched && hasValue {
+				if result.Winner {
+					c.recorder.Count("experiment_matched", 1, []string{"experiment:" + result.ExperimentID, "variant:" + result.VariantKey, "reason:" + string(ReasonWinner)})
+					return NewResolvedValue(value, true).withDetails(EvaluationDetails{
+						Reason:       ReasonWinner,
+						ExperimentID: result.ExperimentID,
+						VariantKey:   result.VariantKey,
+					})
+				}
+
+				reason := ReasonExperiment
+				if result.Forced {
+					reason = ReasonForced
+				}
+				c.recorder.Count("experiment_matched", 1, []string{"experiment:" + result.ExperimentID, "variant:" + result.VariantKey, "reason:" + string(reason)})
+				if !paramOpts.silent {
+					c.emit(ctx, Event{
+						Type:         EventExposure,
+						Timestamp:    time.Now(),
+						ExperimentID: result.ExperimentID,
+						VariantKey:   result.VariantKey,
+						Parameter:    parameterName,
+						Reason:       reason,
+						Attributes:   attrMap,
+					})
+					c.recordBanditTrial(experiments, result, attrMap)
+				}
+				return NewResolvedValue(value, true).withDetails(EvaluationDetails{
+					Reason:       reason,
+					ExperimentID: result.ExperimentID,
+					VariantKey:   result.VariantKey,
+					Weights:      result.Weights,
+					Encrypted:    view.encrypted[parameterName],
+				})
 			}
 		}
 	}
 
-	var config auroratype.Parameter
-	var err error
-
-	config, err = strg.Get(ctx, parameterName)
+	config, err := view.get(ctx, parameterName)
 
 	if err != nil {
 		c.logger.Error("Failed to get parameter config", "parameter", parameterName, "error", err)
 		c.recorder.Count("get_parameter", 1, []string{"status:not_found", "storage:" + storageTag})
-		return NewResolvedValue(nil, false)
+		return NewResolvedValue(nil, false).withDetails(EvaluationDetails{Reason: ReasonNotFound})
 	}
 
 	result := c.engine.evaluateParameter(ctx, parameterName, config, attribute)
+	result.details.Source = view.sources[parameterName]
+	result.details.Encrypted = view.encrypted[parameterName]
 
 	if result.matched {
 		c.recorder.Count("get_parameter", 1, []string{"status:resolved", "storage:" + storageTag})
@@ -138,6 +382,139 @@ func (c *Client) GetParameter(ctx context.Context, parameterName string, attribu
 	return result
 }
 
+// configView is the configuration a single GetParameter call evaluates
+// against. When the storage supports snapshots both experiments and
+// parameters come from the same snapshot.
+type configView struct {
+	experiments []auroratype.Experiment
+	sources     map[string]string
+	encrypted   map[string]bool
+	get         func(ctx context.Context, parameterName string) (auroratype.Parameter, error)
+}
+
+func loadView(ctx context.Context, strg Storage) configView {
+	if ss, ok := strg.(SnapshotStorage); ok {
+		snapshot, err := ss.LoadSnapshot(ctx)
+		if err == nil && snapshot != nil {
+			return configView{
+				experiments: snapshot.Experiments,
+				sources:     snapshot.Sources,
+				encrypted:   snapshot.Encrypted,
+				get: func(ctx context.Context, parameterName string) (auroratype.Parameter, error) {
+					param, ok := snapshot.Parameter(parameterName)
+					if !ok {
+						return auroratype.Parameter{}, errors.New("parameter not found")
+					}
+					return param, nil
+				},
+			}
+		}
+	}
+
+	// experiments are optional, a storage error only disables them
+	experiments, err := strg.GetExperiments(ctx)
+	if err != nil {
+		experiments = nil
+	}
+	return configView{
+		experiments: experiments,
+		get:         strg.Get,
+	}
+}
+
+// Track records a conversion event for the given attributes. Conversions are
+// joined with exposures on the experiment's hash attribute during analysis, so
+// attrs must carry the same identifiers that were passed to GetParameter.
+func (c *Client) Track(ctx context.Context, eventName string, attribute *attribute, value float64) error {
+	attrMap := make(map[string]any)
+	if attribute != nil {
+		for k, v := range attribute.vals {
+			attrMap[k] = v
+		}
+	}
+
+	c.recordBanditReward(ctx, eventName, attrMap, value)
+
+	return c.emit(ctx, Event{
+		Type:       EventConversion,
+		Timestamp:  time.Now(),
+		Name:       eventName,
+		Value:      value,
+		Attributes: attrMap,
+	})
+}
+
+// recordBanditTrial counts an exposure in a bandit experiment. Forced
+// variants and declared winners are not chosen by the weights, so they are
+// not trials, and a unit first served a forced variant is not rewarded
+// either. A unit forced after an organic exposure keeps that trial.
+func (c *Client) recordBanditTrial(experiments []auroratype.Experiment, result *experiment.Evaluation, attrMap map[string]any) {
+	if result.Winner {
+		return
+	}
+	for _, exp := range experiments {
+		if exp.ID != result.ExperimentID {
+			continue
+		}
+		if !exp.IsBandit() {
+			return
+		}
+		unit := fmt.Sprintf("%v", attrMap[exp.HashAttribute])
+		if result.Forced {
+			c.bandit.Exclude(exp.ID, unit)
+			return
+		}
+		c.bandit.RecordTrial(exp.ID, result.VariantKey, unit)
+		return
+	}
+}
+
+// recordBanditReward attributes a reward event to the variant the attributes
+// were exposed to in every bandit experiment rewarding eventName. Units
+// without an unexpired trial, units only served a forced variant and
+// experiments serving a declared winner are not rewarded.
+// A non-positive value counts as a full reward.
+func (c *Client) recordBanditReward(ctx context.Context, eventName string, attrMap map[string]any, value float64) {
+	experiments, err := c.storage.GetExperiments(ctx)
+	if err != nil {
+		return
+	}
+
+	reward := value
+	if reward <= 0 {
+		reward = 1
+	}
+
+	now := time.Now()
+	for _, exp := range experiments {
+		if !exp.IsBandit() || exp.Bandit == nil || exp.Bandit.RewardEvent != eventName {
+			continue
+		}
+		if exp.Winner != "" && exp.EffectiveStatus(now) == auroratype.StatusFinished {
+			continue
+		}
+		unit, ok := attrMap[exp.HashAttribute]
+		if !ok {
+			continue
+		}
+		key := fmt.Sprintf("%v", unit)
+		if _, forced := exp.ForcedVariants[key]; forced {
+			continue
+		}
+		c.bandit.RecordReward(exp.ID, key, reward)
+	}
+}
+
+func (c *Client) emit(ctx context.Context, event Event) error {
+	if err := c.sink.Emit(ctx, event); err != nil {
+		c.logger.Error("Failed to emit event", "type", event.Type, "error", err)
+		c.recorder.Count(MetricEventEmitTotal, 1, []string{"type:" + string(event.Type), "status:error"})
+		return err
+	}
+	c.recorder.Count(MetricEventEmitTotal, 1, []string{"type:" + string(event.Type), "status:success"})
+	return nil
+}
+
 func (c *Client) RegisterOperator(name string, fn func(a, b any) bool) {
 	c.logger.Info("Registering custom operator", "operator", name)
 	c.engine.registerOperator(evaluator.Operator(name), fn)
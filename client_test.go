@@ -1,12 +1,17 @@
 package core
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/tuannguyensn2001/aurora-go/auroratype"
+	"github.com/tuannguyensn2001/aurora-go/experiment"
 	mocks "github.com/tuannguyensn2001/aurora-go/mocks"
 )
 
@@ -181,3 +186,425 @@ func TestClientGetParameterWithDefaultValue(t *testing.T) {
 	assert.False(t, result.matched)
 	assert.Equal(t, "fallback", result.value)
 }
+
+func TestClientEmitsExposureAndConversion(t *testing.T) {
+	ctx := context.Background()
+	experiments := []auroratype.Experiment{
+		{
+			ID:             "exp_001",
+			Name:           "Checkout",
+			Parameters:     []string{"checkoutButton"},
+			HashAttribute:  "userID",
+			PopulationSize: 100,
+			Status:         auroratype.StatusRunning,
+			Variants: []auroratype.Variant{
+				{Key: "control", Rollout: 100, Values: map[string]interface{}{"checkoutButton": "blue"}},
+			},
+		},
+	}
+
+	mockFetcher := new(mocks.MockFetcher)
+	mockFetcher.On("IsStatic").Return(true)
+	mockFetcher.On("Fetch", ctx).Return(make(map[string]auroratype.Parameter), nil)
+	mockFetcher.On("FetchExperiments", ctx).Return(experiments, nil)
+
+	s := NewFetcherStorage(mockFetcher)
+	assert.NoError(t, s.Start(ctx))
+
+	var buf bytes.Buffer
+	client := NewClient(s, ClientOptions{EventSink: NewJSONLSink(&buf)})
+
+	attr := NewAttribute()
+	attr.Set("userID", "user_1")
+	result := client.GetParameter(ctx, "checkoutButton", attr)
+	assert.Equal(t, "blue", result.String(""))
+	assert.Equal(t, ReasonExperiment, result.Details().Reason)
+	assert.Equal(t, "control", result.Details().VariantKey)
+	assert.NoError(t, client.Track(ctx, "purchase", attr, 12.5))
+
+	var events []Event
+	dec := json.NewDecoder(&buf)
+	for dec.More() {
+		var e Event
+		assert.NoError(t, dec.Decode(&e))
+		events = append(events, e)
+	}
+
+	assert.Len(t, events, 2)
+	assert.Equal(t, EventExposure, events[0].Type)
+	assert.Equal(t, "exp_001", events[0].ExperimentID)
+	assert.Equal(t, "control", events[0].VariantKey)
+	assert.Equal(t, "user_1", events[0].Attributes["userID"])
+	assert.Equal(t, EventConversion, events[1].Type)
+	assert.Equal(t, "purchase", events[1].Name)
+	assert.Equal(t, 12.5, events[1].Value)
+}
+
+func banditExperiment() auroratype.Experiment {
+	return auroratype.Experiment{
+		ID:             "exp_bandit",
+		Name:           "Headline",
+		Type:           auroratype.TypeBandit,
+		Parameters:     []string{"headline"},
+		HashAttribute:  "userID",
+		PopulationSize: 100,
+		Status:         auroratype.StatusRunning,
+		Bandit:         &auroratype.BanditConfig{Algorithm: auroratype.BanditEpsilonGreedy, RewardEvent: "click"},
+		Variants: []auroratype.Variant{
+			{Key: "a", Rollout: 50, Values: map[string]interface{}{"headline": "A"}},
+			{Key: "b", Rollout: 50, Values: map[string]interface{}{"headline": "B"}},
+		},
+	}
+}
+
+func newBanditClient(t *testing.T, experiments []auroratype.Experiment, opts ClientOptions) (*Client, *experiment.MemoryBanditStore) {
+	t.Helper()
+	ctx := context.Background()
+	mockFetcher := new(mocks.MockFetcher)
+	mockFetcher.On("IsStatic").Return(true)
+	mockFetcher.On("Fetch", ctx).Return(make(map[string]auroratype.Parameter), nil)
+	mockFetcher.On("FetchExperiments", ctx).Return(experiments, nil)
+
+	s := NewFetcherStorage(mockFetcher)
+	require.NoError(t, s.Start(ctx))
+
+	store := experiment.NewMemoryBanditStore()
+	opts.BanditStore = store
+	return NewClient(s, opts), store
+}
+
+func TestClientBanditRewardsExposedVariant(t *testing.T) {
+	ctx := context.Background()
+	experiments := []auroratype.Experiment{banditExperiment()}
+	client, store := newBanditClient(t, experiments, ClientOptions{})
+
+	attr := NewAttribute()
+	attr.Set("userID", "user_1")
+	variant := client.GetParameter(ctx, "headline", attr).Details().VariantKey
+	require.NotEmpty(t, variant)
+
+	// weights moving all traffic away from the exposed variant must not
+	// move its reward
+	other := "a"
+	if variant == "a" {
+		other = "b"
+	}
+	require.NoError(t, store.Update(ctx, "exp_bandit", func(state *experiment.BanditState) error {
+		state.Weights = map[string]float64{variant: 0, other: 1}
+		return nil
+	}))
+	require.NoError(t, client.bandit.Refresh(ctx, experiments))
+	require.Equal(t, other, client.GetParameter(ctx, "headline", attr).Details().VariantKey)
+
+	assert.NoError(t, client.Track(ctx, "click", attr, 1))
+	assert.NoError(t, client.Track(ctx, "click", attr, 1))
+	unexposed := NewAttribute()
+	unexposed.Set("userID", "user_2")
+	assert.NoError(t, client.Track(ctx, "click", unexposed, 1))
+
+	require.NoError(t, client.bandit.Update(ctx, experiments))
+	state, err := store.Load(ctx, "exp_bandit")
+	require.NoError(t, err)
+	assert.Equal(t, experiment.ArmStats{Trials: 1, Rewards: 1}, state.Arms[variant])
+	assert.Equal(t, experiment.ArmStats{}, state.Arms[other])
+}
+
+func TestClientBanditIgnoresForcedAndWinners(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("forced variants", func(t *testing.T) {
+		exp := banditExperiment()
+		exp.ForcedVariants = map[string]string{"qa_config": "b"}
+		experiments := []auroratype.Experiment{exp}
+		client, store := newBanditClient(t, experiments, ClientOptions{AllowOverrides: true})
+
+		configured := NewAttribute()
+		configured.Set("userID", "qa_config")
+		assert.Equal(t, ReasonForced, client.GetParameter(ctx, "headline", configured).Details().Reason)
+		assert.NoError(t, client.Track(ctx, "click", configured, 1))
+
+		// a unit exposed organically, then forced through a QA override
+		override := NewAttribute()
+		override.Set("userID", "qa_override")
+		assert.Equal(t, ReasonExperiment, client.GetParameter(ctx, "headline", override).Details().Reason)
+		assert.Equal(t, ReasonForced, client.GetParameter(ctx, "headline", override, WithForcedVariant("exp_bandit", "a")).Details().Reason)
+		assert.NoError(t, client.Track(ctx, "click", override, 1))
+
+		require.NoError(t, client.bandit.Update(ctx, experiments))
+		state, err := store.Load(ctx, "exp_bandit")
+		require.NoError(t, err)
+		var trials int64
+		var rewards float64
+		for _, arm := range state.Arms {
+			trials += arm.Trials
+			rewards += arm.Rewards
+		}
+		assert.Equal(t, int64(1), trials, "only the organic exposure is a trial")
+		assert.Equal(t, 1.0, rewards, "the organic trial keeps its reward")
+	})
+
+	t.Run("declared winner", func(t *testing.T) {
+		exp := banditExperiment()
+		exp.Status = auroratype.StatusFinished
+		exp.Winner = "b"
+		experiments := []auroratype.Experiment{exp}
+		client, store := newBanditClient(t, experiments, ClientOptions{})
+
+		attr := NewAttribute()
+		attr.Set("userID", "user_1")
+		assert.Equal(t, ReasonWinner, client.GetParameter(ctx, "headline", attr).Details().Reason)
+		assert.NoError(t, client.Track(ctx, "click", attr, 1))
+
+		require.NoError(t, client.bandit.Update(ctx, experiments))
+		state, err := store.Load(ctx, "exp_bandit")
+		require.NoError(t, err)
+		assert.Zero(t, state.Arms["b"])
+	})
+}
+
+func TestClientOverrides(t *testing.T) {
+	ctx := context.Background()
+	param := auroratype.Parameter{DefaultValue: "default"}
+	experiments := []auroratype.Experiment{
+		{
+			ID:             "exp_001",
+			Name:           "Checkout",
+			Parameters:     []string{"checkoutButton"},
+			HashAttribute:  "userID",
+			PopulationSize: 100,
+			Status:         auroratype.StatusScheduled,
+			Variants: []auroratype.Variant{
+				{Key: "control", Rollout: 50, Values: map[string]interface{}{"checkoutButton": "blue"}},
+				{Key: "treatment", Rollout: 50, Values: map[string]interface{}{"checkoutButton": "green"}},
+			},
+		},
+	}
+
+	mockFetcher := new(mocks.MockFetcher)
+	mockFetcher.On("IsStatic").Return(true)
+	mockFetcher.On("Fetch", ctx).Return(map[string]auroratype.Parameter{"checkoutButton": param}, nil)
+	mockFetcher.On("FetchExperiments", ctx).Return(experiments, nil)
+
+	s := NewFetcherStorage(mockFetcher)
+	assert.NoError(t, s.Start(ctx))
+
+	attr := NewAttribute()
+	attr.Set("userID", "qa_user")
+
+	t.Run("overrides ignored unless allowed", func(t *testing.T) {
+		client := NewClient(s, ClientOptions{})
+		result := client.GetParameter(ctx, "checkoutButton", attr, WithOverride("red"))
+		assert.Equal(t, "default", result.value)
+		assert.Equal(t, ReasonDefault, result.Details().Reason)
+	})
+
+	client := NewClient(s, ClientOptions{AllowOverrides: true})
+
+	t.Run("value override", func(t *testing.T) {
+		result := client.GetParameter(ctx, "checkoutButton", attr, WithOverride("red"))
+		assert.Equal(t, "red", result.String(""))
+		assert.Equal(t, ReasonOverride, result.Details().Reason)
+	})
+
+	t.Run("forced variant requires bypass for scheduled experiment", func(t *testing.T) {
+		result := client.GetParameter(ctx, "checkoutButton", attr, WithForcedVariant("exp_001", "treatment"))
+		assert.Equal(t, "default", result.value)
+
+		result = client.GetParameter(ctx, "checkoutButton", attr, WithForcedVariant("exp_001", "treatment"), WithBypassChecks())
+		assert.Equal(t, "green", result.String(""))
+		assert.Equal(t, ReasonForced, result.Details().Reason)
+		assert.Equal(t, "treatment", result.Details().VariantKey)
+	})
+}
+
+func TestClientExperimentMissingValueFallsBack(t *testing.T) {
+	ctx := context.Background()
+	param := auroratype.Parameter{
+		DefaultValue: "default",
+		Rules: []auroratype.Rule{
+			{
+				RolloutValue: "rule_value",
+				Constraints: []auroratype.Constraint{
+					{Field: "country", Operator: "equal", Value: "US"},
+				},
+			},
+		},
+	}
+	experiments := []auroratype.Experiment{
+		{
+			ID:             "exp_001",
+			Name:           "Checkout",
+			Parameters:     []string{"checkoutButton", "titleText"},
+			HashAttribute:  "userID",
+			PopulationSize: 100,
+			Status:         auroratype.StatusRunning,
+			Variants: []auroratype.Variant{
+				{Key: "control", Rollout: 100, Values: map[string]interface{}{"checkoutButton": "blue"}},
+			},
+		},
+	}
+
+	mockFetcher := new(mocks.MockFetcher)
+	mockFetcher.On("IsStatic").Return(true)
+	mockFetcher.On("Fetch", ctx).Return(map[string]auroratype.Parameter{"titleText": param}, nil)
+	mockFetcher.On("FetchExperiments", ctx).Return(experiments, nil)
+
+	// the incomplete variant would be rejected by validation
+	s := NewFetcherStorage(mockFetcher, WithSnapshotValidation(false))
+	assert.NoError(t, s.Start(ctx))
+	client := NewClient(s, ClientOptions{})
+
+	attr := NewAttribute()
+	attr.Set("userID", "user_1")
+	attr.Set("country", "US")
+
+	result := client.GetParameter(ctx, "titleText", attr)
+	assert.Equal(t, "rule_value", result.String(""))
+	assert.Equal(t, ReasonRuleMatch, result.Details().Reason)
+}
+
+func TestClientRejectsUnknownOperator(t *testing.T) {
+	ctx := context.Background()
+	param := auroratype.Parameter{
+		DefaultValue: "default",
+		Rules: []auroratype.Rule{
+			{
+				RolloutValue: "match",
+				Constraints: []auroratype.Constraint{
+					{Field: "email", Operator: "startsWith", Value: "admin"},
+				},
+			},
+		},
+	}
+
+	mockFetcher := new(mocks.MockFetcher)
+	mockFetcher.On("IsStatic").Return(true)
+	mockFetcher.On("Fetch", ctx).Return(map[string]auroratype.Parameter{"test_param": param}, nil)
+	mockFetcher.On("FetchExperiments", ctx).Return(nil, nil)
+
+	client := NewClient(NewFetcherStorage(mockFetcher), ClientOptions{})
+	err := client.Start(ctx)
+
+	var validationErrors auroratype.ValidationErrors
+	require.ErrorAs(t, err, &validationErrors)
+	assert.Equal(t, "constraints[0].operator", validationErrors.Errors[0].Field)
+	assert.True(t, client.LastSync().Rejected)
+
+	client = NewClient(NewFetcherStorage(mockFetcher), ClientOptions{})
+	client.RegisterOperator("startsWith", func(a, b any) bool {
+		s, ok1 := a.(string)
+		prefix, ok2 := b.(string)
+		return ok1 && ok2 && strings.HasPrefix(s, prefix)
+	})
+	require.NoError(t, client.Start(ctx))
+	assert.False(t, client.LastSync().Rejected)
+
+	attr := NewAttribute()
+	attr.Set("email", "admin@example.com")
+	assert.Equal(t, "match", client.GetParameter(ctx, "test_param", attr).String(""))
+}
+
+type locatingFetcher struct {
+	*mocks.MockFetcher
+	sources map[string]string
+}
+
+func (f *locatingFetcher) ParameterSources() map[string]string {
+	return f.sources
+}
+
+func TestClientReportsParameterSources(t *testing.T) {
+	ctx := context.Background()
+	param := auroratype.Parameter{
+		DefaultValue: "default",
+		Rules: []auroratype.Rule{
+			{
+				RolloutValue: "match",
+				Constraints: []auroratype.Constraint{
+					{Field: "email", Operator: "startsWith", Value: "admin"},
+				},
+			},
+		},
+	}
+
+	mockFetcher := new(mocks.MockFetcher)
+	mockFetcher.On("IsStatic").Return(true)
+	mockFetcher.On("Fetch", ctx).Return(map[string]auroratype.Parameter{"test_param": param}, nil)
+	mockFetcher.On("FetchExperiments", ctx).Return(nil, nil)
+	fetcher := &locatingFetcher{MockFetcher: mockFetcher, sources: map[string]string{"test_param": "config/test.yaml"}}
+
+	client := NewClient(NewFetcherStorage(fetcher), ClientOptions{})
+	err := client.Start(ctx)
+
+	var validationErrors auroratype.ValidationErrors
+	require.ErrorAs(t, err, &validationErrors)
+	assert.Equal(t, "config/test.yaml", validationErrors.Errors[0].Source)
+	assert.Contains(t, err.Error(), "config/test.yaml")
+
+	client = NewClient(NewFetcherStorage(fetcher), ClientOptions{})
+	client.RegisterOperator("startsWith", func(a, b any) bool {
+		s, ok1 := a.(string)
+		prefix, ok2 := b.(string)
+		return ok1 && ok2 && strings.HasPrefix(s, prefix)
+	})
+	require.NoError(t, client.Start(ctx))
+
+	result := client.GetParameter(ctx, "test_param", NewAttribute())
+	assert.Equal(t, ReasonDefault, result.Details().Reason)
+	assert.Equal(t, "config/test.yaml", result.Details().Source)
+}
+
+func TestClientEnvironment(t *testing.T) {
+	ctx := context.Background()
+	invalid := 150
+	param := auroratype.Parameter{
+		DefaultValue: 10,
+		Environments: map[string]auroratype.ParameterOverlay{
+			"prod": {DefaultValue: 100},
+		},
+	}
+
+	mockFetcher := new(mocks.MockFetcher)
+	mockFetcher.On("IsStatic").Return(true)
+	mockFetcher.On("Fetch", ctx).Return(map[string]auroratype.Parameter{"limit": param}, nil)
+	mockFetcher.On("FetchExperiments", ctx).Return(nil, nil)
+
+	client := NewClient(NewFetcherStorage(mockFetcher), ClientOptions{Environment: "prod"})
+	require.NoError(t, client.Start(ctx))
+	assert.Equal(t, 100, client.GetParameter(ctx, "limit", NewAttribute()).Value())
+
+	client = NewClient(NewFetcherStorage(mockFetcher), ClientOptions{})
+	require.NoError(t, client.Start(ctx))
+	assert.Equal(t, 10, client.GetParameter(ctx, "limit", NewAttribute()).Value())
+
+	// an invalid overlay is rejected even when its environment is not active
+	rules := []auroratype.Rule{{RolloutValue: 1, Percentage: &invalid, HashAttribute: new(string)}}
+	param.Environments["staging"] = auroratype.ParameterOverlay{Rules: &rules}
+	mockFetcher = new(mocks.MockFetcher)
+	mockFetcher.On("IsStatic").Return(true)
+	mockFetcher.On("Fetch", ctx).Return(map[string]auroratype.Parameter{"limit": param}, nil)
+	mockFetcher.On("FetchExperiments", ctx).Return(nil, nil)
+
+	client = NewClient(NewFetcherStorage(mockFetcher), ClientOptions{Environment: "prod"})
+	err := client.Start(ctx)
+	var validationErrors auroratype.ValidationErrors
+	require.ErrorAs(t, err, &validationErrors)
+	assert.Contains(t, err.Error(), `environment "staging"`)
+}
+
+func TestClientSnapshot(t *testing.T) {
+	ctx := context.Background()
+	mockFetcher := new(mocks.MockFetcher)
+	mockFetcher.On("IsStatic").Return(true)
+	mockFetcher.On("Fetch", ctx).Return(map[string]auroratype.Parameter{"limit": {DefaultValue: 10}}, nil)
+	mockFetcher.On("FetchExperiments", ctx).Return([]auroratype.Experiment{}, nil)
+
+	client := NewClient(NewFetcherStorage(mockFetcher), ClientOptions{})
+	require.NoError(t, client.Start(ctx))
+
+	snapshot, err := client.Snapshot(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, snapshot)
+	assert.Equal(t, 10, snapshot.Parameters["limit"].DefaultValue)
+	assert.NotEmpty(t, snapshot.Version)
+}
@@ -0,0 +1,163 @@
+// Command aurora-convert converts Aurora configuration between YAML, JSON
+// and the binary snapshot format, compressing or decompressing it along the
+// way.
+//
+// Usage:
+//
+//	aurora-convert -parameters parameters.yaml -experiments experiments.yaml -o snapshot.aurora.zst
+//	aurora-convert -parameters snapshot.aurora.zst -o parameters.json -experiments-out experiments.json
+//
+// Formats follow the file extensions: .yaml or .yml, .json, and .aurora for
+// binary snapshots, optionally followed by .gz or .zst. Input may also be
+// TOML or any other format with a registered codec. A binary snapshot holds
+// both parameters and experiments.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/tuannguyensn2001/aurora-go/auroratype"
+)
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, "aurora-convert:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+	flags := flag.NewFlagSet("aurora-convert", flag.ContinueOnError)
+	parametersIn := flags.String("parameters", "", "parameters file or binary snapshot to read")
+	experimentsIn := flags.String("experiments", "", "experiments file to read")
+	parametersOut := flags.String("o", "", "parameters file or binary snapshot to write")
+	experimentsOut := flags.String("experiments-out", "", "experiments file to write, unless -o is a binary snapshot")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *parametersIn == "" || *parametersOut == "" {
+		return fmt.Errorf("-parameters and -o are required")
+	}
+
+	parameters, experiments, err := readParameters(*parametersIn)
+	if err != nil {
+		return err
+	}
+	if *experimentsIn != "" {
+		if experiments, err = readExperiments(*experimentsIn); err != nil {
+			return err
+		}
+	}
+
+	format, err := formatOf(*parametersOut)
+	if err != nil {
+		return err
+	}
+	if format == auroratype.FormatBinary {
+		if *experimentsOut != "" {
+			return fmt.Errorf("-experiments-out cannot be used with a binary snapshot")
+		}
+		data, err := auroratype.MarshalBinarySnapshot(parameters, experiments)
+		if err != nil {
+			return err
+		}
+		return writeFile(*parametersOut, data)
+	}
+
+	if err := write(*parametersOut, parameters); err != nil {
+		return err
+	}
+	if *experimentsOut != "" {
+		return write(*experimentsOut, map[string][]auroratype.Experiment{"experiments": experiments})
+	}
+	if len(experiments) > 0 {
+		fmt.Fprintf(os.Stderr, "aurora-convert: %d experiments not written, use -experiments-out\n", len(experiments))
+	}
+	return nil
+}
+
+// formatOf returns the output format, "yaml", "json" or "binary", from
+// the extension of name, ignoring a compression extension.
+func formatOf(name string) (string, error) {
+	name, _ = auroratype.SplitCompression(name)
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".yaml", ".yml":
+		return "yaml", nil
+	case ".json":
+		return "json", nil
+	case auroratype.BinarySnapshotExtension:
+		return auroratype.FormatBinary, nil
+	}
+	return "", fmt.Errorf("%s: unsupported format: must be .yaml, .yml, .json or .aurora", name)
+}
+
+// readFile reads and decompresses name, returning its content and format.
+// Any format with a registered codec can be read.
+func readFile(name string) ([]byte, string, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, "", err
+	}
+	_, encoding := auroratype.SplitCompression(name)
+	if data, err = auroratype.Decompress(data, encoding); err != nil {
+		return nil, "", fmt.Errorf("%s: %w", name, err)
+	}
+	return data, auroratype.FormatOf(name, "", data), nil
+}
+
+func readParameters(name string) (map[string]auroratype.Parameter, []auroratype.Experiment, error) {
+	data, format, err := readFile(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	if format == auroratype.FormatBinary {
+		return auroratype.UnmarshalBinarySnapshot(data)
+	}
+	parameters, err := auroratype.DecodeParameters(data, format)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return parameters, nil, nil
+}
+
+func readExperiments(name string) ([]auroratype.Experiment, error) {
+	data, format, err := readFile(name)
+	if err != nil {
+		return nil, err
+	}
+	experiments, err := auroratype.DecodeExperiments(data, format)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return experiments, nil
+}
+
+// write encodes v with the codec for the extension of name.
+func write(name string, v any) error {
+	format, err := formatOf(name)
+	if err != nil {
+		return err
+	}
+	if format == auroratype.FormatBinary {
+		return fmt.Errorf("%s: a binary snapshot holds both parameters and experiments", name)
+	}
+
+	data, err := auroratype.Encode(v, format)
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return writeFile(name, data)
+}
+
+func writeFile(name string, data []byte) error {
+	_, encoding := auroratype.SplitCompression(name)
+	data, err := auroratype.Compress(data, encoding)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(name, data, 0o644)
+}
@@ -0,0 +1,112 @@
+// Command aurora-encrypt encrypts parameter values for Aurora
+// configuration files.
+//
+// Usage:
+//
+//	aurora-encrypt -generate > keys/main.key
+//	aurora-encrypt -key keys/main.key 'https://partner.example.com'
+//	echo '{"retries": 3}' | aurora-encrypt -key keys/main.key -json -format json
+//
+// The key ID defaults to the key file name without its extension, which is
+// how the file key provider looks keys up.
+package main
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	aurora "github.com/tuannguyensn2001/aurora-go"
+	"github.com/tuannguyensn2001/aurora-go/auroratype"
+)
+
+func main() {
+	if err := run(os.Args[1:], os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "aurora-encrypt:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdin io.Reader, stdout io.Writer) error {
+	flags := flag.NewFlagSet("aurora-encrypt", flag.ContinueOnError)
+	keyFile := flags.String("key", "", "file holding the base64-encoded AES key")
+	keyID := flags.String("key-id", "", "key ID recorded in the ciphertext (default: key file name)")
+	isJSON := flags.Bool("json", false, "parse the value as JSON instead of taking it as a string")
+	format := flags.String("format", "yaml", "output format: yaml, json or raw")
+	generate := flags.Bool("generate", false, "print a new random 256-bit key and exit")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *generate {
+		key := make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return err
+		}
+		_, err := fmt.Fprintln(stdout, base64.StdEncoding.EncodeToString(key))
+		return err
+	}
+
+	if *keyFile == "" {
+		return fmt.Errorf("-key is required")
+	}
+	id := *keyID
+	if id == "" {
+		id = strings.TrimSuffix(filepath.Base(*keyFile), filepath.Ext(*keyFile))
+	}
+	key, err := readKey(*keyFile)
+	if err != nil {
+		return err
+	}
+
+	raw := strings.Join(flags.Args(), " ")
+	if flags.NArg() == 0 {
+		data, err := io.ReadAll(stdin)
+		if err != nil {
+			return err
+		}
+		raw = strings.TrimSuffix(string(data), "\n")
+	}
+
+	var value any = raw
+	if *isJSON {
+		if err := json.Unmarshal([]byte(raw), &value); err != nil {
+			return fmt.Errorf("parsing value: %w", err)
+		}
+	}
+
+	ciphertext, err := aurora.Encrypt(key, id, value)
+	if err != nil {
+		return err
+	}
+
+	switch *format {
+	case "yaml":
+		_, err = fmt.Fprintln(stdout, auroratype.EncryptedTag+" "+ciphertext)
+	case "json":
+		var out []byte
+		out, err = json.Marshal(map[string]string{auroratype.EncryptedValueKey: ciphertext})
+		if err == nil {
+			_, err = fmt.Fprintln(stdout, string(out))
+		}
+	case "raw":
+		_, err = fmt.Fprintln(stdout, ciphertext)
+	default:
+		err = fmt.Errorf("unknown format %q", *format)
+	}
+	return err
+}
+
+func readKey(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+}
@@ -0,0 +1,33 @@
+module github.com/tuannguyensn2001/aurora-go/cmd/aurora-relay
+
+go 1.25.5
+
+require (
+	github.com/tuannguyensn2001/aurora-go v0.0.0-00010101000000-000000000000
+	github.com/tuannguyensn2001/aurora-go/auroratype v0.0.0
+	github.com/tuannguyensn2001/aurora-go/fetcher/file v0.0.0-00010101000000-000000000000
+	github.com/tuannguyensn2001/aurora-go/fetcher/http v0.0.0-00010101000000-000000000000
+	google.golang.org/grpc v1.84.0
+	google.golang.org/protobuf v1.36.11
+)
+
+require (
+	github.com/BurntSushi/toml v1.6.0 // indirect
+	github.com/fsnotify/fsnotify v1.10.1 // indirect
+	github.com/klauspost/compress v1.18.0 // indirect
+	github.com/spaolacci/murmur3 v1.1.0 // indirect
+	golang.org/x/net v0.57.0 // indirect
+	golang.org/x/sys v0.47.0 // indirect
+	golang.org/x/text v0.40.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20260706201446-f0a921348800 // indirect
+	gopkg.in/yaml.v2 v2.4.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace github.com/tuannguyensn2001/aurora-go => ../..
+
+replace github.com/tuannguyensn2001/aurora-go/auroratype => ../../auroratype
+
+replace github.com/tuannguyensn2001/aurora-go/fetcher/file => ../../fetcher/file
+
+replace github.com/tuannguyensn2001/aurora-go/fetcher/http => ../../fetcher/http
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/tuannguyensn2001/aurora-go/cmd/aurora-relay/relaypb"
+)
+
+// grpcServer implements the gRPC API of the relay with the same semantics
+// as the HTTP API.
+type grpcServer struct {
+	relaypb.UnimplementedRelayServer
+	relay *relay
+}
+
+func (s *grpcServer) Evaluate(ctx context.Context, req *relaypb.EvaluateRequest) (*relaypb.Evaluation, error) {
+	if req.GetParameter() == "" {
+		return nil, status.Error(codes.InvalidArgument, "parameter is required")
+	}
+	return toProto(s.relay.evaluate(ctx, req.GetParameter(), req.GetAttributes().AsMap()))
+}
+
+func (s *grpcServer) EvaluateAll(ctx context.Context, req *relaypb.EvaluateAllRequest) (*relaypb.EvaluateAllResponse, error) {
+	evaluations, version, err := s.relay.evaluateAll(ctx, req.GetAttributes().AsMap())
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	response := &relaypb.EvaluateAllResponse{Version: version}
+	for _, e := range evaluations {
+		evaluation, err := toProto(e)
+		if err != nil {
+			return nil, err
+		}
+		response.Evaluations = append(response.Evaluations, evaluation)
+	}
+	return response, nil
+}
+
+func (s *grpcServer) GetSnapshot(ctx context.Context, req *relaypb.GetSnapshotRequest) (*relaypb.Snapshot, error) {
+	data, version, err := s.relay.snapshot(ctx, "")
+	if err != nil {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+	if req.GetVersion() == version {
+		return &relaypb.Snapshot{Version: version, NotModified: true}, nil
+	}
+	return &relaypb.Snapshot{Version: version, Data: data}, nil
+}
+
+func toProto(e evaluation) (*relaypb.Evaluation, error) {
+	value, err := structpb.NewValue(e.Value)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "parameter %q: %v", e.Parameter, err)
+	}
+	return &relaypb.Evaluation{
+		Parameter:    e.Parameter,
+		Value:        value,
+		Matched:      e.Matched,
+		Reason:       e.Reason,
+		ExperimentId: e.ExperimentID,
+		VariantKey:   e.VariantKey,
+	}, nil
+}
+
+// healthServer reports the relay as serving once its client is ready.
+type healthServer struct {
+	healthpb.UnimplementedHealthServer
+	relay *relay
+}
+
+func (s *healthServer) Check(ctx context.Context, req *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	if req.GetService() != "" && req.GetService() != relaypb.Relay_ServiceDesc.ServiceName {
+		return nil, status.Error(codes.NotFound, "unknown service")
+	}
+	if !s.relay.client.Status().Ready {
+		return &healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_NOT_SERVING}, nil
+	}
+	return &healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_SERVING}, nil
+}
+
+// newGRPCServer returns a gRPC server with the relay and health services.
+// The relay service requires token as a bearer token when it is set.
+func (r *relay) newGRPCServer(token string) *grpc.Server {
+	server := grpc.NewServer(grpc.UnaryInterceptor(r.intercept(token)))
+	relaypb.RegisterRelayServer(server, &grpcServer{relay: r})
+	healthpb.RegisterHealthServer(server, &healthServer{relay: r})
+	return server
+}
+
+// intercept authorizes and records calls to the relay service.
+func (r *relay) intercept(token string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		service, method, _ := strings.Cut(strings.TrimPrefix(info.FullMethod, "/"), "/")
+		if service != relaypb.Relay_ServiceDesc.ServiceName {
+			return handler(ctx, req)
+		}
+
+		start := time.Now()
+		resp, err := r.authorizeCall(ctx, token, req, handler)
+		duration := float64(time.Since(start).Microseconds())
+		r.recorder.Count(MetricRelayRequestTotal, 1, []string{"api:grpc", "method:" + method, "code:" + status.Code(err).String()})
+		r.recorder.Histogram(MetricRelayRequestLatency, duration, []string{"api:grpc", "method:" + method, "unit:microseconds"})
+		return resp, err
+	}
+}
+
+func (r *relay) authorizeCall(ctx context.Context, token string, req any, handler grpc.UnaryHandler) (any, error) {
+	if token != "" {
+		md, _ := metadata.FromIncomingContext(ctx)
+		authorization := md.Get("authorization")
+		if len(authorization) == 0 || !validToken(token, authorization[0]) {
+			return nil, status.Error(codes.Unauthenticated, "unauthorized")
+		}
+	}
+	return handler(ctx, req)
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tuannguyensn2001/aurora-go/auroratype"
+)
+
+// evaluateRequest is the body of the evaluation endpoints.
+type evaluateRequest struct {
+	Attributes map[string]any `json:"attributes"`
+}
+
+type evaluateAllResponse struct {
+	Evaluations []evaluation `json:"evaluations"`
+	Version     string       `json:"version"`
+}
+
+type statusResponse struct {
+	Ready       bool      `json:"ready"`
+	Stale       bool      `json:"stale"`
+	Version     string    `json:"version,omitempty"`
+	LastSuccess time.Time `json:"lastSuccess,omitzero"`
+	LastError   string    `json:"lastError,omitempty"`
+}
+
+// handler returns the HTTP API of the relay. Every endpoint but health and
+// metrics requires token as a bearer token when it is set.
+func (r *relay) handler(token string, metrics http.Handler) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("POST /v1/evaluate", r.instrument("EvaluateAll", authorized(token, r.serveEvaluateAll)))
+	mux.Handle("POST /v1/evaluate/{parameter}", r.instrument("Evaluate", authorized(token, r.serveEvaluate)))
+	mux.Handle("GET /v1/snapshot", r.instrument("GetSnapshot", authorized(token, r.serveSnapshot)))
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, req *http.Request) {
+		io.WriteString(w, "ok\n")
+	})
+	mux.HandleFunc("GET /readyz", r.serveReady)
+	mux.Handle("GET /metrics", metrics)
+	return mux
+}
+
+func (r *relay) serveEvaluate(w http.ResponseWriter, req *http.Request) {
+	attributes, err := readAttributes(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, http.StatusOK, r.evaluate(req.Context(), req.PathValue("parameter"), attributes))
+}
+
+func (r *relay) serveEvaluateAll(w http.ResponseWriter, req *http.Request) {
+	attributes, err := readAttributes(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	evaluations, version, err := r.evaluateAll(req.Context(), attributes)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, evaluateAllResponse{Evaluations: evaluations, Version: version})
+}
+
+// serveSnapshot serves the binary snapshot with the content version as its
+// ETag, so the HTTP and SSE fetchers of other Aurora clients can poll it
+// cheaply.
+func (r *relay) serveSnapshot(w http.ResponseWriter, req *http.Request) {
+	encoding := acceptedEncoding(req.Header.Get("Accept-Encoding"))
+	data, version, err := r.snapshot(req.Context(), encoding)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	etag := strconv.Quote(version)
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Vary", "Accept-Encoding")
+	if req.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	if encoding != "" {
+		w.Header().Set("Content-Encoding", encoding)
+	}
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.Write(data)
+}
+
+func (r *relay) serveReady(w http.ResponseWriter, req *http.Request) {
+	status := r.client.Status()
+	response := statusResponse{
+		Ready:       status.Ready,
+		Stale:       status.Stale,
+		Version:     status.Version,
+		LastSuccess: status.LastSuccess,
+	}
+	if status.LastError != nil {
+		response.LastError = status.LastError.Error()
+	}
+	code := http.StatusOK
+	if !status.Ready {
+		code = http.StatusServiceUnavailable
+	}
+	writeJSON(w, code, response)
+}
+
+// instrument records the count, status and latency of requests to method,
+// named like the gRPC method with the same semantics.
+func (r *relay) instrument(method string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, req)
+
+		duration := float64(time.Since(start).Microseconds())
+		r.recorder.Count(MetricRelayRequestTotal, 1, []string{"api:http", "method:" + method, "code:" + strconv.Itoa(recorder.status)})
+		r.recorder.Histogram(MetricRelayRequestLatency, duration, []string{"api:http", "method:" + method, "unit:microseconds"})
+	})
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	s.status = status
+	s.ResponseWriter.WriteHeader(status)
+}
+
+func authorized(token string, next http.HandlerFunc) http.Handler {
+	if token == "" {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if !validToken(token, req.Header.Get("Authorization")) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next(w, req)
+	})
+}
+
+// validToken reports whether authorization is "Bearer " followed by token.
+func validToken(token, authorization string) bool {
+	presented, ok := strings.CutPrefix(authorization, "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(presented), []byte(token)) == 1
+}
+
+// readAttributes decodes the attributes of an evaluation request. Numbers
+// are kept exact so that integers are evaluated as integers.
+func readAttributes(req *http.Request) (map[string]any, error) {
+	var body evaluateRequest
+	decoder := json.NewDecoder(req.Body)
+	decoder.UseNumber()
+	if err := decoder.Decode(&body); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	return body.Attributes, nil
+}
+
+// acceptedEncoding picks the content encoding of a snapshot response,
+// preferring zstd.
+func acceptedEncoding(accept string) string {
+	encoding := ""
+	for _, part := range strings.Split(accept, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if strings.ReplaceAll(params, " ", "") == "q=0" {
+			continue
+		}
+		switch strings.TrimSpace(name) {
+		case auroratype.EncodingZstd:
+			return auroratype.EncodingZstd
+		case auroratype.EncodingGzip:
+			encoding = auroratype.EncodingGzip
+		}
+	}
+	return encoding
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,165 @@
+// Command aurora-relay serves Aurora evaluations to services that cannot
+// embed the Go client, such as Python or Node services. It wraps a Client,
+// so evaluations, including experiment bucketing, are identical to those of
+// Go services reading the same configuration.
+//
+// Usage:
+//
+//	aurora-relay -parameters parameters.yaml -experiments experiments.yaml
+//	aurora-relay -parameters https://config.example.com/snapshot.aurora -http-addr :8080 -grpc-addr :9090
+//
+// Configuration is read from files, which are watched, from a directory
+// with -dir, or from HTTP(S) URLs. The HTTP API is:
+//
+//	POST /v1/evaluate/{parameter}  evaluate one parameter
+//	POST /v1/evaluate              evaluate every parameter
+//	GET  /v1/snapshot              binary snapshot, with an ETag
+//	GET  /healthz                  liveness
+//	GET  /readyz                   readiness and sync status
+//	GET  /metrics                  Prometheus metrics
+//
+// Evaluation requests post {"attributes": {...}} and receive the value with
+// its reason, experiment and variant. The gRPC API in relaypb/relay.proto
+// has the same semantics and serves the standard health service. Other
+// Aurora clients can fetch from /v1/snapshot with the HTTP fetcher, or use
+// it as the snapshot URL of the SSE fetcher. Encrypted values stay encrypted
+// in snapshots, so those clients need the keys to read them.
+//
+// With -token, or AURORA_RELAY_TOKEN, the evaluation and snapshot
+// endpoints require it as a bearer token.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	aurora "github.com/tuannguyensn2001/aurora-go"
+	filefetcher "github.com/tuannguyensn2001/aurora-go/fetcher/file"
+	httpfetcher "github.com/tuannguyensn2001/aurora-go/fetcher/http"
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := run(ctx, os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, "aurora-relay:", err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("aurora-relay", flag.ContinueOnError)
+	parameters := flags.String("parameters", "", "parameters file, binary snapshot or HTTP(S) URL")
+	experiments := flags.String("experiments", "", "experiments file or HTTP(S) URL")
+	dir := flags.String("dir", "", "directory of configuration files, instead of -parameters and -experiments")
+	environment := flags.String("environment", "", "environment to apply, e.g. prod")
+	keys := flags.String("keys", "", "directory of keys for encrypted values")
+	interval := flags.Duration("interval", time.Minute, "how often to poll the configuration")
+	httpAddr := flags.String("http-addr", ":8080", "address of the HTTP API")
+	grpcAddr := flags.String("grpc-addr", ":9090", "address of the gRPC API, empty to disable")
+	token := flags.String("token", os.Getenv("AURORA_RELAY_TOKEN"), "bearer token required by the API")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *parameters == "" && *dir == "" {
+		return fmt.Errorf("-parameters or -dir is required")
+	}
+
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, nil))
+	metrics := newMetrics()
+
+	var fetcher aurora.Fetcher
+	if isURL(*parameters) {
+		fetcher = httpfetcher.NewFetcher(httpfetcher.Options{
+			URL:             *parameters,
+			ExperimentsURL:  *experiments,
+			MetricsRecorder: metrics,
+		})
+	} else {
+		fetcher = filefetcher.New(filefetcher.Options{
+			FilePath:            *parameters,
+			ExperimentsFilePath: *experiments,
+			Dir:                 *dir,
+			Environment:         *environment,
+			Watch:               true,
+		})
+	}
+
+	var provider aurora.KeyProvider
+	if *keys != "" {
+		provider = aurora.NewFileKeyProvider(*keys)
+	}
+	storage := aurora.NewFetcherStorage(fetcher,
+		aurora.WithInterval(*interval),
+		aurora.WithLogger(logger),
+		aurora.WithMetricsRecorder(metrics),
+		aurora.WithKeyProvider(provider),
+	)
+	client := aurora.NewClient(storage, aurora.ClientOptions{
+		Logger:          logger,
+		MetricsRecorder: metrics,
+		Environment:     *environment,
+	})
+	if err := client.Start(ctx); err != nil {
+		return err
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		client.Close(ctx)
+	}()
+
+	r := newRelay(client, metrics)
+	errs := make(chan error, 2)
+
+	httpServer := &http.Server{Addr: *httpAddr, Handler: r.handler(*token, metrics)}
+	go func() {
+		logger.Info("Serving HTTP API", "addr", *httpAddr)
+		errs <- httpServer.ListenAndServe()
+	}()
+
+	grpcServer := r.newGRPCServer(*token)
+	if *grpcAddr != "" {
+		listener, err := net.Listen("tcp", *grpcAddr)
+		if err != nil {
+			httpServer.Close()
+			return err
+		}
+		go func() {
+			logger.Info("Serving gRPC API", "addr", *grpcAddr)
+			errs <- grpcServer.Serve(listener)
+		}()
+	}
+
+	var err error
+	select {
+	case <-ctx.Done():
+	case err = <-errs:
+	}
+
+	logger.Info("Shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	grpcServer.GracefulStop()
+	if shutdownErr := httpServer.Shutdown(shutdownCtx); err == nil {
+		err = shutdownErr
+	}
+	if errors.Is(err, http.ErrServerClosed) {
+		err = nil
+	}
+	return err
+}
+
+func isURL(s string) bool {
+	return strings.HasPrefix(s, "http://") || strings.HasPrefix(s, "https://")
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// metrics is a MetricsRecorder that serves what it records in the
+// Prometheus text format. Tags of the form "key:value" become labels.
+// Counts are exposed as counters and histograms as summaries without
+// quantiles, every metric name prefixed with "aurora_".
+type metrics struct {
+	mu     sync.Mutex
+	series map[string]*series
+}
+
+type series struct {
+	name   string
+	labels string
+	kind   string
+	sum    float64
+	count  int64
+}
+
+func newMetrics() *metrics {
+	return &metrics{series: make(map[string]*series)}
+}
+
+func (m *metrics) Count(metricName string, count int, tags []string) {
+	name := metricName
+	if !strings.HasSuffix(name, "_total") {
+		name += "_total"
+	}
+	m.record(name, "counter", float64(count), tags)
+}
+
+func (m *metrics) Histogram(metricName string, value float64, tags []string) {
+	m.record(metricName, "summary", value, tags)
+}
+
+func (m *metrics) record(name, kind string, value float64, tags []string) {
+	name = "aurora_" + sanitize(name)
+	labels := formatLabels(tags)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := name + labels
+	s, ok := m.series[key]
+	if !ok {
+		s = &series{name: name, labels: labels, kind: kind}
+		m.series[key] = s
+	}
+	s.sum += value
+	s.count++
+}
+
+func (m *metrics) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	m.mu.Lock()
+	all := make([]series, 0, len(m.series))
+	for _, s := range m.series {
+		all = append(all, *s)
+	}
+	m.mu.Unlock()
+
+	slices.SortFunc(all, func(a, b series) int {
+		if c := strings.Compare(a.name, b.name); c != 0 {
+			return c
+		}
+		return strings.Compare(a.labels, b.labels)
+	})
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	var previous string
+	for _, s := range all {
+		if s.name != previous {
+			fmt.Fprintf(w, "# TYPE %s %s\n", s.name, s.kind)
+			previous = s.name
+		}
+		if s.kind == "counter" {
+			writeSample(w, s.name, s.labels, s.sum)
+			continue
+		}
+		writeSample(w, s.name+"_sum", s.labels, s.sum)
+		writeSample(w, s.name+"_count", s.labels, float64(s.count))
+	}
+}
+
+func writeSample(w io.Writer, name, labels string, value float64) {
+	fmt.Fprintf(w, "%s%s %s\n", name, labels, strconv.FormatFloat(value, 'g', -1, 64))
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// formatLabels turns tags into a sorted Prometheus label set. Tags without
+// a value are ignored.
+func formatLabels(tags []string) string {
+	var pairs []string
+	for _, tag := range tags {
+		key, value, ok := strings.Cut(tag, ":")
+		if !ok || key == "" {
+			continue
+		}
+		pairs = append(pairs, sanitize(key)+`="`+labelEscaper.Replace(value)+`"`)
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	slices.Sort(pairs)
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// sanitize replaces the characters Prometheus does not allow in names.
+func sanitize(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' {
+			return r
+		}
+		return '_'
+	}, name)
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"sync"
+
+	aurora "github.com/tuannguyensn2001/aurora-go"
+	"github.com/tuannguyensn2001/aurora-go/auroratype"
+)
+
+const (
+	MetricRelayRequestTotal   = "relay_request_total"
+	MetricRelayRequestLatency = "relay_request_latency"
+)
+
+// evaluation is the outcome of evaluating one parameter, shared by the HTTP
+// and gRPC APIs.
+type evaluation struct {
+	Parameter    string `json:"parameter"`
+	Value        any    `json:"value"`
+	Matched      bool   `json:"matched"`
+	Reason       string `json:"reason"`
+	ExperimentID string `json:"experimentId,omitempty"`
+	VariantKey   string `json:"variantKey,omitempty"`
+}
+
+// relay serves the evaluations and configuration of an Aurora client.
+type relay struct {
+	client   *aurora.Client
+	recorder aurora.MetricsRecorder
+
+	// encoded caches the binary snapshot of a version by content encoding.
+	mu      sync.Mutex
+	version string
+	encoded map[string][]byte
+}
+
+func newRelay(client *aurora.Client, recorder aurora.MetricsRecorder) *relay {
+	return &relay{client: client, recorder: recorder}
+}
+
+// evaluate resolves parameter for attributes exactly like a Go service
+// calling GetParameter would.
+func (r *relay) evaluate(ctx context.Context, parameter string, attributes map[string]any) evaluation {
+	attr := aurora.NewAttribute()
+	for k, v := range attributes {
+		attr.Set(k, attributeValue(v))
+	}
+	value := r.client.GetParameter(ctx, parameter, attr)
+	details := value.Details()
+	return evaluation{
+		Parameter:    parameter,
+		Value:        plainValue(value.Value()),
+		Matched:      value.Matched(),
+		Reason:       string(details.Reason),
+		ExperimentID: details.ExperimentID,
+		VariantKey:   details.VariantKey,
+	}
+}
+
+// evaluateAll resolves every parameter defined by the served configuration,
+// including parameters only set by experiment variants, sorted by name.
+func (r *relay) evaluateAll(ctx context.Context, attributes map[string]any) ([]evaluation, string, error) {
+	snapshot, err := r.client.Snapshot(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	if snapshot == nil {
+		return []evaluation{}, "", nil
+	}
+
+	seen := make(map[string]bool, len(snapshot.Parameters))
+	var names []string
+	add := func(name string) {
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	for name := range snapshot.Parameters {
+		add(name)
+	}
+	for _, exp := range snapshot.Experiments {
+		for _, variant := range exp.Variants {
+			for name := range variant.Values {
+				add(name)
+			}
+		}
+	}
+	slices.Sort(names)
+
+	evaluations := make([]evaluation, 0, len(names))
+	for _, name := range names {
+		evaluations = append(evaluations, r.evaluate(ctx, name, attributes))
+	}
+	return evaluations, snapshot.Version, nil
+}
+
+// snapshot returns the served configuration as a binary snapshot compressed
+// with encoding, and its version. Encrypted values are served as fetched, so
+// only clients holding the keys can read them.
+func (r *relay) snapshot(ctx context.Context, encoding string) ([]byte, string, error) {
+	snapshot := r.client.FetchedSnapshot()
+	if snapshot == nil {
+		return nil, "", fmt.Errorf("no configuration loaded")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.version != snapshot.Version {
+		r.version = snapshot.Version
+		r.encoded = make(map[string][]byte)
+	}
+	if data, ok := r.encoded[encoding]; ok {
+		return data, snapshot.Version, nil
+	}
+
+	data, ok := r.encoded[""]
+	var err error
+	if !ok {
+		if data, err = auroratype.MarshalBinarySnapshot(snapshot.Parameters, snapshot.Experiments); err != nil {
+			return nil, "", err
+		}
+		r.encoded[""] = data
+	}
+	if encoding != "" {
+		if data, err = auroratype.Compress(data, encoding); err != nil {
+			return nil, "", err
+		}
+		r.encoded[encoding] = data
+	}
+	return data, snapshot.Version, nil
+}
+
+// attributeValue converts a decoded JSON or protobuf attribute to the type a
+// Go service would pass. Integral numbers become ints, which hash the same
+// as in Go: a float64 1500000 would hash as "1.5e+06".
+func attributeValue(v any) any {
+	switch v := v.(type) {
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return int(n)
+		}
+		f, _ := v.Float64()
+		return f
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) <= 1<<53 {
+			return int(v)
+		}
+		return v
+	case []any:
+		values := make([]any, len(v))
+		for i, e := range v {
+			values[i] = attributeValue(e)
+		}
+		return values
+	case map[string]any:
+		values := make(map[string]any, len(v))
+		for k, e := range v {
+			values[k] = attributeValue(e)
+		}
+		return values
+	}
+	return v
+}
+
+// plainValue converts the maps decoded from YAML, which may have
+// non-string keys, to values that encode as JSON and protobuf.
+func plainValue(v any) any {
+	switch v := v.(type) {
+	case map[any]any:
+		values := make(map[string]any, len(v))
+		for k, e := range v {
+			values[fmt.Sprint(k)] = plainValue(e)
+		}
+		return values
+	case map[string]any:
+		values := make(map[string]any, len(v))
+		for k, e := range v {
+			values[k] = plainValue(e)
+		}
+		return values
+	case []any:
+		values := make([]any, len(v))
+		for i, e := range v {
+			values[i] = plainValue(e)
+		}
+		return values
+	}
+	return v
+}
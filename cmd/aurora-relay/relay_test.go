@@ -0,0 +1,280 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	aurora "github.com/tuannguyensn2001/aurora-go"
+	"github.com/tuannguyensn2001/aurora-go/auroratype"
+	"github.com/tuannguyensn2001/aurora-go/cmd/aurora-relay/relaypb"
+)
+
+type stubFetcher struct {
+	parameters  map[string]auroratype.Parameter
+	experiments []auroratype.Experiment
+}
+
+func (s *stubFetcher) Fetch(ctx context.Context) (map[string]auroratype.Parameter, error) {
+	return s.parameters, nil
+}
+
+func (s *stubFetcher) FetchExperiments(ctx context.Context) ([]auroratype.Experiment, error) {
+	return s.experiments, nil
+}
+
+func (s *stubFetcher) IsStatic() bool {
+	return true
+}
+
+type stubKeys struct {
+	key []byte
+}
+
+func (s stubKeys) Key(ctx context.Context, id string) ([]byte, error) {
+	return s.key, nil
+}
+
+const token = "secret"
+
+func newTestRelay(t *testing.T) (*relay, *aurora.Client) {
+	t.Helper()
+	key := bytes.Repeat([]byte{1}, 32)
+	ciphertext, err := aurora.Encrypt(key, "main", "s3cr3t")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fetcher := &stubFetcher{
+		parameters: map[string]auroratype.Parameter{
+			"limit": {
+				DefaultValue: 10,
+				Rules: []auroratype.Rule{{
+					RolloutValue: 100,
+					Constraints:  []auroratype.Constraint{{Field: "plan", Operator: "equal", Value: "pro"}},
+				}},
+			},
+			"theme":  {DefaultValue: map[any]any{"color": "blue"}},
+			"apiKey": {DefaultValue: map[string]any{auroratype.EncryptedValueKey: ciphertext}},
+		},
+		experiments: []auroratype.Experiment{{
+			ID:             "exp-1",
+			Name:           "Checkout",
+			Parameters:     []string{"checkout"},
+			HashAttribute:  "user_id",
+			PopulationSize: 100,
+			Status:         auroratype.StatusRunning,
+			Variants: []auroratype.Variant{
+				{Key: "control", Rollout: 50, Values: map[string]interface{}{"checkout": "classic"}},
+				{Key: "treatment", Rollout: 50, Values: map[string]interface{}{"checkout": "express"}},
+			},
+		}},
+	}
+
+	m := newMetrics()
+	storage := aurora.NewFetcherStorage(fetcher, aurora.WithKeyProvider(stubKeys{key}))
+	client := aurora.NewClient(storage, aurora.ClientOptions{MetricsRecorder: m})
+	if err := client.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close(context.Background()) })
+	return newRelay(client, m), client
+}
+
+func post(t *testing.T, handler http.Handler, path, body string, v any) int {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if v != nil && rec.Code == http.StatusOK {
+		if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return rec.Code
+}
+
+func TestEvaluateMatchesClient(t *testing.T) {
+	r, client := newTestRelay(t)
+	handler := r.handler(token, newMetrics())
+	ctx := context.Background()
+
+	var e evaluation
+	if code := post(t, handler, "/v1/evaluate/limit", `{"attributes": {"plan": "pro"}}`, &e); code != http.StatusOK {
+		t.Fatalf("status = %d", code)
+	}
+	if e.Value != float64(100) || !e.Matched || e.Reason != "rule_match" {
+		t.Errorf("evaluation = %+v", e)
+	}
+
+	// large integral ids must bucket like the ints a Go service passes
+	for _, id := range []int{1500000, 7, 123456789} {
+		attr := aurora.NewAttribute()
+		attr.Set("user_id", id)
+		want := client.GetParameter(ctx, "checkout", attr).Details().VariantKey
+
+		body, _ := json.Marshal(evaluateRequest{Attributes: map[string]any{"user_id": id}})
+		if post(t, handler, "/v1/evaluate/checkout", string(body), &e); e.VariantKey != want || e.Reason != "experiment" {
+			t.Errorf("user %d: evaluation = %+v, want variant %q", id, e, want)
+		}
+		protoEval := r.evaluate(ctx, "checkout", map[string]any{"user_id": float64(id)})
+		if protoEval.VariantKey != want {
+			t.Errorf("user %d: protobuf number variant = %q, want %q", id, protoEval.VariantKey, want)
+		}
+	}
+
+	var all evaluateAllResponse
+	if code := post(t, handler, "/v1/evaluate", "", &all); code != http.StatusOK {
+		t.Fatalf("status = %d", code)
+	}
+	var names []string
+	for _, e := range all.Evaluations {
+		names = append(names, e.Parameter)
+	}
+	if strings.Join(names, ",") != "apiKey,checkout,limit,theme" || all.Version == "" {
+		t.Errorf("evaluate all = %+v", all)
+	}
+	if theme := all.Evaluations[3].Value; theme.(map[string]any)["color"] != "blue" {
+		t.Errorf("theme = %v", theme)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/evaluate/limit", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("without token: status = %d, want 401", rec.Code)
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	r, _ := newTestRelay(t)
+	server := httptest.NewServer(r.handler("", newMetrics()))
+	defer server.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/v1/snapshot", nil)
+	req.Header.Set("Accept-Encoding", "gzip, zstd")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Encoding") != "zstd" {
+		t.Fatalf("status = %d, encoding = %q", resp.StatusCode, resp.Header.Get("Content-Encoding"))
+	}
+	if data, err = auroratype.Decompress(data, "zstd"); err != nil {
+		t.Fatal(err)
+	}
+	parameters, experiments, err := auroratype.UnmarshalBinarySnapshot(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parameters) != 3 || len(experiments) != 1 {
+		t.Errorf("snapshot = %v, %v, want every parameter and experiment", parameters, experiments)
+	}
+	if _, ok := auroratype.EncryptedValue(parameters["apiKey"].DefaultValue); !ok {
+		t.Errorf("apiKey = %v, want the ciphertext as fetched", parameters["apiKey"].DefaultValue)
+	}
+
+	req.Header.Set("If-None-Match", resp.Header.Get("ETag"))
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotModified {
+		t.Errorf("conditional request: status = %d, want 304", resp.StatusCode)
+	}
+}
+
+func TestGRPC(t *testing.T) {
+	r, _ := newTestRelay(t)
+	listener := bufconn.Listen(1 << 20)
+	server := r.newGRPCServer(token)
+	go server.Serve(listener)
+	defer server.Stop()
+
+	conn, err := grpc.NewClient("passthrough:///relay",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	client := relaypb.NewRelayClient(conn)
+
+	attributes, _ := structpb.NewStruct(map[string]any{"plan": "pro"})
+	if _, err := client.Evaluate(context.Background(), &relaypb.EvaluateRequest{Parameter: "limit"}); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("without token: got %v, want Unauthenticated", err)
+	}
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+	e, err := client.Evaluate(ctx, &relaypb.EvaluateRequest{Parameter: "limit", Attributes: attributes})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.GetValue().GetNumberValue() != 100 || !e.GetMatched() || e.GetReason() != "rule_match" {
+		t.Errorf("evaluation = %v", e)
+	}
+
+	all, err := client.EvaluateAll(ctx, &relaypb.EvaluateAllRequest{Attributes: attributes})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all.GetEvaluations()) != 4 || all.GetVersion() == "" {
+		t.Errorf("evaluate all = %v", all)
+	}
+
+	snapshot, err := client.GetSnapshot(ctx, &relaypb.GetSnapshotRequest{})
+	if err != nil || len(snapshot.GetData()) == 0 {
+		t.Fatalf("GetSnapshot = %v, %v", snapshot, err)
+	}
+	snapshot, err = client.GetSnapshot(ctx, &relaypb.GetSnapshotRequest{Version: snapshot.GetVersion()})
+	if err != nil || !snapshot.GetNotModified() || snapshot.GetData() != nil {
+		t.Errorf("GetSnapshot with current version = %v, %v", snapshot, err)
+	}
+
+	health, err := healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{})
+	if err != nil || health.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		t.Errorf("health = %v, %v", health, err)
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	m := newMetrics()
+	m.Count("get_parameter", 1, []string{"status:resolved", "storage:default"})
+	m.Count("get_parameter", 2, []string{"storage:default", "status:resolved"})
+	m.Count(MetricRelayRequestTotal, 1, []string{"api:http", `method:"odd"`})
+	m.Histogram("relay_request_latency", 250, []string{"unit:microseconds"})
+	m.Histogram("relay_request_latency", 750, []string{"unit:microseconds"})
+
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	want := `# TYPE aurora_get_parameter_total counter
+aurora_get_parameter_total{status="resolved",storage="default"} 3
+# TYPE aurora_relay_request_latency summary
+aurora_relay_request_latency_sum{unit="microseconds"} 1000
+aurora_relay_request_latency_count{unit="microseconds"} 2
+# TYPE aurora_relay_request_total counter
+aurora_relay_request_total{api="http",method="\"odd\""} 1
+`
+	if got := rec.Body.String(); got != want {
+		t.Errorf("metrics =\n%s\nwant\n%s", got, want)
+	}
+}
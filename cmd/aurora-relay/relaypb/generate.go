@@ -0,0 +1,5 @@
+// Package relaypb holds the gRPC API of aurora-relay. relay.proto is the
+// source of truth; clients in other languages generate their stubs from it.
+package relaypb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative relay.proto
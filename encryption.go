@@ -0,0 +1,266 @@
+package core
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/tuannguyensn2001/aurora-go/auroratype"
+)
+
+const encryptionVersion = "v1"
+
+var ErrNoKeyProvider = errors.New("aurora: encrypted value found but no key provider is configured")
+
+// KeyProvider returns the AES keys that decrypt encrypted values. Each
+// ciphertext names the ID of the key it was encrypted with, so keys can be
+// rotated by adding a new key and re-encrypting.
+type KeyProvider interface {
+	Key(ctx context.Context, id string) ([]byte, error)
+}
+
+// FileKeyProvider reads keys from files named <id>.key in Dir. Each file
+// holds a base64-encoded key of 16, 24 or 32 bytes.
+type FileKeyProvider struct {
+	Dir string
+}
+
+func NewFileKeyProvider(dir string) *FileKeyProvider {
+	return &FileKeyProvider{Dir: dir}
+}
+
+func (p *FileKeyProvider) Key(ctx context.Context, id string) ([]byte, error) {
+	if id == "" || strings.ContainsAny(id, `/\`) || strings.HasPrefix(id, ".") {
+		return nil, fmt.Errorf("invalid key id %q", id)
+	}
+	data, err := os.ReadFile(filepath.Join(p.Dir, id+".key"))
+	if err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+}
+
+// WithKeyProvider decrypts encrypted values during sync. Without it a
+// snapshot holding encrypted values is rejected.
+func WithKeyProvider(provider KeyProvider) func(opts *fetcherStorage) {
+	return func(opts *fetcherStorage) {
+		opts.keyProvider = provider
+	}
+}
+
+// Encrypt encrypts value with AES-GCM under key and returns the ciphertext
+// to configure as {"$enc": "<ciphertext>"} or !encrypted <ciphertext>. The
+// value keeps its type when decrypted.
+func Encrypt(key []byte, keyID string, value any) (string, error) {
+	if keyID == "" || strings.Contains(keyID, ":") {
+		return "", fmt.Errorf("invalid key id %q", keyID)
+	}
+	plaintext, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, plaintext, additionalData(keyID))
+	return encryptionVersion + ":" + keyID + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// additionalData binds a ciphertext to its key ID.
+func additionalData(keyID string) []byte {
+	return []byte("aurora:" + encryptionVersion + ":" + keyID)
+}
+
+// decrypter decrypts values, asking the provider once per key.
+type decrypter struct {
+	ctx      context.Context
+	provider KeyProvider
+	keys     map[string]cipher.AEAD
+}
+
+// value returns v decrypted if it is an encrypted value.
+func (d *decrypter) value(v any) (any, bool, error) {
+	ciphertext, ok := auroratype.EncryptedValue(v)
+	if !ok {
+		return v, false, nil
+	}
+	if d.provider == nil {
+		return nil, false, ErrNoKeyProvider
+	}
+
+	parts := strings.SplitN(ciphertext, ":", 3)
+	if len(parts) != 3 || parts[0] != encryptionVersion {
+		return nil, false, errors.New("malformed encrypted value")
+	}
+	keyID := parts[1]
+	sealed, err := base64.StdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, false, fmt.Errorf("malformed encrypted value: %w", err)
+	}
+
+	gcm, ok := d.keys[keyID]
+	if !ok {
+		key, err := d.provider.Key(d.ctx, keyID)
+		if err != nil {
+			return nil, false, fmt.Errorf("key %q: %w", keyID, err)
+		}
+		if gcm, err = newGCM(key); err != nil {
+			return nil, false, fmt.Errorf("key %q: %w", keyID, err)
+		}
+		d.keys[keyID] = gcm
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, false, errors.New("malformed encrypted value")
+	}
+	plaintext, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], additionalData(keyID))
+	if err != nil {
+		return nil, false, fmt.Errorf("decrypting with key %q: %w", keyID, err)
+	}
+
+	// YAML keeps integers apart from floats, which JSON would not
+	var decrypted any
+	if err := yaml.Unmarshal(plaintext, &decrypted); err != nil {
+		return nil, false, err
+	}
+	return decrypted, true, nil
+}
+
+func (d *decrypter) rules(rules []auroratype.Rule) ([]auroratype.Rule, bool, error) {
+	var copied []auroratype.Rule
+	for i, rule := range rules {
+		value, encrypted, err := d.value(rule.RolloutValue)
+		if err != nil {
+			return nil, false, fmt.Errorf("rules[%d].rolloutValue: %w", i, err)
+		}
+		if !encrypted {
+			continue
+		}
+		if copied == nil {
+			copied = append([]auroratype.Rule(nil), rules...)
+		}
+		copied[i].RolloutValue = value
+	}
+	if copied == nil {
+		return rules, false, nil
+	}
+	return copied, true, nil
+}
+
+func (d *decrypter) parameter(param auroratype.Parameter) (auroratype.Parameter, bool, error) {
+	value, found, err := d.value(param.DefaultValue)
+	if err != nil {
+		return param, false, fmt.Errorf("defaultValue: %w", err)
+	}
+	param.DefaultValue = value
+
+	rules, encrypted, err := d.rules(param.Rules)
+	if err != nil {
+		return param, false, err
+	}
+	param.Rules = rules
+	found = found || encrypted
+
+	if len(param.Environments) == 0 {
+		return param, found, nil
+	}
+	environments := make(map[string]auroratype.ParameterOverlay, len(param.Environments))
+	for env, overlay := range param.Environments {
+		if overlay.DefaultValue, encrypted, err = d.value(overlay.DefaultValue); err != nil {
+			return param, false, fmt.Errorf("environments.%s.defaultValue: %w", env, err)
+		}
+		found = found || encrypted
+		if overlay.Rules != nil {
+			rules, encrypted, err := d.rules(*overlay.Rules)
+			if err != nil {
+				return param, false, fmt.Errorf("environments.%s.%w", env, err)
+			}
+			overlay.Rules = &rules
+			found = found || encrypted
+		}
+		environments[env] = overlay
+	}
+	param.Environments = environments
+	return param, found, nil
+}
+
+// decrypt returns a copy of snapshot with every encrypted value decrypted
+// and Encrypted naming the parameters that held one. A snapshot without
+// encrypted values is returned as is.
+func (w *fetcherStorage) decrypt(ctx context.Context, snapshot *auroratype.Snapshot) (*auroratype.Snapshot, error) {
+	d := &decrypter{ctx: ctx, provider: w.keyProvider, keys: make(map[string]cipher.AEAD)}
+	encrypted := make(map[string]bool)
+
+	parameters := make(map[string]auroratype.Parameter, len(snapshot.Parameters))
+	for name, param := range snapshot.Parameters {
+		param, ok, err := d.parameter(param)
+		if err != nil {
+			return nil, fmt.Errorf("parameter %q: %w", name, err)
+		}
+		parameters[name] = param
+		if ok {
+			encrypted[name] = true
+		}
+	}
+
+	var experiments []auroratype.Experiment
+	if snapshot.Experiments != nil {
+		experiments = make([]auroratype.Experiment, len(snapshot.Experiments))
+	}
+	for i, exp := range snapshot.Experiments {
+		variants := make([]auroratype.Variant, len(exp.Variants))
+		for j, variant := range exp.Variants {
+			values := make(map[string]interface{}, len(variant.Values))
+			for param, v := range variant.Values {
+				value, ok, err := d.value(v)
+				if err != nil {
+					return nil, fmt.Errorf("experiment %q variant %q value %q: %w", exp.ID, variant.Key, param, err)
+				}
+				values[param] = value
+				if ok {
+					encrypted[param] = true
+				}
+			}
+			if variant.Values != nil {
+				variant.Values = values
+			}
+			variants[j] = variant
+		}
+		if exp.Variants != nil {
+			exp.Variants = variants
+		}
+		experiments[i] = exp
+	}
+
+	if len(encrypted) == 0 {
+		return snapshot, nil
+	}
+	decrypted := *snapshot
+	decrypted.Parameters = parameters
+	decrypted.Experiments = experiments
+	decrypted.Encrypted = encrypted
+	return &decrypted, nil
+}
@@ -0,0 +1,113 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tuannguyensn2001/aurora-go/auroratype"
+	mocks "github.com/tuannguyensn2001/aurora-go/mocks"
+)
+
+func writeKey(t *testing.T, dir, id string) []byte {
+	t.Helper()
+	key := make([]byte, 32)
+	_, err := rand.Read(key)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, id+".key"), []byte(base64.StdEncoding.EncodeToString(key)+"\n"), 0o600))
+	return key
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	key := writeKey(t, dir, "main")
+	d := &decrypter{ctx: ctx, provider: NewFileKeyProvider(dir), keys: make(map[string]cipher.AEAD)}
+
+	for _, value := range []any{"https://partner.example.com", 42, 1.5, true, map[string]any{"token": "secret"}} {
+		ciphertext, err := Encrypt(key, "main", value)
+		require.NoError(t, err)
+
+		decrypted, ok, err := d.value(map[string]any{auroratype.EncryptedValueKey: ciphertext})
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, value, decrypted)
+	}
+
+	plain, ok, err := d.value("plain")
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, "plain", plain)
+
+	other := make([]byte, 32)
+	ciphertext, err := Encrypt(other, "main", "value")
+	require.NoError(t, err)
+	_, _, err = d.value(map[string]any{auroratype.EncryptedValueKey: ciphertext})
+	assert.Error(t, err, "wrong key")
+
+	ciphertext, err = Encrypt(key, "missing", "value")
+	require.NoError(t, err)
+	_, _, err = d.value(map[string]any{auroratype.EncryptedValueKey: ciphertext})
+	assert.Error(t, err, "unknown key")
+
+	_, err = NewFileKeyProvider(dir).Key(ctx, "../main")
+	assert.Error(t, err)
+}
+
+func TestClientDecryptsValues(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	key := writeKey(t, dir, "main")
+	secret, err := Encrypt(key, "main", "https://partner.example.com")
+	require.NoError(t, err)
+
+	params := map[string]auroratype.Parameter{
+		"partnerURL": {DefaultValue: map[any]any{auroratype.EncryptedValueKey: secret}},
+		"limit":      {DefaultValue: 10},
+	}
+	mockFetcher := new(mocks.MockFetcher)
+	mockFetcher.On("IsStatic").Return(true)
+	mockFetcher.On("Fetch", ctx).Return(params, nil)
+	mockFetcher.On("FetchExperiments", ctx).Return(nil, nil)
+
+	// without a key provider the snapshot is rejected
+	client := NewClient(NewFetcherStorage(mockFetcher), ClientOptions{})
+	assert.ErrorIs(t, client.Start(ctx), ErrNoKeyProvider)
+
+	cachePath := filepath.Join(t.TempDir(), "aurora.cache")
+	client = NewClient(NewFetcherStorage(mockFetcher, WithKeyProvider(NewFileKeyProvider(dir)), WithCache(CacheOptions{Path: cachePath})), ClientOptions{})
+	require.NoError(t, client.Start(ctx))
+
+	result := client.GetParameter(ctx, "partnerURL", NewAttribute())
+	assert.Equal(t, "https://partner.example.com", result.Value())
+	assert.True(t, result.Details().Encrypted)
+	assert.False(t, client.GetParameter(ctx, "limit", NewAttribute()).Details().Encrypted)
+
+	var logs bytes.Buffer
+	slog.New(slog.NewTextHandler(&logs, nil)).Info("evaluated", "value", result)
+	assert.Contains(t, logs.String(), "[REDACTED]")
+	assert.NotContains(t, logs.String(), "partner.example.com")
+
+	cached, err := os.ReadFile(cachePath)
+	require.NoError(t, err)
+	assert.NotContains(t, string(cached), "partner.example.com")
+	assert.Contains(t, string(cached), secret)
+
+	// the cached ciphertext is decrypted again when the fetcher is down
+	failing := new(mocks.MockFetcher)
+	failing.On("IsStatic").Return(true)
+	failing.On("Fetch", ctx).Return(nil, errors.New("unavailable"))
+	client = NewClient(NewFetcherStorage(failing, WithKeyProvider(NewFileKeyProvider(dir)), WithCache(CacheOptions{Path: cachePath})), ClientOptions{})
+	require.NoError(t, client.Start(ctx))
+	assert.Equal(t, "https://partner.example.com", client.GetParameter(ctx, "partnerURL", NewAttribute()).Value())
+}
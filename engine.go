@@ -2,6 +2,7 @@ package core
 
 import (
 	"context"
+	"slices"
 	"sync"
 	"time"
 
@@ -23,6 +24,25 @@ func (e *engine) registerOperator(name evaluator.Operator, fn func(a, b any) boo
 	e.operators[name] = fn
 }
 
+func (e *engine) hasOperator(name evaluator.Operator) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	_, ok := e.operators[name]
+	return ok
+}
+
+// operatorNames returns the names of the registered operators, sorted.
+func (e *engine) operatorNames() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	names := make([]string, 0, len(e.operators))
+	for name := range e.operators {
+		names = append(names, string(name))
+	}
+	slices.Sort(names)
+	return names
+}
+
 func newEngine() *engine {
 	return &engine{
 		operators: make(map[evaluator.Operator]func(a, b any) bool),
@@ -45,10 +65,10 @@ func (e *engine) evaluateParameter(ctx context.Context, parameterName string, pa
 	for _, rule := range parameter.Rules {
 		match := e.evaluateRule(ctx, parameterName, rule, attribute)
 		if match {
-			return NewResolvedValue(rule.RolloutValue, match)
+			return NewResolvedValue(rule.RolloutValue, match).withDetails(EvaluationDetails{Reason: ReasonRuleMatch})
 		}
 	}
-	return NewResolvedValue(parameter.DefaultValue, false)
+	return NewResolvedValue(parameter.DefaultValue, false).withDetails(EvaluationDetails{Reason: ReasonDefault})
 }
 
 func (e *engine) evaluateRule(ctx context.Context, parameterName string, rule auroratype.Rule, attribute *attribute) bool {
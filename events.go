@@ -0,0 +1,63 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+type EventType string
+
+const (
+	EventExposure   EventType = "exposure"
+	EventConversion EventType = "conversion"
+)
+
+// Event is a single exposure or conversion record. Exposures are emitted by
+// GetParameter whenever an experiment assigns a variant, conversions by Track.
+type Event struct {
+	Type         EventType      `json:"type"`
+	Timestamp    time.Time      `json:"timestamp"`
+	ExperimentID string         `json:"experimentId,omitempty"`
+	VariantKey   string         `json:"variantKey,omitempty"`
+	Parameter    string         `json:"parameter,omitempty"`
+	Reason       Reason         `json:"reason,omitempty"`
+	Name         string         `json:"name,omitempty"`
+	Value        float64        `json:"value,omitempty"`
+	Attributes   map[string]any `json:"attributes,omitempty"`
+}
+
+// EventSink receives exposure and conversion events. Implementations must be
+// safe for concurrent use.
+type EventSink interface {
+	Emit(ctx context.Context, event Event) error
+}
+
+type noopSink struct{}
+
+func (n *noopSink) Emit(ctx context.Context, event Event) error { return nil }
+
+func NewNoopSink() EventSink {
+	return &noopSink{}
+}
+
+// JSONLSink writes one JSON encoded event per line. The output can be read
+// back by the analysis package.
+type JSONLSink struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+func NewJSONLSink(w io.Writer) *JSONLSink {
+	return &JSONLSink{
+		enc: json.NewEncoder(w),
+	}
+}
+
+func (s *JSONLSink) Emit(ctx context.Context, event Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.enc.Encode(event)
+}
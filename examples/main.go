@@ -6,6 +6,7 @@ import (
 	"log"
 	"log/slog"
 	"os"
+	"strings"
 
 	aurora "github.com/tuannguyensn2001/aurora-go"
 	filefetcher "github.com/tuannguyensn2001/aurora-go/fetcher/file"
@@ -39,10 +40,24 @@ func main() {
 	//     Logger: slog.New(slog.NewJSONHandler(os.Stdout, opts)),
 	// })
 
+	// Custom operators used by parameters.yaml must be registered before
+	// Start, otherwise the configuration is rejected as invalid.
+	client.RegisterOperator("startsWith", func(a, b any) bool {
+		s, ok1 := a.(string)
+		prefix, ok2 := b.(string)
+		return ok1 && ok2 && strings.HasPrefix(s, prefix)
+	})
+	client.RegisterOperator("modulo", func(a, b any) bool {
+		n, ok1 := a.(int)
+		m, ok2 := b.(int)
+		return ok1 && ok2 && m != 0 && n%m == 0
+	})
+
 	err := client.Start(context.Background())
 	if err != nil {
 		log.Fatalf("failed to start client: %v", err)
 	}
+	defer client.Close(context.Background())
 
 	fmt.Println("=== Testing parameters without experiment ===")
 	for i := 0; i < 10; i++ {
@@ -0,0 +1,588 @@
+package experiment
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"hash/fnv"
+	"math"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/tuannguyensn2001/aurora-go/auroratype"
+)
+
+// weightScale is the rollout resolution used when bandit weights are turned
+// into variant rollouts for SelectVariantByHash.
+const weightScale = 10000
+
+const thompsonDraws = 2000
+
+type ArmStats struct {
+	Trials  int64   `json:"trials"`
+	Rewards float64 `json:"rewards"`
+}
+
+// BanditState is the persisted state of a bandit experiment. It is shared by
+// all replicas through a BanditStore.
+type BanditState struct {
+	Arms      map[string]ArmStats `json:"arms"`
+	Weights   map[string]float64  `json:"weights"`
+	UpdatedAt time.Time           `json:"updatedAt"`
+}
+
+// BanditStore persists bandit state so that every replica serves the same
+// weights. Update must apply fn atomically with respect to other callers of
+// the same store.
+type BanditStore interface {
+	Load(ctx context.Context, experimentID string) (*BanditState, error)
+	Update(ctx context.Context, experimentID string, fn func(state *BanditState) error) error
+}
+
+// Bandit accumulates trials and rewards locally and periodically folds them
+// into the shared store, recomputing variant weights. Between updates the
+// weights are fixed, so variant assignment stays deterministic.
+type Bandit struct {
+	store BanditStore
+
+	mu      sync.RWMutex
+	weights map[string]map[string]float64
+	pending map[string]map[string]ArmStats
+	// exposures holds the variant each unit was exposed to, so that its
+	// rewards go to that variant even if the weights have assigned it
+	// elsewhere since. Exposures outlive update windows and are pruned once
+	// older than exposureTTL.
+	exposures   map[string]map[string]*exposure
+	exposureTTL time.Duration
+	now         func() time.Time
+}
+
+// DefaultBanditExposureTTL is how long a unit's exposure is remembered for
+// attributing rewards and deduplicating trials.
+const DefaultBanditExposureTTL = 24 * time.Hour
+
+type exposure struct {
+	variant  string
+	at       time.Time
+	rewarded bool
+	// excluded is set for units served a forced variant, whose rewards must
+	// not move the weights.
+	excluded bool
+}
+
+func NewBandit(store BanditStore) *Bandit {
+	if store == nil {
+		store = NewMemoryBanditStore()
+	}
+	return &Bandit{
+		store:       store,
+		weights:     make(map[string]map[string]float64),
+		pending:     make(map[string]map[string]ArmStats),
+		exposures:   make(map[string]map[string]*exposure),
+		exposureTTL: DefaultBanditExposureTTL,
+		now:         time.Now,
+	}
+}
+
+// SetExposureTTL changes how long exposures are remembered. A unit exposed
+// again after the TTL counts as a new trial, and its rewards arriving after
+// the TTL are dropped. Non-positive values restore the default.
+func (b *Bandit) SetExposureTTL(ttl time.Duration) {
+	if ttl <= 0 {
+		ttl = DefaultBanditExposureTTL
+	}
+	b.mu.Lock()
+	b.exposureTTL = ttl
+	b.mu.Unlock()
+}
+
+// Weights returns the current weights of an experiment, or nil when no
+// weights have been computed yet.
+func (b *Bandit) Weights(experimentID string) map[string]float64 {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	w := b.weights[experimentID]
+	if w == nil {
+		return nil
+	}
+	out := make(map[string]float64, len(w))
+	for k, v := range w {
+		out[k] = v
+	}
+	return out
+}
+
+// RecordTrial counts an exposure of unit to variant. A unit is counted at
+// most once per exposure TTL, for the first variant it was exposed to.
+func (b *Bandit) RecordTrial(experimentID, variant, unit string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	exposures := b.unitExposures(experimentID)
+	if exposed, ok := exposures[unit]; ok && !b.expired(exposed, now) {
+		return
+	}
+	exposures[unit] = &exposure{variant: variant, at: now}
+
+	arms := b.pendingArms(experimentID)
+	arm := arms[variant]
+	arm.Trials++
+	arms[variant] = arm
+}
+
+// RecordReward counts a reward of unit for the variant it was exposed to,
+// even if the exposure was counted in an earlier update window. A unit is
+// rewarded at most once per exposure, and not at all without a trial or once
+// the exposure has expired. Rewards are clamped to [0, 1] so they can be
+// used as Bernoulli successes. It reports whether the reward was counted.
+func (b *Bandit) RecordReward(experimentID, unit string, reward float64) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	exposed, ok := b.exposures[experimentID][unit]
+	if !ok || exposed.rewarded || exposed.excluded || b.expired(exposed, b.now()) {
+		return false
+	}
+	exposed.rewarded = true
+
+	arms := b.pendingArms(experimentID)
+	arm := arms[exposed.variant]
+	arm.Rewards += math.Max(0, math.Min(1, reward))
+	arms[exposed.variant] = arm
+	return true
+}
+
+// Exclude stops counting unit until its exposure expires, because it was
+// served a forced variant. A unit already exposed organically keeps its trial
+// and stays eligible for its reward, so that trials and rewards stay paired.
+func (b *Bandit) Exclude(experimentID, unit string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	exposures := b.unitExposures(experimentID)
+	if exposed, ok := exposures[unit]; ok && !b.expired(exposed, now) {
+		return
+	}
+	exposures[unit] = &exposure{at: now, excluded: true}
+}
+
+func (b *Bandit) expired(e *exposure, now time.Time) bool {
+	return now.Sub(e.at) >= b.exposureTTL
+}
+
+// pruneExposures drops expired exposures. It must be called with b.mu held.
+func (b *Bandit) pruneExposures() {
+	now := b.now()
+	for id, exposures := range b.exposures {
+		for unit, exposed := range exposures {
+			if b.expired(exposed, now) {
+				delete(exposures, unit)
+			}
+		}
+		if len(exposures) == 0 {
+			delete(b.exposures, id)
+		}
+	}
+}
+
+func (b *Bandit) unitExposures(experimentID string) map[string]*exposure {
+	exposures := b.exposures[experimentID]
+	if exposures == nil {
+		exposures = make(map[string]*exposure)
+		b.exposures[experimentID] = exposures
+	}
+	return exposures
+}
+
+func (b *Bandit) pendingArms(experimentID string) map[string]ArmStats {
+	arms := b.pending[experimentID]
+	if arms == nil {
+		arms = make(map[string]ArmStats)
+		b.pending[experimentID] = arms
+	}
+	return arms
+}
+
+// Update flushes local counters of every bandit experiment into the store,
+// recomputes the weights there and loads the result.
+func (b *Bandit) Update(ctx context.Context, experiments []auroratype.Experiment) error {
+	b.mu.Lock()
+	pending := b.pending
+	b.pending = make(map[string]map[string]ArmStats)
+	b.pruneExposures()
+	b.mu.Unlock()
+
+	var errs []error
+	for _, exp := range experiments {
+		if !exp.IsBandit() {
+			continue
+		}
+
+		deltas := pending[exp.ID]
+		err := b.store.Update(ctx, exp.ID, func(state *BanditState) error {
+			if state.Arms == nil {
+				state.Arms = make(map[string]ArmStats)
+			}
+			for variant, d := range deltas {
+				arm := state.Arms[variant]
+				arm.Trials += d.Trials
+				arm.Rewards += d.Rewards
+				state.Arms[variant] = arm
+			}
+			state.Weights = ComputeWeights(exp, state.Arms)
+			state.UpdatedAt = time.Now()
+			return nil
+		})
+		if err != nil {
+			b.restore(exp.ID, deltas)
+			errs = append(errs, err)
+			continue
+		}
+
+		if err := b.load(ctx, exp.ID); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Refresh loads the persisted weights without contributing local counters.
+func (b *Bandit) Refresh(ctx context.Context, experiments []auroratype.Experiment) error {
+	var errs []error
+	for _, exp := range experiments {
+		if !exp.IsBandit() {
+			continue
+		}
+		if err := b.load(ctx, exp.ID); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (b *Bandit) load(ctx context.Context, experimentID string) error {
+	state, err := b.store.Load(ctx, experimentID)
+	if err != nil {
+		return err
+	}
+	if state == nil || len(state.Weights) == 0 {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.weights[experimentID] = state.Weights
+	return nil
+}
+
+func (b *Bandit) restore(experimentID string, deltas map[string]ArmStats) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	arms := b.pendingArms(experimentID)
+	for variant, d := range deltas {
+		arm := arms[variant]
+		arm.Trials += d.Trials
+		arm.Rewards += d.Rewards
+		arms[variant] = arm
+	}
+}
+
+// ComputeWeights derives variant weights from arm statistics using the
+// experiment's bandit algorithm. Weights sum to 1.
+func ComputeWeights(exp auroratype.Experiment, arms map[string]ArmStats) map[string]float64 {
+	algorithm := auroratype.BanditThompson
+	epsilon := 0.1
+	if exp.Bandit != nil {
+		if exp.Bandit.Algorithm != "" {
+			algorithm = exp.Bandit.Algorithm
+		}
+		if exp.Bandit.Epsilon > 0 {
+			epsilon = exp.Bandit.Epsilon
+		}
+	}
+
+	if algorithm == auroratype.BanditEpsilonGreedy {
+		return epsilonGreedyWeights(exp.Variants, arms, epsilon)
+	}
+	return thompsonWeights(exp.ID, exp.Variants, arms)
+}
+
+func epsilonGreedyWeights(variants []auroratype.Variant, arms map[string]ArmStats, epsilon float64) map[string]float64 {
+	weights := make(map[string]float64, len(variants))
+	if len(variants) == 0 {
+		return weights
+	}
+
+	best := variants[0].Key
+	bestMean := -1.0
+	for _, v := range variants {
+		arm := arms[v.Key]
+		mean := 0.0
+		if arm.Trials > 0 {
+			mean = arm.Rewards / float64(arm.Trials)
+		}
+		if mean > bestMean {
+			best, bestMean = v.Key, mean
+		}
+	}
+
+	explore := epsilon / float64(len(variants))
+	for _, v := range variants {
+		weights[v.Key] = explore
+	}
+	weights[best] += 1 - epsilon
+	return weights
+}
+
+// thompsonWeights estimates the probability that each variant is the best by
+// sampling from the Beta posterior of every arm. The generator is seeded from
+// the experiment and its statistics so that identical state yields identical
+// weights on every replica.
+func thompsonWeights(experimentID string, variants []auroratype.Variant, arms map[string]ArmStats) map[string]float64 {
+	weights := make(map[string]float64, len(variants))
+	if len(variants) == 0 {
+		return weights
+	}
+
+	h := fnv.New64a()
+	h.Write([]byte(experimentID))
+	var total int64
+	for _, v := range variants {
+		total += arms[v.Key].Trials
+	}
+	rng := rand.New(rand.NewPCG(h.Sum64(), uint64(total)))
+
+	wins := make([]int, len(variants))
+	for d := 0; d < thompsonDraws; d++ {
+		best, bestSample := 0, -1.0
+		for i, v := range variants {
+			arm := arms[v.Key]
+			failures := math.Max(0, float64(arm.Trials)-arm.Rewards)
+			sample := sampleBeta(rng, arm.Rewards+1, failures+1)
+			if sample > bestSample {
+				best, bestSample = i, sample
+			}
+		}
+		wins[best]++
+	}
+
+	for i, v := range variants {
+		weights[v.Key] = float64(wins[i]) / thompsonDraws
+	}
+	return weights
+}
+
+func sampleBeta(rng *rand.Rand, a, b float64) float64 {
+	x := sampleGamma(rng, a)
+	y := sampleGamma(rng, b)
+	if x+y == 0 {
+		return 0
+	}
+	return x / (x + y)
+}
+
+// sampleGamma draws from Gamma(shape, 1) using Marsaglia and Tsang's method.
+func sampleGamma(rng *rand.Rand, shape float64) float64 {
+	if shape < 1 {
+		return sampleGamma(rng, shape+1) * math.Pow(rng.Float64(), 1/shape)
+	}
+
+	d := shape - 1.0/3
+	c := 1 / math.Sqrt(9*d)
+	for {
+		x := rng.NormFloat64()
+		v := 1 + c*x
+		if v <= 0 {
+			continue
+		}
+		v = v * v * v
+		u := rng.Float64()
+		if u < 1-0.0331*x*x*x*x || math.Log(u) < 0.5*x*x+d*(1-v+math.Log(v)) {
+			return d * v
+		}
+	}
+}
+
+// applyWeights returns a copy of variants whose rollouts reflect weights.
+func applyWeights(variants []auroratype.Variant, weights map[string]float64) []auroratype.Variant {
+	out := make([]auroratype.Variant, len(variants))
+	for i, v := range variants {
+		out[i] = v
+		out[i].Rollout = int(math.Round(weights[v.Key] * weightScale))
+	}
+	return out
+}
+
+// MemoryBanditStore keeps bandit state in process. It is suitable for a
+// single replica or for tests.
+type MemoryBanditStore struct {
+	mu     sync.Mutex
+	states map[string]BanditState
+}
+
+func NewMemoryBanditStore() *MemoryBanditStore {
+	return &MemoryBanditStore{
+		states: make(map[string]BanditState),
+	}
+}
+
+func (m *MemoryBanditStore) Load(ctx context.Context, experimentID string) (*BanditState, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	state, ok := m.states[experimentID]
+	if !ok {
+		return nil, nil
+	}
+	return cloneState(state), nil
+}
+
+func (m *MemoryBanditStore) Update(ctx context.Context, experimentID string, fn func(state *BanditState) error) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	state := cloneState(m.states[experimentID])
+	if err := fn(state); err != nil {
+		return err
+	}
+	m.states[experimentID] = *state
+	return nil
+}
+
+// fileLockRetry is how often FileBanditStore retries a lock held by another
+// process. Updates hold the lock for a read and a write only.
+const fileLockRetry = 10 * time.Millisecond
+
+// FileBanditStore persists bandit state as one JSON file per experiment in a
+// directory, typically on a volume shared by all replicas. Files are replaced
+// atomically, and updates are serialized across processes by an advisory
+// flock on a lock file next to the state, so the volume must support flock.
+// The lock is released by the kernel when a process dies, so a crashed
+// replica never leaves it held. On platforms without flock, updates are only
+// serialized within the process.
+type FileBanditStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+func NewFileBanditStore(dir string) *FileBanditStore {
+	return &FileBanditStore{dir: dir}
+}
+
+func (f *FileBanditStore) path(experimentID string) string {
+	return filepath.Join(f.dir, experimentID+".bandit.json")
+}
+
+func (f *FileBanditStore) Load(ctx context.Context, experimentID string) (*BanditState, error) {
+	data, err := os.ReadFile(f.path(experimentID))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var state BanditState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+// lock takes the lock of an experiment, waiting for other processes to
+// release it. The lock file itself is never removed, so that two processes
+// always lock the same inode.
+func (f *FileBanditStore) lock(ctx context.Context, experimentID string) (func(), error) {
+	file, err := os.OpenFile(filepath.Join(f.dir, experimentID+".bandit.lock"), os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		locked, err := tryLockFile(file)
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+		if locked {
+			return func() {
+				unlockFile(file)
+				file.Close()
+			}, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			file.Close()
+			return nil, ctx.Err()
+		case <-time.After(fileLockRetry):
+		}
+	}
+}
+
+func (f *FileBanditStore) Update(ctx context.Context, experimentID string, fn func(state *BanditState) error) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := os.MkdirAll(f.dir, 0o755); err != nil {
+		return err
+	}
+	unlock, err := f.lock(ctx, experimentID)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	state, err := f.Load(ctx, experimentID)
+	if err != nil {
+		return err
+	}
+	if state == nil {
+		state = &BanditState{}
+	}
+	if err := fn(state); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(f.dir, experimentID+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), f.path(experimentID))
+}
+
+func cloneState(state BanditState) *BanditState {
+	out := &BanditState{
+		Arms:      make(map[string]ArmStats, len(state.Arms)),
+		Weights:   make(map[string]float64, len(state.Weights)),
+		UpdatedAt: state.UpdatedAt,
+	}
+	for k, v := range state.Arms {
+		out.Arms[k] = v
+	}
+	for k, v := range state.Weights {
+		out.Weights[k] = v
+	}
+	return out
+}
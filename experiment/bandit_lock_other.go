@@ -0,0 +1,13 @@
+//go:build !unix
+
+package experiment
+
+import "os"
+
+// tryLockFile always succeeds where flock is unavailable. Updates are then
+// serialized by FileBanditStore's mutex within the process only.
+func tryLockFile(file *os.File) (bool, error) {
+	return true, nil
+}
+
+func unlockFile(file *os.File) {}
@@ -0,0 +1,304 @@
+package experiment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tuannguyensn2001/aurora-go/auroratype"
+)
+
+func banditExperiment(algorithm auroratype.BanditAlgorithm) auroratype.Experiment {
+	return auroratype.Experiment{
+		ID:             "exp_bandit",
+		Name:           "Copy Test",
+		Type:           auroratype.TypeBandit,
+		Parameters:     []string{"headline"},
+		HashAttribute:  "userID",
+		PopulationSize: 100,
+		Status:         auroratype.StatusRunning,
+		Bandit:         &auroratype.BanditConfig{Algorithm: algorithm, RewardEvent: "click", Epsilon: 0.2},
+		Variants: []auroratype.Variant{
+			{Key: "a", Rollout: 50, Values: map[string]interface{}{"headline": "A"}},
+			{Key: "b", Rollout: 50, Values: map[string]interface{}{"headline": "B"}},
+		},
+	}
+}
+
+func TestComputeWeights_EpsilonGreedy(t *testing.T) {
+	exp := banditExperiment(auroratype.BanditEpsilonGreedy)
+	weights := ComputeWeights(exp, map[string]ArmStats{
+		"a": {Trials: 100, Rewards: 10},
+		"b": {Trials: 100, Rewards: 30},
+	})
+
+	if weights["a"] != 0.1 || weights["b"] != 0.9 {
+		t.Errorf("Expected weights a=0.1 b=0.9, got %v", weights)
+	}
+}
+
+func TestComputeWeights_Thompson(t *testing.T) {
+	exp := banditExperiment(auroratype.BanditThompson)
+	arms := map[string]ArmStats{
+		"a": {Trials: 1000, Rewards: 100},
+		"b": {Trials: 1000, Rewards: 200},
+	}
+
+	weights := ComputeWeights(exp, arms)
+	if weights["b"] < 0.99 {
+		t.Errorf("Expected clearly better arm to receive almost all traffic, got %v", weights)
+	}
+
+	again := ComputeWeights(exp, arms)
+	if weights["a"] != again["a"] || weights["b"] != again["b"] {
+		t.Errorf("Expected identical state to yield identical weights, got %v and %v", weights, again)
+	}
+
+	even := ComputeWeights(exp, map[string]ArmStats{})
+	if even["a"] < 0.4 || even["a"] > 0.6 {
+		t.Errorf("Expected roughly even weights without data, got %v", even)
+	}
+}
+
+func TestBandit_UpdateShiftsAssignment(t *testing.T) {
+	ctx := context.Background()
+	exp := banditExperiment(auroratype.BanditEpsilonGreedy)
+	store := NewMemoryBanditStore()
+	bandit := NewBandit(store)
+
+	for i := 0; i < 100; i++ {
+		bandit.RecordTrial(exp.ID, "a", fmt.Sprintf("a_%d", i))
+		bandit.RecordTrial(exp.ID, "b", fmt.Sprintf("b_%d", i))
+		// the same unit is only counted once per window
+		bandit.RecordTrial(exp.ID, "b", fmt.Sprintf("b_%d", i))
+	}
+	for i := 0; i < 40; i++ {
+		bandit.RecordReward(exp.ID, fmt.Sprintf("b_%d", i), 1)
+		// repeated reward events of a unit are only counted once per window
+		bandit.RecordReward(exp.ID, fmt.Sprintf("b_%d", i), 1)
+	}
+	if bandit.RecordReward(exp.ID, "unexposed", 1) {
+		t.Error("Expected a unit without a trial not to be rewarded")
+	}
+
+	if err := bandit.Update(ctx, []auroratype.Experiment{exp}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	state, _ := store.Load(ctx, exp.ID)
+	if state.Arms["b"].Trials != 100 || state.Arms["b"].Rewards != 40 {
+		t.Errorf("Expected persisted stats for b, got %+v", state.Arms["b"])
+	}
+
+	weights := bandit.Weights(exp.ID)
+	if weights["b"] != 0.9 {
+		t.Errorf("Expected b weight 0.9, got %v", weights)
+	}
+
+	// another replica sharing the store converges on the same weights
+	replica := NewBandit(store)
+	if err := replica.Refresh(ctx, []auroratype.Experiment{exp}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if replica.Weights(exp.ID)["b"] != 0.9 {
+		t.Errorf("Expected replica to load persisted weights, got %v", replica.Weights(exp.ID))
+	}
+
+	engine := NewEngine()
+	engine.Bootstrap()
+	engine.SetBandit(bandit)
+
+	counts := map[string]int{}
+	for i := 0; i < 1000; i++ {
+		attr := map[string]any{"userID": fmt.Sprintf("user_%d", i)}
+		result := engine.Evaluate(ctx, []auroratype.Experiment{exp}, "headline", attr)
+		if !result.Matched {
+			t.Fatal("Expected bandit experiment to match")
+		}
+		if result.Weights["b"] != 0.9 {
+			t.Fatalf("Expected evaluation to expose weights, got %v", result.Weights)
+		}
+		again := engine.Evaluate(ctx, []auroratype.Experiment{exp}, "headline", attr)
+		if again.VariantKey != result.VariantKey {
+			t.Fatal("Expected assignment to be deterministic between updates")
+		}
+		counts[result.VariantKey]++
+	}
+
+	if counts["b"] < 850 || counts["b"] > 950 {
+		t.Errorf("Expected about 90%% of traffic on b, got %v", counts)
+	}
+}
+
+func TestBandit_RewardsExposedVariant(t *testing.T) {
+	ctx := context.Background()
+	exp := banditExperiment(auroratype.BanditEpsilonGreedy)
+	store := NewMemoryBanditStore()
+	bandit := NewBandit(store)
+	now := time.Now()
+	bandit.now = func() time.Time { return now }
+	update := func() {
+		t.Helper()
+		if err := bandit.Update(ctx, []auroratype.Experiment{exp}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	bandit.RecordTrial(exp.ID, "a", "user_1")
+	// units served a forced variant are neither trials nor rewarded
+	bandit.Exclude(exp.ID, "qa_user")
+	bandit.RecordTrial(exp.ID, "b", "qa_user")
+	// a unit forced after an organic exposure keeps its trial and reward
+	bandit.RecordTrial(exp.ID, "b", "user_2")
+	bandit.Exclude(exp.ID, "user_2")
+	update()
+
+	// the exposure carries over the window boundary: the weights moved the
+	// unit to b, but it is not a new trial and its reward still goes to a
+	bandit.RecordTrial(exp.ID, "b", "user_1")
+	if !bandit.RecordReward(exp.ID, "user_1", 1) {
+		t.Fatal("Expected the reward to be counted")
+	}
+	if bandit.RecordReward(exp.ID, "user_1", 1) {
+		t.Error("Expected a unit to be rewarded once")
+	}
+	if bandit.RecordReward(exp.ID, "qa_user", 1) {
+		t.Error("Expected an excluded unit not to be rewarded")
+	}
+	if !bandit.RecordReward(exp.ID, "user_2", 0.5) {
+		t.Error("Expected the organic trial of a forced unit to be rewarded")
+	}
+	update()
+
+	state, _ := store.Load(ctx, exp.ID)
+	if state.Arms["a"] != (ArmStats{Trials: 1, Rewards: 1}) || state.Arms["b"] != (ArmStats{Trials: 1, Rewards: 0.5}) {
+		t.Errorf("Expected the trials and rewards of the organic exposures, got %+v", state.Arms)
+	}
+
+	// expired exposures are forgotten: no late reward, and a new trial
+	now = now.Add(DefaultBanditExposureTTL)
+	update()
+	if bandit.RecordReward(exp.ID, "user_1", 1) {
+		t.Error("Expected no reward after the exposure expired")
+	}
+	bandit.RecordTrial(exp.ID, "b", "user_1")
+	update()
+
+	state, _ = store.Load(ctx, exp.ID)
+	if state.Arms["b"] != (ArmStats{Trials: 2, Rewards: 0.5}) {
+		t.Errorf("Expected a new trial on b, got %+v", state.Arms)
+	}
+}
+
+func TestFileBanditStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewFileBanditStore(t.TempDir())
+
+	state, err := store.Load(ctx, "exp_bandit")
+	if err != nil || state != nil {
+		t.Fatalf("Expected no state, got %v %v", state, err)
+	}
+
+	err = store.Update(ctx, "exp_bandit", func(state *BanditState) error {
+		state.Weights = map[string]float64{"a": 0.25, "b": 0.75}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	state, err = store.Load(ctx, "exp_bandit")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if state.Weights["b"] != 0.75 {
+		t.Errorf("Expected persisted weights, got %v", state.Weights)
+	}
+}
+
+func TestFileBanditStore_SharedDirectory(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	// stores of different replicas only share the directory
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		store := NewFileBanditStore(dir)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 25; j++ {
+				err := store.Update(ctx, "exp_bandit", func(state *BanditState) error {
+					if state.Arms == nil {
+						state.Arms = make(map[string]ArmStats)
+					}
+					arm := state.Arms["a"]
+					arm.Trials++
+					state.Arms["a"] = arm
+					return nil
+				})
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	state, err := NewFileBanditStore(dir).Load(ctx, "exp_bandit")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if state.Arms["a"].Trials != 100 {
+		t.Errorf("Expected no update to be lost, got %d trials", state.Arms["a"].Trials)
+	}
+}
+
+func TestFileBanditStore_WaitsForLock(t *testing.T) {
+	dir := t.TempDir()
+	store := NewFileBanditStore(dir)
+
+	// another process holding the lock, or one that crashed while holding it
+	// and whose lock the kernel released
+	held, err := os.OpenFile(filepath.Join(dir, "exp_bandit.bandit.lock"), os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if locked, err := tryLockFile(held); !locked || err != nil {
+		t.Fatalf("Expected to take the lock, got %v %v", locked, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err = store.Update(ctx, "exp_bandit", func(*BanditState) error { return nil })
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected the update to wait for the lock, got %v", err)
+	}
+
+	held.Close()
+	if err := store.Update(context.Background(), "exp_bandit", func(*BanditState) error { return nil }); err != nil {
+		t.Errorf("Expected the update to take the released lock, got %v", err)
+	}
+}
+
+func TestValidateExperiments_Bandit(t *testing.T) {
+	exp := banditExperiment(auroratype.BanditThompson)
+	if errs := ValidateExperiments([]auroratype.Experiment{exp}); len(errs) != 0 {
+		t.Errorf("Expected valid bandit experiment, got %v", errs)
+	}
+
+	exp.Bandit = &auroratype.BanditConfig{Algorithm: "ucb", Epsilon: 2}
+	if errs := ValidateExperiments([]auroratype.Experiment{exp}); len(errs) != 3 {
+		t.Errorf("Expected 3 errors, got %v", errs)
+	}
+
+	exp.Bandit = nil
+	if errs := ValidateExperiments([]auroratype.Experiment{exp}); len(errs) != 1 {
+		t.Errorf("Expected missing bandit config error, got %v", errs)
+	}
+}
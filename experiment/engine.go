@@ -2,7 +2,9 @@ package experiment
 
 import (
 	"context"
+	"fmt"
 	"sort"
+	"sync"
 	"time"
 
 	"github.com/tuannguyensn2001/aurora-go/auroratype"
@@ -14,15 +16,59 @@ type Evaluation struct {
 	VariantKey   string
 	Values       map[string]interface{}
 	Matched      bool
+	// Weights holds the variant weights used for assignment when the
+	// experiment is a bandit.
+	Weights map[string]float64
+	// Forced is set when the variant came from a forced assignment rather
+	// than from hashing. Forced assignments must be excluded from analysis.
+	Forced bool
+	// Winner is set when a finished experiment serves its winning variant.
+	Winner bool
+}
+
+// TransitionHandler is called when the effective status of an experiment
+// changes between two observations.
+type TransitionHandler func(experimentID string, from, to auroratype.ExperimentStatus)
+
+type EvaluateOption func(*evaluateOptions)
+
+type evaluateOptions struct {
+	forced       map[string]string
+	bypassChecks bool
+}
+
+// WithForcedVariant assigns variantKey for experimentID regardless of the
+// hash bucket.
+func WithForcedVariant(experimentID, variantKey string) EvaluateOption {
+	return func(o *evaluateOptions) {
+		if o.forced == nil {
+			o.forced = make(map[string]string)
+		}
+		o.forced[experimentID] = variantKey
+	}
+}
+
+// WithBypassChecks lets variants forced through WithForcedVariant skip the
+// status, time and population checks.
+func WithBypassChecks() EvaluateOption {
+	return func(o *evaluateOptions) {
+		o.bypassChecks = true
+	}
 }
 
 type Engine struct {
-	operators map[evaluator.Operator]func(a, b any) bool
+	operators    map[evaluator.Operator]func(a, b any) bool
+	bandit       *Bandit
+	onTransition TransitionHandler
+
+	mu       sync.Mutex
+	statuses map[string]auroratype.ExperimentStatus
 }
 
 func NewEngine() *Engine {
 	return &Engine{
 		operators: make(map[evaluator.Operator]func(a, b any) bool),
+		statuses:  make(map[string]auroratype.ExperimentStatus),
 	}
 }
 
@@ -30,17 +76,61 @@ func (e *Engine) Bootstrap() {
 	e.operators = evaluator.DefaultOperators
 }
 
+// HasOperator reports whether constraints using name can be evaluated.
+func (e *Engine) HasOperator(name evaluator.Operator) bool {
+	_, ok := e.operators[name]
+	return ok
+}
+
+// SetBandit enables weighted assignment for bandit experiments.
+func (e *Engine) SetBandit(b *Bandit) {
+	e.bandit = b
+}
+
+// SetTransitionHandler registers fn to be notified of lifecycle transitions.
+func (e *Engine) SetTransitionHandler(fn TransitionHandler) {
+	e.onTransition = fn
+}
+
+// Observe records the effective status of every experiment at now and
+// reports transitions to the registered handler. It is called on every
+// evaluation and can be called periodically so that transitions are seen
+// without traffic.
+func (e *Engine) Observe(experiments []auroratype.Experiment, now time.Time) {
+	for _, exp := range experiments {
+		e.observe(exp, now)
+	}
+}
+
+func (e *Engine) observe(exp auroratype.Experiment, now time.Time) auroratype.ExperimentStatus {
+	status := exp.EffectiveStatus(now)
+
+	e.mu.Lock()
+	previous, seen := e.statuses[exp.ID]
+	e.statuses[exp.ID] = status
+	e.mu.Unlock()
+
+	if seen && previous != status && e.onTransition != nil {
+		e.onTransition(exp.ID, previous, status)
+	}
+	return status
+}
+
 func (e *Engine) Evaluate(
 	ctx context.Context,
 	experiments []auroratype.Experiment,
 	parameterName string,
 	attr map[string]any,
+	opts ...EvaluateOption,
 ) *Evaluation {
-	sort.Slice(experiments, func(i, j int) bool {
-		return experiments[i].Priority < experiments[j].Priority
+	// experiments may be shared by concurrent callers, sort a copy
+	sorted := make([]auroratype.Experiment, len(experiments))
+	copy(sorted, experiments)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Priority < sorted[j].Priority
 	})
 
-	for _, exp := range experiments {
+	for _, exp := range sorted {
 		hasParam := false
 		for _, p := range exp.Parameters {
 			if p == parameterName {
@@ -52,56 +142,125 @@ func (e *Engine) Evaluate(
 			continue
 		}
 
-		if !e.checkStatus(exp) {
-			continue
+		if evaluation := e.EvaluateExperiment(ctx, exp, attr, opts...); evaluation.Matched {
+			return evaluation
 		}
+	}
 
-		if !e.checkTime(exp) {
-			continue
-		}
+	return &Evaluation{
+		Matched: false,
+	}
+}
 
-		if !e.checkPopulation(ctx, exp, attr) {
-			continue
+// EvaluateExperiment runs the eligibility checks of a single experiment and
+// assigns a variant when they pass.
+func (e *Engine) EvaluateExperiment(ctx context.Context, exp auroratype.Experiment, attr map[string]any, opts ...EvaluateOption) *Evaluation {
+	var options evaluateOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	status := e.observe(exp, time.Now())
+	if status == auroratype.StatusFinished && exp.Winner != "" {
+		if winner := findVariant(exp.Variants, exp.Winner); winner != nil {
+			return &Evaluation{
+				ExperimentID: exp.ID,
+				VariantKey:   winner.Key,
+				Values:       winner.Values,
+				Matched:      true,
+				Winner:       true,
+			}
 		}
+	}
 
-		if !e.checkConstraints(ctx, exp, attr) {
-			continue
+	forced, bypass := e.forcedVariant(exp, attr, options)
+
+	if !bypass {
+		if !e.checkStatus(status) {
+			return &Evaluation{Matched: false}
 		}
 
-		variant := evaluator.SelectVariantByHash(exp.ID, exp.HashAttribute, attr, exp.Variants)
-		if variant == nil {
-			continue
+		if !e.checkPopulation(ctx, exp, attr) {
+			return &Evaluation{Matched: false}
 		}
+	}
 
+	if !e.checkConstraints(ctx, exp, attr) {
+		return &Evaluation{Matched: false}
+	}
+
+	if forced != nil {
 		return &Evaluation{
 			ExperimentID: exp.ID,
-			VariantKey:   variant.Key,
-			Values:       variant.Values,
+			VariantKey:   forced.Key,
+			Values:       forced.Values,
 			Matched:      true,
+			Forced:       true,
 		}
 	}
 
+	variants := exp.Variants
+	var weights map[string]float64
+	if exp.IsBandit() && e.bandit != nil {
+		weights = e.bandit.Weights(exp.ID)
+		if weights != nil {
+			variants = applyWeights(exp.Variants, weights)
+		}
+	}
+
+	variant := evaluator.SelectVariantByHash(exp.ID, exp.HashAttribute, attr, variants)
+	if variant == nil {
+		return &Evaluation{Matched: false}
+	}
+
 	return &Evaluation{
-		Matched: false,
+		ExperimentID: exp.ID,
+		VariantKey:   variant.Key,
+		Values:       variant.Values,
+		Matched:      true,
+		Weights:      weights,
 	}
 }
 
-func (e *Engine) checkStatus(exp auroratype.Experiment) bool {
-	return exp.Status == auroratype.StatusRunning
-}
+// forcedVariant resolves a forced assignment, preferring one passed for this
+// call over the experiment's configured ForcedVariants. It also reports
+// whether the assignment may skip the status, time and population checks.
+func (e *Engine) forcedVariant(exp auroratype.Experiment, attr map[string]any, opts evaluateOptions) (*auroratype.Variant, bool) {
+	if key, ok := opts.forced[exp.ID]; ok {
+		if v := findVariant(exp.Variants, key); v != nil {
+			return v, opts.bypassChecks
+		}
+	}
 
-func (e *Engine) checkTime(exp auroratype.Experiment) bool {
-	now := time.Now().Unix()
+	if len(exp.ForcedVariants) == 0 || exp.HashAttribute == "" {
+		return nil, false
+	}
 
-	if exp.StartTime != nil && now < *exp.StartTime {
-		return false
+	hashValue := attr[exp.HashAttribute]
+	if hashValue == nil {
+		return nil, false
 	}
 
-	if exp.EndTime != nil && now > *exp.EndTime {
-		return false
+	if key, ok := exp.ForcedVariants[fmt.Sprintf("%v", hashValue)]; ok {
+		if v := findVariant(exp.Variants, key); v != nil {
+			return v, exp.ForceBypassChecks
+		}
 	}
 
-	return true
+	return nil, false
+}
+
+func findVariant(variants []auroratype.Variant, key string) *auroratype.Variant {
+	for i := range variants {
+		if variants[i].Key == key {
+			return &variants[i]
+		}
+	}
+	return nil
+}
+
+func (e *Engine) checkStatus(status auroratype.ExperimentStatus) bool {
+	return status == auroratype.StatusRunning
 }
 
 func (e *Engine) checkPopulation(ctx context.Context, exp auroratype.Experiment, attr map[string]any) bool {
@@ -3,6 +3,7 @@ package experiment
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/tuannguyensn2001/aurora-go/auroratype"
 )
@@ -165,3 +166,112 @@ func TestEngine_StatusNotRunning(t *testing.T) {
 		t.Error("Expected experiment not to match when status is not running")
 	}
 }
+
+func TestEngine_ForcedVariants(t *testing.T) {
+	engine := NewEngine()
+	engine.Bootstrap()
+
+	exp := auroratype.Experiment{
+		ID:             "exp_001",
+		Name:           "Test Experiment",
+		Parameters:     []string{"buttonColor"},
+		HashAttribute:  "userID",
+		PopulationSize: 1,
+		Priority:       1,
+		Status:         auroratype.StatusRunning,
+		ForcedVariants: map[string]string{"qa_user": "treatment"},
+		Variants: []auroratype.Variant{
+			{Key: "control", Rollout: 100, Values: map[string]interface{}{"buttonColor": "blue"}},
+			{Key: "treatment", Rollout: 0, Values: map[string]interface{}{"buttonColor": "green"}},
+		},
+	}
+
+	ctx := context.Background()
+	attr := map[string]any{"userID": "qa_user"}
+
+	result := engine.Evaluate(ctx, []auroratype.Experiment{exp}, "buttonColor", attr)
+	if result.Matched {
+		t.Error("Expected forced assignment to respect population without forceBypassChecks")
+	}
+
+	exp.ForceBypassChecks = true
+	result = engine.Evaluate(ctx, []auroratype.Experiment{exp}, "buttonColor", attr)
+	if !result.Matched || result.VariantKey != "treatment" || !result.Forced {
+		t.Errorf("Expected forced treatment, got %+v", result)
+	}
+
+	exp.Status = auroratype.StatusScheduled
+	exp.ForceBypassChecks = false
+	exp.PopulationSize = 100
+	other := map[string]any{"userID": "user123"}
+
+	result = engine.Evaluate(ctx, []auroratype.Experiment{exp}, "buttonColor", other, WithForcedVariant("exp_001", "treatment"))
+	if result.Matched {
+		t.Error("Expected call-level forced variant to respect status without bypass")
+	}
+
+	result = engine.Evaluate(ctx, []auroratype.Experiment{exp}, "buttonColor", other, WithForcedVariant("exp_001", "treatment"), WithBypassChecks())
+	if !result.Matched || result.VariantKey != "treatment" || !result.Forced {
+		t.Errorf("Expected call-level forced treatment, got %+v", result)
+	}
+
+	result = engine.Evaluate(ctx, []auroratype.Experiment{exp}, "buttonColor", other, WithForcedVariant("exp_001", "missing"), WithBypassChecks())
+	if result.Matched {
+		t.Error("Expected unknown forced variant to be ignored")
+	}
+}
+
+func TestEngine_LifecycleAndWinner(t *testing.T) {
+	engine := NewEngine()
+	engine.Bootstrap()
+
+	var transitions []string
+	engine.SetTransitionHandler(func(experimentID string, from, to auroratype.ExperimentStatus) {
+		transitions = append(transitions, experimentID+":"+string(from)+"->"+string(to))
+	})
+
+	now := time.Now()
+	start := now.Add(time.Hour).Unix()
+	end := now.Add(2 * time.Hour).Unix()
+	exp := auroratype.Experiment{
+		ID:             "exp_001",
+		Name:           "Test Experiment",
+		Parameters:     []string{"buttonColor"},
+		HashAttribute:  "userID",
+		PopulationSize: 100,
+		Status:         auroratype.StatusScheduled,
+		StartTime:      &start,
+		EndTime:        &end,
+		Constraints: []auroratype.Constraint{
+			{Field: "country", Operator: "equal", Value: "US"},
+		},
+		Winner: "treatment",
+		Variants: []auroratype.Variant{
+			{Key: "control", Rollout: 50, Values: map[string]interface{}{"buttonColor": "blue"}},
+			{Key: "treatment", Rollout: 50, Values: map[string]interface{}{"buttonColor": "green"}},
+		},
+	}
+
+	engine.Observe([]auroratype.Experiment{exp}, now)
+	engine.Observe([]auroratype.Experiment{exp}, now.Add(90*time.Minute))
+	engine.Observe([]auroratype.Experiment{exp}, now.Add(3*time.Hour))
+
+	expected := []string{"exp_001:scheduled->running", "exp_001:running->finished"}
+	if len(transitions) != 2 || transitions[0] != expected[0] || transitions[1] != expected[1] {
+		t.Errorf("Expected transitions %v, got %v", expected, transitions)
+	}
+
+	ctx := context.Background()
+	result := engine.Evaluate(ctx, []auroratype.Experiment{exp}, "buttonColor", map[string]any{"userID": "user123", "country": "US"})
+	if result.Matched {
+		t.Error("Expected scheduled experiment not to match")
+	}
+
+	past := now.Add(-time.Hour).Unix()
+	exp.EndTime = &past
+	exp.StartTime = nil
+	result = engine.Evaluate(ctx, []auroratype.Experiment{exp}, "buttonColor", map[string]any{"country": "VN"})
+	if !result.Matched || !result.Winner || result.Values["buttonColor"] != "green" {
+		t.Errorf("Expected finished experiment to serve the winner to everyone, got %+v", result)
+	}
+}
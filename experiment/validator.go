@@ -2,6 +2,7 @@ package experiment
 
 import (
 	"fmt"
+	"reflect"
 
 	"github.com/tuannguyensn2001/aurora-go/auroratype"
 )
@@ -116,6 +117,17 @@ func validateExperiment(exp auroratype.Experiment) []ValidationError {
 				Field:      fmt.Sprintf("variants[%d].values", i),
 				Message:    "cannot be empty",
 			})
+			continue
+		}
+
+		for _, param := range exp.Parameters {
+			if _, ok := variant.Values[param]; !ok {
+				errors = append(errors, ValidationError{
+					Experiment: exp.ID,
+					Field:      fmt.Sprintf("variants[%d].values.%s", i, param),
+					Message:    "is required for every experiment parameter",
+				})
+			}
 		}
 	}
 
@@ -131,6 +143,131 @@ func validateExperiment(exp auroratype.Experiment) []ValidationError {
 		errors = append(errors, validateExperimentConstraint(exp.ID, i, constraint)...)
 	}
 
+	errors = append(errors, validateExperimentType(exp)...)
+
+	if exp.Winner != "" && findVariant(exp.Variants, exp.Winner) == nil {
+		errors = append(errors, ValidationError{
+			Experiment: exp.ID,
+			Field:      "winner",
+			Message:    fmt.Sprintf("unknown variant: %s", exp.Winner),
+		})
+	}
+
+	for value, key := range exp.ForcedVariants {
+		if findVariant(exp.Variants, key) == nil {
+			errors = append(errors, ValidationError{
+				Experiment: exp.ID,
+				Field:      fmt.Sprintf("forcedVariants[%s]", value),
+				Message:    fmt.Sprintf("unknown variant: %s", key),
+			})
+		}
+	}
+
+	return errors
+}
+
+// ValidateExperimentsWithParameters runs ValidateExperiments and additionally
+// checks that every variant value has the same kind as the default value of
+// the parameter it overrides. Parameters missing from parameters are only
+// served by experiments and are not type checked.
+func ValidateExperimentsWithParameters(experiments []auroratype.Experiment, parameters map[string]auroratype.Parameter) []ValidationError {
+	errors := ValidateExperiments(experiments)
+
+	for _, exp := range experiments {
+		for i, variant := range exp.Variants {
+			for _, param := range exp.Parameters {
+				config, ok := parameters[param]
+				if !ok || config.DefaultValue == nil {
+					continue
+				}
+				value, ok := variant.Values[param]
+				if !ok || value == nil {
+					continue
+				}
+
+				want, got := valueKind(config.DefaultValue), valueKind(value)
+				if want != got {
+					errors = append(errors, ValidationError{
+						Experiment: exp.ID,
+						Field:      fmt.Sprintf("variants[%d].values.%s", i, param),
+						Message:    fmt.Sprintf("expected %s to match the parameter default, got %s", want, got),
+					})
+				}
+			}
+		}
+	}
+
+	return errors
+}
+
+func valueKind(v any) string {
+	switch reflect.ValueOf(v).Kind() {
+	case reflect.Bool:
+		return "bool"
+	case reflect.String:
+		return "string"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "list"
+	case reflect.Map, reflect.Struct:
+		return "map"
+	default:
+		return "unknown"
+	}
+}
+
+func validateExperimentType(exp auroratype.Experiment) []ValidationError {
+	var errors []ValidationError
+
+	switch exp.Type {
+	case "", auroratype.TypeABTest:
+		return nil
+	case auroratype.TypeBandit:
+	default:
+		return []ValidationError{{
+			Experiment: exp.ID,
+			Field:      "type",
+			Message:    fmt.Sprintf("unknown experiment type: %s", exp.Type),
+		}}
+	}
+
+	if exp.Bandit == nil {
+		return []ValidationError{{
+			Experiment: exp.ID,
+			Field:      "bandit",
+			Message:    "is required when type is bandit",
+		}}
+	}
+
+	if exp.Bandit.RewardEvent == "" {
+		errors = append(errors, ValidationError{
+			Experiment: exp.ID,
+			Field:      "bandit.rewardEvent",
+			Message:    "cannot be empty",
+		})
+	}
+
+	switch exp.Bandit.Algorithm {
+	case "", auroratype.BanditThompson, auroratype.BanditEpsilonGreedy:
+	default:
+		errors = append(errors, ValidationError{
+			Experiment: exp.ID,
+			Field:      "bandit.algorithm",
+			Message:    fmt.Sprintf("unknown algorithm: %s", exp.Bandit.Algorithm),
+		})
+	}
+
+	if exp.Bandit.Epsilon < 0 || exp.Bandit.Epsilon > 1 {
+		errors = append(errors, ValidationError{
+			Experiment: exp.ID,
+			Field:      "bandit.epsilon",
+			Message:    "must be between 0 and 1",
+		})
+	}
+
 	return errors
 }
 
@@ -0,0 +1,54 @@
+package experiment
+
+import (
+	"testing"
+
+	"github.com/tuannguyensn2001/aurora-go/auroratype"
+)
+
+func completenessExperiment() auroratype.Experiment {
+	return auroratype.Experiment{
+		ID:             "exp_001",
+		Name:           "Checkout Button Color Test",
+		Parameters:     []string{"checkoutButton", "titleText"},
+		HashAttribute:  "userID",
+		PopulationSize: 100,
+		Status:         auroratype.StatusRunning,
+		Variants: []auroratype.Variant{
+			{Key: "control", Rollout: 50, Values: map[string]interface{}{"checkoutButton": "blue", "titleText": "Buy Now"}},
+			{Key: "treatment", Rollout: 50, Values: map[string]interface{}{"checkoutButton": "green"}},
+		},
+	}
+}
+
+func TestValidateExperiments_MissingVariantValue(t *testing.T) {
+	errs := ValidateExperiments([]auroratype.Experiment{completenessExperiment()})
+	if len(errs) != 1 {
+		t.Fatalf("Expected 1 error, got %v", errs)
+	}
+	if errs[0].Field != "variants[1].values.titleText" {
+		t.Errorf("Expected missing titleText on treatment, got %s", errs[0].Field)
+	}
+}
+
+func TestValidateExperimentsWithParameters(t *testing.T) {
+	exp := completenessExperiment()
+	exp.Variants[1].Values["titleText"] = 42
+
+	parameters := map[string]auroratype.Parameter{
+		"titleText": {DefaultValue: "Buy"},
+	}
+
+	errs := ValidateExperimentsWithParameters([]auroratype.Experiment{exp}, parameters)
+	if len(errs) != 1 {
+		t.Fatalf("Expected 1 error, got %v", errs)
+	}
+	if errs[0].Field != "variants[1].values.titleText" {
+		t.Errorf("Expected type mismatch on treatment titleText, got %s", errs[0].Field)
+	}
+
+	exp.Variants[1].Values["titleText"] = "Purchase"
+	if errs := ValidateExperimentsWithParameters([]auroratype.Experiment{exp}, parameters); len(errs) != 0 {
+		t.Errorf("Expected no errors, got %v", errs)
+	}
+}
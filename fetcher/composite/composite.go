@@ -0,0 +1,379 @@
+package composite
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/tuannguyensn2001/aurora-go/auroratype"
+)
+
+const (
+	MetricCompositeLayerFetchTotal = "composite_layer_fetch_total"
+)
+
+type MetricsRecorder interface {
+	Count(metricName string, count int, tags []string)
+	Histogram(metricName string, value float64, tags []string)
+}
+
+// LayerFetcher is the fetcher of a single layer. It has the same methods as
+// the client's Fetcher, so any fetcher can be used as a layer, including
+// another composite.
+type LayerFetcher interface {
+	Fetch(ctx context.Context) (map[string]auroratype.Parameter, error)
+	FetchExperiments(ctx context.Context) ([]auroratype.Experiment, error)
+	IsStatic() bool
+}
+
+// MergeMode controls how a layer's parameters combine with the same
+// parameters from the layers below it.
+type MergeMode int
+
+const (
+	// MergeReplace replaces the whole parameter.
+	MergeReplace MergeMode = iota
+	// MergePrependRules keeps the default value from below and evaluates
+	// the layer's rules before the rules from below, in each environment
+	// too. A parameter that is not defined below is added as is.
+	MergePrependRules
+)
+
+// Layer is one source of configuration.
+type Layer struct {
+	// Name identifies the layer in sources, status and metrics. Defaults
+	// to "layer<index>".
+	Name    string
+	Fetcher LayerFetcher
+	Merge   MergeMode
+	// Required fails the whole fetch when the layer fails. A layer that is
+	// not required is served from its last successful fetch, or left out
+	// if it never succeeded.
+	Required bool
+}
+
+// Options configures the composite Fetcher.
+type Options struct {
+	// Layers in precedence order: later layers override earlier ones.
+	Layers          []Layer
+	MetricsRecorder MetricsRecorder
+}
+
+// LayerStatus reports the health of a layer.
+type LayerStatus struct {
+	Name        string
+	LastSuccess time.Time
+	// Err is the error of the most recent fetch, if it failed.
+	Err error
+	// Serving is set when the layer contributes to the merged result,
+	// possibly from an earlier fetch if the last one failed.
+	Serving bool
+}
+
+// Fetcher merges the configuration of several fetchers. Experiments are
+// merged by ID, a later layer replacing an experiment of an earlier one.
+// Fetch and FetchExperiments report auroratype.ErrNotModified when no
+// layer changed since their previous call.
+type Fetcher struct {
+	layers   []Layer
+	recorder MetricsRecorder
+
+	mu     sync.Mutex
+	states []layerState
+	// sources of the most recent merged parameters
+	sources map[string]string
+	// merged results have been returned at least once
+	fetchedParameters  bool
+	fetchedExperiments bool
+}
+
+type layerState struct {
+	parameters     map[string]auroratype.Parameter
+	sources        map[string]string
+	hasParameters  bool
+	parametersErr  error
+	experiments    []auroratype.Experiment
+	hasExperiments bool
+	experimentsErr error
+	lastSuccess    time.Time
+}
+
+// NewFetcher creates a composite Fetcher.
+func NewFetcher(opts Options) *Fetcher {
+	recorder := opts.MetricsRecorder
+	if recorder == nil {
+		recorder = &noopRecorder{}
+	}
+	layers := make([]Layer, len(opts.Layers))
+	for i, layer := range opts.Layers {
+		if layer.Name == "" {
+			layer.Name = fmt.Sprintf("layer%d", i)
+		}
+		layers[i] = layer
+	}
+	return &Fetcher{
+		layers:   layers,
+		recorder: recorder,
+		states:   make([]layerState, len(layers)),
+	}
+}
+
+type noopRecorder struct{}
+
+func (n *noopRecorder) Count(metricName string, count int, tags []string)         {}
+func (n *noopRecorder) Histogram(metricName string, value float64, tags []string) {}
+
+type result[T any] struct {
+	value T
+	err   error
+}
+
+// fetchAll calls fetch for every layer concurrently.
+func fetchAll[T any](ctx context.Context, layers []Layer, fetch func(f LayerFetcher, ctx context.Context) (T, error)) []result[T] {
+	results := make([]result[T], len(layers))
+	var wg sync.WaitGroup
+	for i, layer := range layers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			value, err := fetch(layer.Fetcher, ctx)
+			results[i] = result[T]{value: value, err: err}
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// Fetch fetches every layer and merges their parameters.
+func (f *Fetcher) Fetch(ctx context.Context) (map[string]auroratype.Parameter, error) {
+	results := fetchAll(ctx, f.layers, LayerFetcher.Fetch)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	changed := !f.fetchedParameters
+	var errs []error
+	for i, res := range results {
+		layer, state := f.layers[i], &f.states[i]
+		switch {
+		case res.err == nil:
+			state.parameters = res.value
+			state.sources = nil
+			if locator, ok := layer.Fetcher.(interface{ ParameterSources() map[string]string }); ok {
+				state.sources = locator.ParameterSources()
+			}
+			state.hasParameters = true
+			state.parametersErr = nil
+			state.lastSuccess = time.Now()
+			changed = true
+			f.count(layer, "success")
+		case errors.Is(res.err, auroratype.ErrNotModified):
+			state.parametersErr = nil
+			f.count(layer, "not_modified")
+		default:
+			state.parametersErr = res.err
+			f.count(layer, "error")
+			if layer.Required {
+				errs = append(errs, fmt.Errorf("layer %s: %w", layer.Name, res.err))
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	if !changed {
+		return nil, auroratype.ErrNotModified
+	}
+
+	merged := make(map[string]auroratype.Parameter)
+	sources := make(map[string]string)
+	for i, layer := range f.layers {
+		state := f.states[i]
+		if !state.hasParameters {
+			continue
+		}
+		for name, param := range state.parameters {
+			if base, ok := merged[name]; ok && layer.Merge == MergePrependRules {
+				param = prependRules(base, param)
+			}
+			merged[name] = param
+			sources[name] = layer.Name
+			if source := state.sources[name]; source != "" {
+				sources[name] = layer.Name + ":" + source
+			}
+		}
+	}
+
+	f.sources = sources
+	f.fetchedParameters = true
+	return merged, nil
+}
+
+// prependRules merges param over base for MergePrependRules, in every
+// environment as well: each environment keeps the default value of base and
+// evaluates the rules param resolves to before the rules base resolves to.
+func prependRules(base, param auroratype.Parameter) auroratype.Parameter {
+	merged := auroratype.Parameter{
+		DefaultValue: base.DefaultValue,
+		Rules:        concatRules(param.Rules, base.Rules),
+	}
+
+	envs := make(map[string]bool)
+	for env := range base.Environments {
+		envs[env] = true
+	}
+	for env := range param.Environments {
+		envs[env] = true
+	}
+	for env := range envs {
+		baseOverlay, paramOverlay := base.Environments[env], param.Environments[env]
+		overlay := auroratype.ParameterOverlay{DefaultValue: baseOverlay.DefaultValue}
+		if baseOverlay.Rules != nil || paramOverlay.Rules != nil {
+			baseRules, paramRules := base.Rules, param.Rules
+			if baseOverlay.Rules != nil {
+				baseRules = *baseOverlay.Rules
+			}
+			if paramOverlay.Rules != nil {
+				paramRules = *paramOverlay.Rules
+			}
+			rules := concatRules(paramRules, baseRules)
+			overlay.Rules = &rules
+		}
+		if overlay.DefaultValue == nil && overlay.Rules == nil {
+			continue
+		}
+		if merged.Environments == nil {
+			merged.Environments = make(map[string]auroratype.ParameterOverlay)
+		}
+		merged.Environments[env] = overlay
+	}
+	return merged
+}
+
+func concatRules(first, second []auroratype.Rule) []auroratype.Rule {
+	rules := make([]auroratype.Rule, 0, len(first)+len(second))
+	rules = append(rules, first...)
+	return append(rules, second...)
+}
+
+// FetchExperiments fetches every layer and merges their experiments by ID.
+// A layer returning nil experiments keeps its previous ones.
+func (f *Fetcher) FetchExperiments(ctx context.Context) ([]auroratype.Experiment, error) {
+	results := fetchAll(ctx, f.layers, LayerFetcher.FetchExperiments)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	changed := !f.fetchedExperiments
+	var errs []error
+	for i, res := range results {
+		layer, state := f.layers[i], &f.states[i]
+		switch {
+		case res.err == nil && res.value != nil:
+			state.experiments = res.value
+			state.hasExperiments = true
+			state.experimentsErr = nil
+			state.lastSuccess = time.Now()
+			changed = true
+		case res.err == nil || errors.Is(res.err, auroratype.ErrNotModified):
+			state.experimentsErr = nil
+		default:
+			state.experimentsErr = res.err
+			if layer.Required {
+				errs = append(errs, fmt.Errorf("layer %s: %w", layer.Name, res.err))
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	if !changed {
+		return nil, auroratype.ErrNotModified
+	}
+
+	// an empty slice rather than nil, so that removals apply
+	merged := []auroratype.Experiment{}
+	index := make(map[string]int)
+	for _, state := range f.states {
+		for _, exp := range state.experiments {
+			if i, ok := index[exp.ID]; ok {
+				merged[i] = exp
+				continue
+			}
+			index[exp.ID] = len(merged)
+			merged = append(merged, exp)
+		}
+	}
+
+	f.fetchedExperiments = true
+	return merged, nil
+}
+
+// IsStatic reports whether every layer is static.
+func (f *Fetcher) IsStatic() bool {
+	for _, layer := range f.layers {
+		if !layer.Fetcher.IsStatic() {
+			return false
+		}
+	}
+	return true
+}
+
+// ParameterSources returns the layer each parameter of the most recent
+// Fetch came from, as "layer" or "layer:source" when the layer's fetcher
+// reports sources itself.
+func (f *Fetcher) ParameterSources() map[string]string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.sources
+}
+
+// Status reports the health of every layer in precedence order.
+func (f *Fetcher) Status() []LayerStatus {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	status := make([]LayerStatus, len(f.layers))
+	for i, layer := range f.layers {
+		state := f.states[i]
+		status[i] = LayerStatus{
+			Name:        layer.Name,
+			LastSuccess: state.lastSuccess,
+			Err:         errors.Join(state.parametersErr, state.experimentsErr),
+			Serving:     state.hasParameters || state.hasExperiments,
+		}
+	}
+	return status
+}
+
+// Watch calls notify whenever a layer that can watch its source reports a
+// change, until ctx is done. It returns immediately if no layer can watch.
+func (f *Fetcher) Watch(ctx context.Context, notify func()) error {
+	type watcher interface {
+		Watch(ctx context.Context, notify func()) error
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(f.layers))
+	for i, layer := range f.layers {
+		w, ok := layer.Fetcher.(watcher)
+		if !ok {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := w.Watch(ctx, notify); err != nil {
+				errs[i] = fmt.Errorf("layer %s: %w", layer.Name, err)
+			}
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+func (f *Fetcher) count(layer Layer, status string) {
+	f.recorder.Count(MetricCompositeLayerFetchTotal, 1, []string{"layer:" + layer.Name, "status:" + status})
+}
@@ -0,0 +1,230 @@
+package composite
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/tuannguyensn2001/aurora-go/auroratype"
+)
+
+type stubFetcher struct {
+	parameters  map[string]auroratype.Parameter
+	experiments []auroratype.Experiment
+	sources     map[string]string
+	err         error
+	static      bool
+}
+
+func (s *stubFetcher) Fetch(ctx context.Context) (map[string]auroratype.Parameter, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return s.parameters, nil
+}
+
+func (s *stubFetcher) FetchExperiments(ctx context.Context) ([]auroratype.Experiment, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return s.experiments, nil
+}
+
+func (s *stubFetcher) IsStatic() bool {
+	return s.static
+}
+
+type locatingFetcher struct {
+	*stubFetcher
+}
+
+func (l locatingFetcher) ParameterSources() map[string]string {
+	return l.sources
+}
+
+func rule(value any) auroratype.Rule {
+	return auroratype.Rule{RolloutValue: value}
+}
+
+func TestFetchMergesLayersInOrder(t *testing.T) {
+	base := &stubFetcher{
+		parameters: map[string]auroratype.Parameter{
+			"limit":   {DefaultValue: 10, Rules: []auroratype.Rule{rule(20)}},
+			"timeout": {DefaultValue: 5, Rules: []auroratype.Rule{rule(6)}},
+		},
+		experiments: []auroratype.Experiment{{ID: "a", Name: "base"}, {ID: "b"}},
+	}
+	region := &stubFetcher{
+		parameters: map[string]auroratype.Parameter{
+			"limit": {DefaultValue: 100},
+		},
+		experiments: []auroratype.Experiment{{ID: "a", Name: "region"}},
+	}
+	local := locatingFetcher{&stubFetcher{
+		parameters: map[string]auroratype.Parameter{
+			"timeout": {DefaultValue: 1, Rules: []auroratype.Rule{rule(2)}},
+			"debug":   {DefaultValue: true},
+		},
+		sources: map[string]string{"timeout": "local.yaml", "debug": "local.yaml"},
+	}}
+
+	f := NewFetcher(Options{Layers: []Layer{
+		{Name: "base", Fetcher: base, Required: true},
+		{Name: "region", Fetcher: region},
+		{Name: "local", Fetcher: local, Merge: MergePrependRules},
+	}})
+	ctx := context.Background()
+
+	config, err := f.Fetch(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := config["limit"]; got.DefaultValue != 100 || len(got.Rules) != 0 {
+		t.Errorf("limit = %+v, want replaced by region", got)
+	}
+	timeout := config["timeout"]
+	if timeout.DefaultValue != 5 || len(timeout.Rules) != 2 || timeout.Rules[0].RolloutValue != 2 || timeout.Rules[1].RolloutValue != 6 {
+		t.Errorf("timeout = %+v, want local rules prepended to base", timeout)
+	}
+	if config["debug"].DefaultValue != true {
+		t.Errorf("debug = %+v, want added by local", config["debug"])
+	}
+
+	sources := f.ParameterSources()
+	want := map[string]string{"limit": "region", "timeout": "local:local.yaml", "debug": "local:local.yaml"}
+	for name, source := range want {
+		if sources[name] != source {
+			t.Errorf("source of %s = %q, want %q", name, sources[name], source)
+		}
+	}
+
+	experiments, err := f.FetchExperiments(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(experiments) != 2 || experiments[0].Name != "region" || experiments[1].ID != "b" {
+		t.Errorf("experiments = %+v", experiments)
+	}
+}
+
+func TestFetchPrependRulesKeepsEnvironments(t *testing.T) {
+	prodRules := []auroratype.Rule{rule(9)}
+	base := &stubFetcher{parameters: map[string]auroratype.Parameter{
+		"timeout": {
+			DefaultValue: 5,
+			Rules:        []auroratype.Rule{rule(6)},
+			Environments: map[string]auroratype.ParameterOverlay{"staging": {DefaultValue: 50}},
+		},
+	}}
+	local := &stubFetcher{parameters: map[string]auroratype.Parameter{
+		"timeout": {
+			DefaultValue: 1,
+			Rules:        []auroratype.Rule{rule(2)},
+			Environments: map[string]auroratype.ParameterOverlay{"prod": {Rules: &prodRules}},
+		},
+	}}
+	f := NewFetcher(Options{Layers: []Layer{
+		{Name: "base", Fetcher: base, Required: true},
+		{Name: "local", Fetcher: local, Merge: MergePrependRules},
+	}})
+
+	config, err := f.Fetch(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		env     string
+		def     any
+		rollout []any
+	}{
+		{env: "", def: 5, rollout: []any{2, 6}},
+		{env: "staging", def: 50, rollout: []any{2, 6}},
+		{env: "prod", def: 5, rollout: []any{9, 6}},
+	}
+	for _, tt := range tests {
+		timeout := auroratype.ResolveParameters(config, tt.env)["timeout"]
+		var rollout []any
+		for _, r := range timeout.Rules {
+			rollout = append(rollout, r.RolloutValue)
+		}
+		if timeout.DefaultValue != tt.def || len(rollout) != len(tt.rollout) || rollout[0] != tt.rollout[0] || rollout[1] != tt.rollout[1] {
+			t.Errorf("timeout in %q = %v %v, want %v %v", tt.env, timeout.DefaultValue, rollout, tt.def, tt.rollout)
+		}
+	}
+}
+
+func TestFetchPartialFailure(t *testing.T) {
+	base := &stubFetcher{parameters: map[string]auroratype.Parameter{"a": {DefaultValue: 1}}}
+	overrides := &stubFetcher{parameters: map[string]auroratype.Parameter{"a": {DefaultValue: 2}}}
+	f := NewFetcher(Options{Layers: []Layer{
+		{Name: "base", Fetcher: base, Required: true},
+		{Name: "overrides", Fetcher: overrides},
+	}})
+	ctx := context.Background()
+
+	if _, err := f.Fetch(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// a failing optional layer keeps serving its last result
+	overrides.err = errors.New("unavailable")
+	base.parameters = map[string]auroratype.Parameter{"a": {DefaultValue: 1}, "b": {DefaultValue: 3}}
+	config, err := f.Fetch(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config["a"].DefaultValue != 2 || config["b"].DefaultValue != 3 {
+		t.Errorf("config = %+v", config)
+	}
+	status := f.Status()
+	if status[0].Err != nil || status[1].Err == nil || !status[1].Serving {
+		t.Errorf("status = %+v", status)
+	}
+
+	// a failing optional layer that never succeeded is left out
+	f = NewFetcher(Options{Layers: []Layer{
+		{Name: "base", Fetcher: base, Required: true},
+		{Name: "overrides", Fetcher: overrides},
+	}})
+	config, err = f.Fetch(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config["a"].DefaultValue != 1 || f.Status()[1].Serving {
+		t.Errorf("config = %+v, status = %+v", config, f.Status())
+	}
+
+	// a failing required layer fails the fetch
+	base.err = errors.New("unavailable")
+	if _, err := f.Fetch(ctx); err == nil {
+		t.Error("expected error from required layer")
+	}
+}
+
+func TestFetchNotModified(t *testing.T) {
+	base := &stubFetcher{parameters: map[string]auroratype.Parameter{"a": {DefaultValue: 1}}}
+	f := NewFetcher(Options{Layers: []Layer{{Fetcher: base}}})
+	ctx := context.Background()
+
+	if _, err := f.Fetch(ctx); err != nil {
+		t.Fatal(err)
+	}
+	base.err = auroratype.ErrNotModified
+	if _, err := f.Fetch(ctx); !errors.Is(err, auroratype.ErrNotModified) {
+		t.Errorf("err = %v, want ErrNotModified", err)
+	}
+	if name := f.Status()[0].Name; name != "layer0" {
+		t.Errorf("name = %q, want layer0", name)
+	}
+}
+
+func TestIsStatic(t *testing.T) {
+	f := NewFetcher(Options{Layers: []Layer{
+		{Fetcher: &stubFetcher{static: true}},
+		{Fetcher: &stubFetcher{}},
+	}})
+	if f.IsStatic() {
+		t.Error("expected dynamic when any layer is dynamic")
+	}
+}
@@ -0,0 +1,71 @@
+package file
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFetchTOML(t *testing.T) {
+	dir := t.TempDir()
+	paramsPath := filepath.Join(dir, "parameters.toml")
+	expPath := filepath.Join(dir, "experiments.toml")
+	writeFile(t, paramsPath, "[checkout]\ndefaultValue = 10\n")
+	writeFile(t, expPath, "[[experiments]]\nid = \"exp-1\"\n")
+
+	f := New(Options{FilePath: paramsPath, ExperimentsFilePath: expPath})
+	ctx := context.Background()
+
+	config, err := f.Fetch(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := config["checkout"].DefaultValue; got != 10 {
+		t.Errorf("checkout = %v, want 10", got)
+	}
+	experiments, err := f.FetchExperiments(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(experiments) != 1 || experiments[0].ID != "exp-1" {
+		t.Errorf("experiments = %+v", experiments)
+	}
+}
+
+func TestDirMixesFormats(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		"checkout.toml":            "[checkout]\ndefaultValue = true\n",
+		"search.yaml":              "search:\n  defaultValue: bm25\n",
+		"pricing.experiments.toml": "[[experiments]]\nid = \"price\"\n",
+	})
+
+	f := New(Options{Dir: root})
+	ctx := context.Background()
+
+	config, err := f.Fetch(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config["checkout"].DefaultValue != true || config["search"].DefaultValue != "bm25" {
+		t.Errorf("config = %+v", config)
+	}
+	experiments, err := f.FetchExperiments(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(experiments) != 1 || experiments[0].ID != "price" {
+		t.Errorf("experiments = %+v", experiments)
+	}
+}
+
+func TestFetchUnsupportedFormat(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "parameters.ini")
+	writeFile(t, path, "checkout = 10\n")
+
+	_, err := New(Options{FilePath: path}).Fetch(context.Background())
+	if err == nil || !strings.Contains(err.Error(), ".toml") {
+		t.Errorf("Fetch: got %v, want an error listing the supported formats", err)
+	}
+}
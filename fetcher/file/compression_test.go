@@ -0,0 +1,116 @@
+package file
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tuannguyensn2001/aurora-go/auroratype"
+)
+
+func writeCompressed(t *testing.T, path, content, encoding string) {
+	t.Helper()
+	data, err := auroratype.Compress([]byte(content), encoding)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFetchCompressedFiles(t *testing.T) {
+	dir := t.TempDir()
+	paramsPath := filepath.Join(dir, "parameters.yaml.gz")
+	expPath := filepath.Join(dir, "experiments.yaml.zst")
+	writeCompressed(t, paramsPath, "checkout:\n  defaultValue: 10\n", auroratype.EncodingGzip)
+	writeCompressed(t, expPath, "experiments:\n  - id: exp-1\n", auroratype.EncodingZstd)
+	writeCompressed(t, filepath.Join(dir, "parameters.prod.yaml.gz"), "checkout:\n  defaultValue: 20\n", auroratype.EncodingGzip)
+
+	f := New(Options{FilePath: paramsPath, ExperimentsFilePath: expPath, Environment: "prod"})
+	ctx := context.Background()
+
+	config, err := f.Fetch(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := config["checkout"].DefaultValue; got != 20 {
+		t.Errorf("checkout = %v, want the prod overlay's 20", got)
+	}
+
+	experiments, err := f.FetchExperiments(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(experiments) != 1 || experiments[0].ID != "exp-1" {
+		t.Errorf("experiments = %+v", experiments)
+	}
+
+	payload, err := f.FetchRaw(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if payload.Format != "yaml" || payload.Encoding != auroratype.EncodingGzip {
+		t.Errorf("payload format %q encoding %q", payload.Format, payload.Encoding)
+	}
+}
+
+func TestFetchBinarySnapshot(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "snapshot.aurora.zst")
+	data, err := auroratype.MarshalBinarySnapshot(
+		map[string]auroratype.Parameter{"checkout": {DefaultValue: 10}},
+		[]auroratype.Experiment{{ID: "exp-1"}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeCompressed(t, path, string(data), auroratype.EncodingZstd)
+	// ignored: the snapshot holds the experiments
+	writeFile(t, filepath.Join(dir, "experiments.yaml"), "experiments:\n  - id: other\n")
+
+	f := New(Options{FilePath: path})
+	ctx := context.Background()
+
+	config, err := f.Fetch(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := config["checkout"].DefaultValue; got != 10 {
+		t.Errorf("checkout = %v, want 10", got)
+	}
+
+	experiments, err := f.FetchExperiments(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(experiments) != 1 || experiments[0].ID != "exp-1" {
+		t.Errorf("experiments = %+v", experiments)
+	}
+}
+
+func TestDirLoadsCompressedFiles(t *testing.T) {
+	root := t.TempDir()
+	writeCompressed(t, filepath.Join(root, "checkout.yaml.gz"), "checkout:\n  defaultValue: true\n", auroratype.EncodingGzip)
+	writeCompressed(t, filepath.Join(root, "experiments.json.zst"), `{"experiments": [{"id": "exp-1"}]}`, auroratype.EncodingZstd)
+
+	f := New(Options{Dir: root})
+	ctx := context.Background()
+
+	config, err := f.Fetch(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := config["checkout"].DefaultValue; got != true {
+		t.Errorf("checkout = %v, want true", got)
+	}
+
+	experiments, err := f.FetchExperiments(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(experiments) != 1 || experiments[0].ID != "exp-1" {
+		t.Errorf("experiments = %+v", experiments)
+	}
+}
@@ -0,0 +1,203 @@
+package file
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/tuannguyensn2001/aurora-go/auroratype"
+)
+
+func (f *Fetcher) fetchDir() (map[string]auroratype.Parameter, error) {
+	files, err := f.dirFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	parameters := make(map[string]auroratype.Parameter)
+	sources := make(map[string]string)
+	var errs []error
+	for _, file := range files {
+		if isExperimentsFile(file) {
+			continue
+		}
+
+		config, err := decodeParameters(file)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, name := range sortedKeys(config) {
+			if previous, ok := sources[name]; ok {
+				errs = append(errs, fmt.Errorf("duplicate parameter %q defined in %s and %s", name, previous, file))
+				continue
+			}
+			parameters[name] = config[name]
+			sources[name] = file
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	f.setSources(sources)
+	if f.environment == "" {
+		return parameters, nil
+	}
+	f.setBaseParameters(parameters, nil)
+	return auroratype.ResolveParameters(parameters, f.environment), nil
+}
+
+func (f *Fetcher) fetchDirExperiments() ([]auroratype.Experiment, error) {
+	files, err := f.dirFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	// an empty slice rather than nil, so that removing the last experiment
+	// file removes the experiments
+	experiments := []auroratype.Experiment{}
+	sources := make(map[string]string)
+	var errs []error
+	for _, file := range files {
+		if !isExperimentsFile(file) {
+			continue
+		}
+
+		data, format, err := readFile(file)
+		if err != nil {
+			return nil, err
+		}
+		fileExperiments, err := auroratype.DecodeExperiments(data, format)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+
+		for _, exp := range fileExperiments {
+			if previous, ok := sources[exp.ID]; ok {
+				errs = append(errs, fmt.Errorf("duplicate experiment %q defined in %s and %s", exp.ID, previous, file))
+				continue
+			}
+			experiments = append(experiments, exp)
+			sources[exp.ID] = file
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	if f.environment == "" {
+		return experiments, nil
+	}
+	f.setBaseExperiments(experiments, nil)
+	return auroratype.ResolveExperiments(experiments, f.environment), nil
+}
+
+// dirFiles lists the config files under the directory in lexical order.
+// Hidden files and directories are skipped, which also skips the "..data"
+// indirection of Kubernetes ConfigMap volumes while keeping the symlinks
+// that point into it.
+func (f *Fetcher) dirFiles() ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(f.dir, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if file != f.dir && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() || !isConfigFile(file) {
+			return nil
+		}
+
+		// follow symlinks, skipping anything that is not a regular file
+		info, err := os.Stat(file)
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(f.dir, file)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if len(f.include) > 0 {
+			ok, err := matchAny(f.include, rel)
+			if err != nil || !ok {
+				return err
+			}
+		}
+		excluded, err := matchAny(f.exclude, rel)
+		if err != nil || excluded {
+			return err
+		}
+
+		files = append(files, file)
+		return nil
+	})
+	return files, err
+}
+
+func matchAny(patterns []string, rel string) (bool, error) {
+	for _, pattern := range patterns {
+		for _, name := range []string{rel, path.Base(rel)} {
+			ok, err := path.Match(pattern, name)
+			if err != nil {
+				return false, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+			}
+			if ok {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+func decodeParameters(file string) (map[string]auroratype.Parameter, error) {
+	data, format, err := readFile(file)
+	if err != nil {
+		return nil, err
+	}
+	config, err := auroratype.DecodeParameters(data, format)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	return config, nil
+}
+
+// isConfigFile reports whether name has the extension of a registered
+// codec, possibly followed by a compression extension.
+func isConfigFile(name string) bool {
+	_, ok := auroratype.CodecByExtension(name)
+	return ok
+}
+
+// isExperimentsFile reports whether name holds experiments rather than
+// parameters: experiments.yaml or a name ending in .experiments.yaml, with
+// any of the supported extensions.
+func isExperimentsFile(name string) bool {
+	base, _ := auroratype.SplitCompression(filepath.Base(name))
+	base = strings.TrimSuffix(base, filepath.Ext(base))
+	return base == "experiments" || strings.HasSuffix(base, ".experiments")
+}
+
+func sortedKeys(config map[string]auroratype.Parameter) []string {
+	keys := make([]string, 0, len(config))
+	for k := range config {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,142 @@
+package file
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeTree(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		writeFile(t, path, content)
+	}
+}
+
+func TestDirMergesFiles(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		"checkout.yaml":            "checkout:\n  defaultValue: true\n",
+		"search/ranking.json":      `{"ranking": {"defaultValue": "bm25"}}`,
+		"search/experiments.yml":   "experiments:\n  - id: rank\n",
+		"pricing.experiments.json": `{"experiments": [{"id": "price"}]}`,
+		".hidden.yaml":             "hidden:\n  defaultValue: 1\n",
+		"README.md":                "not config",
+	})
+
+	f := New(Options{Dir: root})
+	ctx := context.Background()
+
+	config, err := f.Fetch(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(config) != 2 || config["checkout"].DefaultValue != true || config["ranking"].DefaultValue != "bm25" {
+		t.Errorf("config = %v", config)
+	}
+
+	sources := f.ParameterSources()
+	if want := filepath.Join(root, "search", "ranking.json"); sources["ranking"] != want {
+		t.Errorf("source of ranking = %q, want %q", sources["ranking"], want)
+	}
+
+	experiments, err := f.FetchExperiments(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(experiments) != 2 || experiments[0].ID != "price" || experiments[1].ID != "rank" {
+		t.Errorf("experiments = %v", experiments)
+	}
+}
+
+func TestDirRejectsDuplicates(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		"a.yaml":             "shared:\n  defaultValue: 1\n",
+		"b/b.yaml":           "shared:\n  defaultValue: 2\n",
+		"a.experiments.yaml": "experiments:\n  - id: exp\n",
+		"b/experiments.yaml": "experiments:\n  - id: exp\n",
+	})
+
+	f := New(Options{Dir: root})
+	ctx := context.Background()
+
+	_, err := f.Fetch(ctx)
+	if err == nil {
+		t.Fatal("expected duplicate parameter error")
+	}
+	for _, want := range []string{`"shared"`, filepath.Join(root, "a.yaml"), filepath.Join(root, "b", "b.yaml")} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %s", err, want)
+		}
+	}
+
+	_, err = f.FetchExperiments(ctx)
+	if err == nil || !strings.Contains(err.Error(), `duplicate experiment "exp"`) {
+		t.Errorf("err = %v, want duplicate experiment error", err)
+	}
+}
+
+func TestDirIncludeExclude(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		"prod/a.yaml":      "a:\n  defaultValue: 1\n",
+		"prod/b.yaml":      "b:\n  defaultValue: 1\n",
+		"prod/draft.yaml":  "draft:\n  defaultValue: 1\n",
+		"staging/c.yaml":   "c:\n  defaultValue: 1\n",
+		"prod/experiments": "ignored",
+	})
+
+	f := New(Options{Dir: root, Include: []string{"prod/*"}, Exclude: []string{"draft.*"}})
+	config, err := f.Fetch(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(config) != 2 || config["a"].DefaultValue == nil || config["b"].DefaultValue == nil {
+		t.Errorf("config = %v, want a and b", config)
+	}
+
+	f = New(Options{Dir: root, Include: []string{"["}})
+	if _, err := f.Fetch(context.Background()); err == nil {
+		t.Error("expected invalid pattern error")
+	}
+}
+
+func TestSingleFileSources(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "parameters.yaml")
+	writeFile(t, path, "a:\n  defaultValue: 1\n")
+
+	f := New(Options{FilePath: path})
+	if _, err := f.Fetch(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := f.ParameterSources()["a"]; got != path {
+		t.Errorf("source = %q, want %q", got, path)
+	}
+}
+
+func TestWatchDirNewSubdirectory(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{"a.yaml": "a:\n  defaultValue: 1\n"})
+
+	f := New(Options{Dir: root, Watch: true, WatchDebounce: 20 * time.Millisecond})
+	notified := startWatch(t, f)
+
+	if err := os.Mkdir(filepath.Join(root, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	expectNotifications(t, notified, 1)
+
+	writeFile(t, filepath.Join(root, "sub", "b.yaml"), "b:\n  defaultValue: 2\n")
+	expectNotifications(t, notified, 1)
+
+	writeFile(t, filepath.Join(root, "notes.txt"), "ignored")
+	expectNotifications(t, notified, 0)
+}
@@ -0,0 +1,159 @@
+package file
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/tuannguyensn2001/aurora-go/auroratype"
+)
+
+func TestEnvironmentOverlayFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"parameters.yaml": `
+limit:
+  defaultValue: 10
+  rules:
+    - rolloutValue: 20
+  environments:
+    prod:
+      defaultValue: 50
+timeout:
+  defaultValue: 5
+`,
+		"parameters.prod.yaml": `
+limit:
+  defaultValue: 100
+timeout:
+  rules: []
+debug:
+  defaultValue: false
+`,
+		"experiments.yaml": `
+experiments:
+  - id: a
+  - id: b
+    environments:
+      prod:
+        disabled: true
+`,
+		"experiments.prod.yaml": `
+experiments:
+  a:
+    disabled: true
+`,
+	})
+	path := filepath.Join(dir, "parameters.yaml")
+	ctx := context.Background()
+
+	f := New(Options{FilePath: path, Environment: "prod"})
+	config, err := f.Fetch(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := config["limit"]; got.DefaultValue != 100 || len(got.Rules) != 1 || got.Environments != nil {
+		t.Errorf("limit = %+v", got)
+	}
+	if got := config["timeout"]; got.DefaultValue != 5 || got.Rules == nil || len(got.Rules) != 0 {
+		t.Errorf("timeout = %+v, want rules replaced with none", got)
+	}
+	if got := config["debug"]; got.DefaultValue != false {
+		t.Errorf("debug = %+v, want added by overlay", got)
+	}
+	if got := f.ParameterSources()["limit"]; got != filepath.Join(dir, "parameters.prod.yaml") {
+		t.Errorf("source of limit = %q", got)
+	}
+
+	experiments, err := f.FetchExperiments(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(experiments) != 0 {
+		t.Errorf("experiments = %+v, want all disabled", experiments)
+	}
+	if got := f.ResolvedEnvironments()["prod"]; got.Parameters["limit"].DefaultValue != 100 || len(got.Experiments) != 0 {
+		t.Errorf("resolved prod = %+v", got)
+	}
+
+	// an environment without overlay files only applies its sections
+	f = New(Options{FilePath: path, Environment: "staging"})
+	config, err = f.Fetch(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := config["limit"]; got.DefaultValue != 10 || config["debug"].DefaultValue != nil {
+		t.Errorf("config = %+v", config)
+	}
+	experiments, err = f.FetchExperiments(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(experiments) != 2 {
+		t.Errorf("experiments = %+v", experiments)
+	}
+}
+
+func TestResolvedEnvironmentsIncludeInactiveOverlays(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"parameters.yaml": `
+limit:
+  defaultValue: 10
+`,
+		"parameters.staging.yaml": `
+limit:
+  rules:
+    - rolloutValue: 20
+      percentage: 150
+`,
+		"parameters.prod.old.yaml": `
+limit:
+  defaultValue: broken
+`,
+		"experiments.yaml": `
+experiments:
+  - id: a
+`,
+		"experiments.dev.yaml": `
+experiments:
+  a:
+    disabled: true
+`,
+	})
+	ctx := context.Background()
+
+	f := New(Options{FilePath: filepath.Join(dir, "parameters.yaml"), Environment: "prod"})
+	config, err := f.Fetch(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.FetchExperiments(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := config["limit"]; got.DefaultValue != 10 || len(got.Rules) != 0 {
+		t.Errorf("limit = %+v, want the staging overlay not applied", got)
+	}
+
+	resolved := f.ResolvedEnvironments()
+	if len(resolved) != 2 {
+		t.Fatalf("resolved = %+v, want staging and dev", resolved)
+	}
+	staging := resolved["staging"].Parameters
+	if errs := auroratype.ValidateConfig(staging); len(errs) == 0 {
+		t.Errorf("staging = %+v, want the broken overlay to fail validation", staging)
+	}
+	if got := resolved["dev"]; len(got.Experiments) != 0 || got.Parameters["limit"].DefaultValue != 10 {
+		t.Errorf("dev = %+v", got)
+	}
+
+	// without an environment the client resolves sections, overlay files
+	// are not read
+	f = New(Options{FilePath: filepath.Join(dir, "parameters.yaml")})
+	if _, err := f.Fetch(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := f.ResolvedEnvironments(); got != nil {
+		t.Errorf("resolved = %+v, want none", got)
+	}
+}
@@ -2,94 +2,436 @@ package file
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/tuannguyensn2001/aurora-go/auroratype"
-	"gopkg.in/yaml.v2"
 )
 
 type Fetcher struct {
 	filePath            string
 	experimentsFilePath string
+	dir                 string
+	environment         string
+	include             []string
+	exclude             []string
 	static              bool
+	watch               bool
+	debounce            time.Duration
+
+	mu      sync.Mutex
+	sources map[string]string
+	// snapshot holds the experiments of the binary snapshot decoded by the
+	// most recent Fetch, or nil if FilePath is not a binary snapshot.
+	snapshot []auroratype.Experiment
+	// base holds what the most recent fetches read before resolving the
+	// environment, with the overlay files of every environment. See
+	// ResolvedEnvironments.
+	base environments
+
+	// writeMu serializes writes so that revisions are checked and files
+	// replaced atomically. See StoreParameters.
+	writeMu sync.Mutex
 }
 
 type Options struct {
+	// FilePath and ExperimentsFilePath may be compressed, named with a .gz
+	// or .zst extension, e.g. parameters.yaml.gz. FilePath may also be a
+	// binary snapshot, which holds the experiments as well unless
+	// ExperimentsFilePath is set.
 	FilePath            string
 	ExperimentsFilePath string
-	Static              bool
+	// Dir loads and merges every configuration file under Dir, in any
+	// format registered with auroratype.RegisterCodec, instead of FilePath
+	// and ExperimentsFilePath. See Fetcher.Fetch.
+	Dir string
+	// Include and Exclude are glob patterns matched against each file's
+	// path relative to Dir and against its base name. When Include is set
+	// only matching files are loaded; files matching Exclude are skipped.
+	Include []string
+	Exclude []string
+	// Environment applies the environment sections of parameters and
+	// experiments, followed by the overlay files next to FilePath and
+	// ExperimentsFilePath: parameters.prod.yaml overrides parameters.yaml
+	// for the "prod" environment. Directory mode applies sections only.
+	// The overlay files of the other environments are read as well, so that
+	// every environment is validated. Without an Environment, sections are
+	// left for the client to resolve and overlay files are not read.
+	Environment string
+	Static      bool
+	// Watch syncs as soon as the files change instead of waiting for the
+	// next poll. Polling continues as a safety net unless Static is set.
+	Watch bool
+	// WatchDebounce is how long a burst of filesystem events must be quiet
+	// before a sync is triggered. Defaults to 100ms.
+	WatchDebounce time.Duration
 }
 
 func New(opts Options) *Fetcher {
+	debounce := opts.WatchDebounce
+	if debounce <= 0 {
+		debounce = 100 * time.Millisecond
+	}
 	return &Fetcher{
 		filePath:            opts.FilePath,
 		experimentsFilePath: opts.ExperimentsFilePath,
+		dir:                 opts.Dir,
+		environment:         opts.Environment,
+		include:             opts.Include,
+		exclude:             opts.Exclude,
 		static:              opts.Static,
+		watch:               opts.Watch,
+		debounce:            debounce,
 	}
 }
 
+// Fetch loads the parameters file, or in directory mode every parameters
+// file under the directory. Files named experiments.* or *.experiments.*
+// hold experiments and are skipped. A parameter defined in more than one
+// file is an error naming both files.
 func (f *Fetcher) Fetch(ctx context.Context) (map[string]auroratype.Parameter, error) {
+	if f.dir != "" {
+		return f.fetchDir()
+	}
+
 	if f.filePath == "" {
 		return make(map[string]auroratype.Parameter), nil
 	}
 
-	file, err := os.Open(f.filePath)
+	if _, err := formatOf(f.filePath); err != nil {
+		return nil, err
+	}
+	data, format, err := readFile(f.filePath)
 	if err != nil {
 		return nil, err
 	}
-	defer file.Close()
 
 	var config map[string]auroratype.Parameter
-
-	ext := strings.ToLower(filepath.Ext(f.filePath))
-	if ext == ".yaml" || ext == ".yml" {
-		err = yaml.NewDecoder(file).Decode(&config)
-	} else if ext == ".json" {
-		err = json.NewDecoder(file).Decode(&config)
+	var snapshot []auroratype.Experiment
+	if format == auroratype.FormatBinary {
+		config, snapshot, err = auroratype.UnmarshalBinarySnapshot(data)
+		if snapshot == nil {
+			snapshot = []auroratype.Experiment{}
+		}
 	} else {
-		return nil, errors.New("unsupported file format: must be .yaml, .yml, or .json")
+		config, err = auroratype.DecodeParameters(data, format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", f.filePath, err)
+	}
+	f.setSnapshot(snapshot)
+
+	sources := make(map[string]string, len(config))
+	for name := range config {
+		sources[name] = f.filePath
+	}
+
+	if f.environment != "" {
+		overlays, err := parameterOverlays(f.filePath)
+		if err != nil {
+			return nil, err
+		}
+		f.setBaseParameters(config, overlays)
+
+		config = auroratype.ApplyParameterOverlays(auroratype.ResolveParameters(config, f.environment), overlays[f.environment])
+		for name := range overlays[f.environment] {
+			sources[name] = overlayPath(f.filePath, f.environment)
+		}
+	}
+	f.setSources(sources)
+
+	return config, nil
+}
+
+// overlayPath returns the overlay file of path for env, e.g.
+// parameters.prod.yaml for parameters.yaml and parameters.prod.yaml.gz for
+// parameters.yaml.gz.
+func overlayPath(path, env string) string {
+	base, _ := auroratype.SplitCompression(path)
+	ext := filepath.Ext(base)
+	return strings.TrimSuffix(base, ext) + "." + env + ext + path[len(base):]
+}
+
+// overlayFiles returns the overlay file of path for every environment that
+// has one, e.g. parameters.prod.yaml for "prod".
+func overlayFiles(path string) (map[string]string, error) {
+	base, _ := auroratype.SplitCompression(path)
+	ext := filepath.Ext(base)
+	prefix := strings.TrimSuffix(filepath.Base(base), ext) + "."
+	suffix := ext + path[len(base):]
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		return nil, err
 	}
+	files := make(map[string]string)
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, suffix) || len(name) <= len(prefix)+len(suffix) {
+			continue
+		}
+		env := name[len(prefix) : len(name)-len(suffix)]
+		if strings.Contains(env, ".") {
+			continue
+		}
+		files[env] = filepath.Join(filepath.Dir(path), name)
+	}
+	return files, nil
+}
 
+// parameterOverlays decodes the parameter overlay files of path, keyed by
+// environment.
+func parameterOverlays(path string) (map[string]map[string]auroratype.ParameterOverlay, error) {
+	files, err := overlayFiles(path)
 	if err != nil {
 		return nil, err
 	}
+	overlays := make(map[string]map[string]auroratype.ParameterOverlay, len(files))
+	for env, file := range files {
+		var envOverlays map[string]auroratype.ParameterOverlay
+		if err := decodeFile(file, &envOverlays); err != nil {
+			return nil, err
+		}
+		overlays[env] = envOverlays
+	}
+	return overlays, nil
+}
 
-	return config, nil
+// experimentOverlays decodes the experiment overlay files of path, keyed
+// by environment.
+func experimentOverlays(path string) (map[string]map[string]auroratype.ExperimentOverlay, error) {
+	files, err := overlayFiles(path)
+	if err != nil {
+		return nil, err
+	}
+	overlays := make(map[string]map[string]auroratype.ExperimentOverlay, len(files))
+	for env, file := range files {
+		var envOverlays struct {
+			Experiments map[string]auroratype.ExperimentOverlay `yaml:"experiments" json:"experiments"`
+		}
+		if err := decodeFile(file, &envOverlays); err != nil {
+			return nil, err
+		}
+		overlays[env] = envOverlays.Experiments
+	}
+	return overlays, nil
+}
+
+// ParameterSources returns the file each parameter was loaded from by the
+// most recent Fetch.
+func (f *Fetcher) ParameterSources() map[string]string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.sources
+}
+
+func (f *Fetcher) setSources(sources map[string]string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sources = sources
+}
+
+func (f *Fetcher) setSnapshot(experiments []auroratype.Experiment) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.snapshot = experiments
+}
+
+// snapshotExperiments returns the experiments of the binary snapshot loaded
+// by the most recent Fetch, and whether FilePath is one.
+func (f *Fetcher) snapshotExperiments() ([]auroratype.Experiment, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.snapshot, f.snapshot != nil
+}
+
+// readFile reads path, decompressing it according to its extension, and
+// returns the content with its format. See auroratype.FormatOf.
+func readFile(path string) ([]byte, string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, "", err
+	}
+	_, encoding := auroratype.SplitCompression(path)
+	if data, err = auroratype.Decompress(data, encoding); err != nil {
+		return nil, "", fmt.Errorf("%s: %w", path, err)
+	}
+	return data, auroratype.FormatOf(path, "", data), nil
+}
+
+// decodeFile decodes the overlays file at path into v.
+func decodeFile(path string, v any) error {
+	if _, err := formatOf(path); err != nil {
+		return err
+	}
+	data, format, err := readFile(path)
+	if err != nil {
+		return err
+	}
+	if err := auroratype.Decode(data, format, v); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// formatOf returns the format named by the extension of path, ignoring a
+// compression extension. Any registered codec is supported.
+func formatOf(path string) (string, error) {
+	if base, _ := auroratype.SplitCompression(path); strings.EqualFold(filepath.Ext(base), auroratype.BinarySnapshotExtension) {
+		return auroratype.FormatBinary, nil
+	}
+	if codec, ok := auroratype.CodecByExtension(path); ok {
+		return codec.Name, nil
+	}
+	extensions := append(auroratype.Extensions(), auroratype.BinarySnapshotExtension)
+	return "", fmt.Errorf("unsupported file format: must be %s", strings.Join(extensions, ", "))
+}
+
+// FetchRaw returns the parameters file undecoded. Environment overlay files
+// are not applied, and directory mode is not supported.
+func (f *Fetcher) FetchRaw(ctx context.Context) (auroratype.Payload, error) {
+	if f.dir != "" {
+		return auroratype.Payload{}, errors.New("file: raw payloads are not supported in directory mode")
+	}
+	if f.filePath == "" {
+		return auroratype.Payload{}, nil
+	}
+	if _, err := formatOf(f.filePath); err != nil {
+		return auroratype.Payload{}, err
+	}
+	return readPayload(f.filePath)
+}
+
+// FetchExperimentsRaw returns the experiments file undecoded, or an empty
+// payload if there is none.
+func (f *Fetcher) FetchExperimentsRaw(ctx context.Context) (auroratype.Payload, error) {
+	if f.dir != "" {
+		return auroratype.Payload{}, errors.New("file: raw payloads are not supported in directory mode")
+	}
+	path := f.experimentsPath()
+	if path == "" {
+		return auroratype.Payload{}, nil
+	}
+	payload, err := readPayload(path)
+	if os.IsNotExist(err) {
+		return auroratype.Payload{}, nil
+	}
+	return payload, err
+}
+
+// ReadObject reads the file at name, decompressing it according to its
+// extension.
+func (f *Fetcher) ReadObject(ctx context.Context, name string) ([]byte, error) {
+	data, _, err := readFile(name)
+	return data, err
+}
+
+func readPayload(path string) (auroratype.Payload, error) {
+	data, format, err := readFile(path)
+	if err != nil {
+		return auroratype.Payload{}, err
+	}
+	_, encoding := auroratype.SplitCompression(path)
+	return auroratype.Payload{Name: path, Data: data, Format: format, Encoding: encoding}, nil
+}
+
+// experimentsPath returns the experiments file, which defaults to
+// experiments.yaml next to the parameters file unless that is a binary
+// snapshot.
+func (f *Fetcher) experimentsPath() string {
+	if f.experimentsFilePath == "" && f.filePath != "" {
+		if format, _ := formatOf(f.filePath); format == auroratype.FormatBinary {
+			return ""
+		}
+		if _, ok := f.snapshotExperiments(); ok {
+			return ""
+		}
+		return filepath.Join(filepath.Dir(f.filePath), "experiments.yaml")
+	}
+	return f.experimentsFilePath
 }
 
 func (f *Fetcher) FetchExperiments(ctx context.Context) ([]auroratype.Experiment, error) {
-	expFilePath := f.experimentsFilePath
+	if f.dir != "" {
+		return f.fetchDirExperiments()
+	}
 
-	if expFilePath == "" && f.filePath != "" {
-		dir := filepath.Dir(f.filePath)
-		expFilePath = filepath.Join(dir, "experiments.yaml")
+	if f.experimentsFilePath == "" {
+		if experiments, ok := f.snapshotExperiments(); ok {
+			if f.environment == "" {
+				return experiments, nil
+			}
+			f.setBaseExperiments(experiments, nil)
+			return auroratype.ResolveExperiments(experiments, f.environment), nil
+		}
 	}
 
+	expFilePath := f.experimentsPath()
 	if expFilePath == "" {
 		return nil, nil
 	}
 
-	data, err := os.ReadFile(expFilePath)
+	data, format, err := readFile(expFilePath)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return nil, nil
 		}
 		return nil, err
 	}
+	experiments, err := auroratype.DecodeExperiments(data, format)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", expFilePath, err)
+	}
 
-	var config struct {
-		Experiments []auroratype.Experiment `yaml:"experiments"`
+	if f.environment == "" {
+		return experiments, nil
 	}
 
-	if err := yaml.Unmarshal(data, &config); err != nil {
+	overlays, err := experimentOverlays(expFilePath)
+	if err != nil {
 		return nil, err
 	}
+	f.setBaseExperiments(experiments, overlays)
+	return auroratype.ApplyExperimentOverlays(auroratype.ResolveExperiments(experiments, f.environment), overlays[f.environment]), nil
+}
+
+// environments is the configuration before an environment is resolved.
+type environments struct {
+	parameters         map[string]auroratype.Parameter
+	parameterOverlays  map[string]map[string]auroratype.ParameterOverlay
+	experiments        []auroratype.Experiment
+	experimentOverlays map[string]map[string]auroratype.ExperimentOverlay
+}
+
+func (f *Fetcher) setBaseParameters(parameters map[string]auroratype.Parameter, overlays map[string]map[string]auroratype.ParameterOverlay) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.base.parameters = parameters
+	f.base.parameterOverlays = overlays
+}
 
-	return config.Experiments, nil
+func (f *Fetcher) setBaseExperiments(experiments []auroratype.Experiment, overlays map[string]map[string]auroratype.ExperimentOverlay) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.base.experiments = experiments
+	f.base.experimentOverlays = overlays
+}
+
+// ResolvedEnvironments returns the configuration of the most recent fetches
+// resolved for every environment with a section or an overlay file. See
+// auroratype.EnvironmentResolver.
+func (f *Fetcher) ResolvedEnvironments() map[string]auroratype.EnvironmentContent {
+	if f.environment == "" {
+		return nil
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return auroratype.ResolveEnvironments(f.base.parameters, f.base.experiments, f.base.parameterOverlays, f.base.experimentOverlays)
 }
 
 func (f *Fetcher) IsStatic() bool {
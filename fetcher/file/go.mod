@@ -3,8 +3,16 @@ module github.com/tuannguyensn2001/aurora-go/fetcher/file
 go 1.25.5
 
 require (
+	github.com/fsnotify/fsnotify v1.10.1
 	github.com/tuannguyensn2001/aurora-go/auroratype v0.0.0
-	gopkg.in/yaml.v2 v2.4.0
+)
+
+require (
+	github.com/BurntSushi/toml v1.6.0 // indirect
+	github.com/klauspost/compress v1.18.0 // indirect
+	golang.org/x/sys v0.13.0 // indirect
+	gopkg.in/yaml.v2 v2.4.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
 
 replace github.com/tuannguyensn2001/aurora-go/auroratype => ../../auroratype
@@ -0,0 +1,146 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Watch calls notify after the parameters or experiments file changes,
+// until ctx is done. It returns immediately if watching is not enabled.
+//
+// The directories holding the files are watched rather than the files
+// themselves, so that editors renaming a new file over the old one and
+// Kubernetes ConfigMap volumes, which swap a "..data" symlink, are both
+// seen. Bursts of events are coalesced into a single notification.
+//
+// In directory mode every directory under Dir is watched, including ones
+// created later, and any change to a config file triggers a notification.
+func (f *Fetcher) Watch(ctx context.Context, notify func()) error {
+	if !f.watch {
+		return nil
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	if f.dir != "" {
+		return f.watchDir(ctx, watcher, notify)
+	}
+
+	targets := make(map[string]bool)
+	dirs := make(map[string]bool)
+	paths := []string{f.filePath, f.experimentsPath()}
+	if f.environment != "" {
+		for _, path := range paths {
+			if path != "" {
+				paths = append(paths, overlayPath(path, f.environment))
+			}
+		}
+	}
+	for _, path := range paths {
+		if path == "" {
+			continue
+		}
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return err
+		}
+		targets[abs] = true
+		dirs[filepath.Dir(abs)] = true
+	}
+	if len(dirs) == 0 {
+		return errors.New("file: nothing to watch")
+	}
+	for dir := range dirs {
+		if err := watcher.Add(dir); err != nil {
+			return err
+		}
+	}
+
+	relevant := func(event fsnotify.Event) bool {
+		// "..data" and its timestamped targets are how Kubernetes
+		// atomically updates projected volumes
+		return targets[event.Name] || strings.HasPrefix(filepath.Base(event.Name), "..")
+	}
+
+	return f.watchLoop(ctx, watcher, relevant, notify)
+}
+
+func (f *Fetcher) watchDir(ctx context.Context, watcher *fsnotify.Watcher, notify func()) error {
+	err := filepath.WalkDir(f.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if path != f.dir && strings.HasPrefix(d.Name(), ".") {
+			return filepath.SkipDir
+		}
+		return watcher.Add(path)
+	})
+	if err != nil {
+		return err
+	}
+
+	relevant := func(event fsnotify.Event) bool {
+		base := filepath.Base(event.Name)
+		if strings.HasPrefix(base, "..") {
+			return true
+		}
+		if strings.HasPrefix(base, ".") {
+			return false
+		}
+		if event.Has(fsnotify.Create) {
+			if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+				// a new directory may already hold files by the time it
+				// is watched, so treat it as a change
+				_ = watcher.Add(event.Name)
+				return true
+			}
+		}
+		// removing a directory removes the files in it
+		return isConfigFile(event.Name) || event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename)
+	}
+
+	return f.watchLoop(ctx, watcher, relevant, notify)
+}
+
+func (f *Fetcher) watchLoop(ctx context.Context, watcher *fsnotify.Watcher, relevant func(fsnotify.Event) bool, notify func()) error {
+	debounce := time.NewTimer(f.debounce)
+	debounce.Stop()
+	defer debounce.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if event.Op == fsnotify.Chmod || !relevant(event) {
+				continue
+			}
+			debounce.Reset(f.debounce)
+		case _, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			// events may have been dropped, so sync to be safe
+			debounce.Reset(f.debounce)
+		case <-debounce.C:
+			notify()
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package file
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func startWatch(t *testing.T, f *Fetcher) <-chan struct{} {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	notified := make(chan struct{}, 16)
+	done := make(chan error, 1)
+	go func() {
+		done <- f.Watch(ctx, func() { notified <- struct{}{} })
+	}()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("Watch: %v", err)
+		}
+	})
+	// let the watcher register its directories
+	time.Sleep(50 * time.Millisecond)
+	return notified
+}
+
+func expectNotifications(t *testing.T, notified <-chan struct{}, want int) {
+	t.Helper()
+	deadline := time.After(500 * time.Millisecond)
+	got := 0
+	for {
+		select {
+		case <-notified:
+			got++
+		case <-deadline:
+			if got != want {
+				t.Errorf("notifications = %d, want %d", got, want)
+			}
+			return
+		}
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWatchRenameOverFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "parameters.yaml")
+	writeFile(t, path, "a:\n  defaultValue: 1\n")
+
+	f := New(Options{FilePath: path, Watch: true, WatchDebounce: 20 * time.Millisecond})
+	notified := startWatch(t, f)
+
+	tmp := filepath.Join(dir, ".parameters.yaml.swp")
+	writeFile(t, tmp, "a:\n  defaultValue: 2\n")
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatal(err)
+	}
+
+	expectNotifications(t, notified, 1)
+
+	config, err := f.Fetch(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := config["a"].DefaultValue; got != 2 {
+		t.Errorf("a = %v, want 2", got)
+	}
+}
+
+func TestWatchConfigMapSymlinkSwap(t *testing.T) {
+	dir := t.TempDir()
+	for _, version := range []string{"..v1", "..v2"} {
+		if err := os.Mkdir(filepath.Join(dir, version), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFile(t, filepath.Join(dir, "..v1", "parameters.yaml"), "a:\n  defaultValue: 1\n")
+	writeFile(t, filepath.Join(dir, "..v2", "parameters.yaml"), "a:\n  defaultValue: 2\n")
+	if err := os.Symlink("..v1", filepath.Join(dir, "..data")); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "parameters.yaml")
+	if err := os.Symlink(filepath.Join("..data", "parameters.yaml"), path); err != nil {
+		t.Fatal(err)
+	}
+
+	f := New(Options{FilePath: path, Watch: true, WatchDebounce: 20 * time.Millisecond})
+	notified := startWatch(t, f)
+
+	if err := os.Symlink("..v2", filepath.Join(dir, "..data_tmp")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(filepath.Join(dir, "..data_tmp"), filepath.Join(dir, "..data")); err != nil {
+		t.Fatal(err)
+	}
+
+	expectNotifications(t, notified, 1)
+
+	config, err := f.Fetch(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := config["a"].DefaultValue; got != 2 {
+		t.Errorf("a = %v, want 2", got)
+	}
+}
+
+func TestWatchDebouncesBursts(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "parameters.yaml")
+	writeFile(t, path, "a:\n  defaultValue: 1\n")
+
+	f := New(Options{FilePath: path, Watch: true, WatchDebounce: 50 * time.Millisecond})
+	notified := startWatch(t, f)
+
+	for i := 0; i < 10; i++ {
+		writeFile(t, path, "a:\n  defaultValue: 2\n")
+	}
+	writeFile(t, filepath.Join(dir, "unrelated.txt"), "ignored")
+
+	expectNotifications(t, notified, 1)
+}
+
+func TestWatchDisabled(t *testing.T) {
+	f := New(Options{FilePath: "parameters.yaml"})
+	if err := f.Watch(context.Background(), func() {}); err != nil {
+		t.Errorf("Watch: %v", err)
+	}
+}
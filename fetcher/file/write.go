@@ -0,0 +1,168 @@
+package file
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/tuannguyensn2001/aurora-go/auroratype"
+)
+
+// Load reads the parameters and experiments files as stored, without
+// applying environment sections or overlay files. Revisions are hashes of
+// the file contents. Directory mode and binary snapshots are read-only.
+func (f *Fetcher) Load(ctx context.Context) (auroratype.SourceContent, error) {
+	paramsPath, expPath, err := f.writablePaths()
+	if err != nil {
+		return auroratype.SourceContent{}, err
+	}
+
+	var content auroratype.SourceContent
+	data, format, revision, err := readRevision(paramsPath)
+	if err != nil {
+		return auroratype.SourceContent{}, err
+	}
+	if content.Parameters, err = auroratype.DecodeParameters(data, format); err != nil {
+		return auroratype.SourceContent{}, fmt.Errorf("%s: %w", paramsPath, err)
+	}
+	content.ParametersRevision = revision
+
+	data, format, revision, err = readRevision(expPath)
+	if err != nil {
+		return auroratype.SourceContent{}, err
+	}
+	if content.Experiments, err = auroratype.DecodeExperiments(data, format); err != nil {
+		return auroratype.SourceContent{}, fmt.Errorf("%s: %w", expPath, err)
+	}
+	content.ExperimentsRevision = revision
+	return content, nil
+}
+
+// StoreParameters replaces the parameters file, keeping its format and
+// compression, unless it no longer has the given revision.
+func (f *Fetcher) StoreParameters(ctx context.Context, parameters map[string]auroratype.Parameter, revision string) (string, error) {
+	path, _, err := f.writablePaths()
+	if err != nil {
+		return "", err
+	}
+	return f.store(path, revision, func(format string) ([]byte, error) {
+		return auroratype.Encode(parameters, format)
+	})
+}
+
+// StoreExperiments replaces the experiments file, keeping its format and
+// compression, unless it no longer has the given revision.
+func (f *Fetcher) StoreExperiments(ctx context.Context, experiments []auroratype.Experiment, revision string) (string, error) {
+	_, path, err := f.writablePaths()
+	if err != nil {
+		return "", err
+	}
+	return f.store(path, revision, func(format string) ([]byte, error) {
+		return auroratype.EncodeExperiments(experiments, format)
+	})
+}
+
+// writablePaths returns the parameters and experiments files, or an error
+// if the configuration cannot be written back.
+func (f *Fetcher) writablePaths() (string, string, error) {
+	if f.dir != "" {
+		return "", "", errors.New("file: directory mode is read-only")
+	}
+	if f.filePath == "" {
+		return "", "", errors.New("file: no parameters file to write")
+	}
+	format, err := formatOf(f.filePath)
+	if err != nil {
+		return "", "", err
+	}
+	if format == auroratype.FormatBinary {
+		return "", "", errors.New("file: binary snapshots are read-only")
+	}
+	return f.filePath, f.experimentsPath(), nil
+}
+
+// store writes the file at path atomically, through a temporary file in
+// the same directory, if it still has revision.
+func (f *Fetcher) store(path, revision string, encode func(format string) ([]byte, error)) (string, error) {
+	format, err := formatOf(path)
+	if err != nil {
+		return "", err
+	}
+	if format == auroratype.FormatBinary {
+		return "", fmt.Errorf("file: %s: binary snapshots are read-only", path)
+	}
+
+	f.writeMu.Lock()
+	defer f.writeMu.Unlock()
+
+	if _, _, current, err := readRevision(path); err != nil {
+		return "", err
+	} else if current != revision {
+		return "", fmt.Errorf("%s: %w", path, auroratype.ErrConflict)
+	}
+
+	data, err := encode(format)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", path, err)
+	}
+	_, encoding := auroratype.SplitCompression(path)
+	if data, err = auroratype.Compress(data, encoding); err != nil {
+		return "", err
+	}
+	if err := writeAtomic(path, data); err != nil {
+		return "", err
+	}
+	return hash(data), nil
+}
+
+// readRevision reads path like readFile and returns the hash of its stored
+// bytes. A missing file has no content and an empty revision.
+func readRevision(path string) ([]byte, string, string, error) {
+	stored, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, auroratype.FormatOf(path, "", nil), "", nil
+	}
+	if err != nil {
+		return nil, "", "", err
+	}
+	_, encoding := auroratype.SplitCompression(path)
+	data, err := auroratype.Decompress(stored, encoding)
+	if err != nil {
+		return nil, "", "", fmt.Errorf("%s: %w", path, err)
+	}
+	return data, auroratype.FormatOf(path, "", data), hash(stored), nil
+}
+
+func writeAtomic(path string, data []byte) error {
+	mode := os.FileMode(0o644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func hash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,98 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tuannguyensn2001/aurora-go/auroratype"
+)
+
+func TestStoreParameters(t *testing.T) {
+	dir := t.TempDir()
+	paramsPath := filepath.Join(dir, "parameters.json.gz")
+	writeCompressed(t, paramsPath, `{"checkout": {"defaultValue": 10}}`, "gzip")
+
+	f := New(Options{FilePath: paramsPath})
+	ctx := context.Background()
+
+	content, err := f.Load(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content.Parameters["checkout"].DefaultValue != float64(10) || content.ParametersRevision == "" {
+		t.Fatalf("content = %+v", content)
+	}
+	if content.Experiments != nil || content.ExperimentsRevision != "" {
+		t.Errorf("missing experiments file: content = %+v", content)
+	}
+
+	content.Parameters["killSwitch"] = auroratype.Parameter{DefaultValue: true}
+	revision, err := f.StoreParameters(ctx, content.Parameters, content.ParametersRevision)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if revision == content.ParametersRevision {
+		t.Error("revision did not change")
+	}
+
+	// the file keeps its format and compression
+	config, err := f.Fetch(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config["killSwitch"].DefaultValue != true || config["checkout"].DefaultValue != float64(10) {
+		t.Errorf("config = %+v", config)
+	}
+
+	if _, err := f.StoreParameters(ctx, content.Parameters, content.ParametersRevision); !errors.Is(err, auroratype.ErrConflict) {
+		t.Errorf("stale revision: err = %v, want ErrConflict", err)
+	}
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("temporary files left behind: %v", entries)
+	}
+}
+
+func TestStoreExperiments(t *testing.T) {
+	dir := t.TempDir()
+	paramsPath := filepath.Join(dir, "parameters.yaml")
+	writeFile(t, paramsPath, "checkout:\n  defaultValue: 10\n")
+	f := New(Options{FilePath: paramsPath})
+	ctx := context.Background()
+
+	experiments := []auroratype.Experiment{{ID: "exp-1", HashAttribute: "user_id", Status: auroratype.StatusAborted}}
+	if _, err := f.StoreExperiments(ctx, experiments, "stale"); !errors.Is(err, auroratype.ErrConflict) {
+		t.Errorf("new file with a revision: err = %v, want ErrConflict", err)
+	}
+	revision, err := f.StoreExperiments(ctx, experiments, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := f.Load(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content.ExperimentsRevision != revision || len(content.Experiments) != 1 || content.Experiments[0].Status != auroratype.StatusAborted {
+		t.Errorf("content = %+v, want the stored experiment at revision %s", content, revision)
+	}
+}
+
+func TestStoreReadOnly(t *testing.T) {
+	ctx := context.Background()
+	for name, f := range map[string]*Fetcher{
+		"dir":    New(Options{Dir: t.TempDir()}),
+		"binary": New(Options{FilePath: filepath.Join(t.TempDir(), "snapshot.aurora")}),
+	} {
+		if _, err := f.Load(ctx); err == nil {
+			t.Errorf("%s: Load succeeded", name)
+		}
+		if _, err := f.StoreParameters(ctx, nil, ""); err == nil {
+			t.Errorf("%s: StoreParameters succeeded", name)
+		}
+	}
+}
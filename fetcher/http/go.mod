@@ -0,0 +1,14 @@
+module github.com/tuannguyensn2001/aurora-go/fetcher/http
+
+go 1.25.5
+
+require github.com/tuannguyensn2001/aurora-go/auroratype v0.0.0
+
+require (
+	github.com/BurntSushi/toml v1.6.0 // indirect
+	github.com/klauspost/compress v1.18.0 // indirect
+	gopkg.in/yaml.v2 v2.4.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace github.com/tuannguyensn2001/aurora-go/auroratype => ../../auroratype
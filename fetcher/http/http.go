@@ -0,0 +1,284 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/tuannguyensn2001/aurora-go/auroratype"
+)
+
+const (
+	MetricHTTPFetchLatency            = "http_fetch_latency"
+	MetricHTTPFetchTotal              = "http_fetch_total"
+	MetricHTTPFetchExperimentsLatency = "http_fetch_experiments_latency"
+	MetricHTTPFetchExperimentsTotal   = "http_fetch_experiments_total"
+)
+
+type MetricsRecorder interface {
+	Count(metricName string, count int, tags []string)
+	Histogram(metricName string, value float64, tags []string)
+}
+
+// Fetcher fetches configuration from HTTP(S) endpoints. Responses are
+// requested conditionally with If-None-Match and If-Modified-Since, and an
+// unchanged payload is reported as auroratype.ErrNotModified without being
+// downloaded or parsed again.
+//
+// gzip and zstd responses are accepted and decompressed, as are URLs with a
+// .gz or .zst extension. The parameters may be a binary snapshot, which
+// holds the experiments as well unless ExperimentsURL is set.
+type Fetcher struct {
+	client         *http.Client
+	url            string
+	experimentsURL string
+	header         http.Header
+	auth           func(req *http.Request) error
+	recorder       MetricsRecorder
+
+	parameters  validators
+	experiments validators
+
+	// snapshot holds the experiments of a binary snapshot retrieved by
+	// Fetch until FetchExperiments returns them.
+	snapshotMu sync.Mutex
+	snapshot   []auroratype.Experiment
+}
+
+// Options configures the HTTP Fetcher.
+type Options struct {
+	// URL serves the parameters. Empty means no parameters.
+	URL string
+	// ExperimentsURL serves the experiments. Empty means no experiments.
+	ExperimentsURL string
+	// Client defaults to http.DefaultClient.
+	Client *http.Client
+	// Header is added to every request.
+	Header http.Header
+	// Auth is called for every request before it is sent, for example to
+	// attach a freshly minted token.
+	Auth            func(req *http.Request) error
+	MetricsRecorder MetricsRecorder
+}
+
+// NewFetcher creates a new HTTP-based Fetcher.
+func NewFetcher(opts Options) *Fetcher {
+	client := opts.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	recorder := opts.MetricsRecorder
+	if recorder == nil {
+		recorder = &noopRecorder{}
+	}
+	return &Fetcher{
+		client:         client,
+		url:            opts.URL,
+		experimentsURL: opts.ExperimentsURL,
+		header:         opts.Header,
+		auth:           opts.Auth,
+		recorder:       recorder,
+	}
+}
+
+type noopRecorder struct{}
+
+func (n *noopRecorder) Count(metricName string, count int, tags []string)         {}
+func (n *noopRecorder) Histogram(metricName string, value float64, tags []string) {}
+
+// validators remembers the ETag and Last-Modified of the last successful
+// response for a URL.
+type validators struct {
+	mu           sync.Mutex
+	etag         string
+	lastModified string
+}
+
+// Fetch retrieves parameters from the configured URL.
+func (f *Fetcher) Fetch(ctx context.Context) (map[string]auroratype.Parameter, error) {
+	if f.url == "" {
+		return make(map[string]auroratype.Parameter), nil
+	}
+	return fetch(ctx, f, f.url, &f.parameters, MetricHTTPFetchLatency, MetricHTTPFetchTotal,
+		func(p auroratype.Payload, data []byte) (map[string]auroratype.Parameter, error) {
+			if p.Format != auroratype.FormatBinary {
+				return auroratype.DecodeParameters(data, p.Format)
+			}
+			config, experiments, err := auroratype.UnmarshalBinarySnapshot(data)
+			if err == nil && f.experimentsURL == "" {
+				if experiments == nil {
+					experiments = []auroratype.Experiment{}
+				}
+				f.setSnapshot(experiments)
+			}
+			return config, err
+		})
+}
+
+// FetchExperiments retrieves experiments from the configured URL.
+func (f *Fetcher) FetchExperiments(ctx context.Context) ([]auroratype.Experiment, error) {
+	if f.experimentsURL == "" {
+		// nil, meaning unchanged, unless Fetch retrieved a new snapshot
+		return f.takeSnapshot(), nil
+	}
+	return fetch(ctx, f, f.experimentsURL, &f.experiments, MetricHTTPFetchExperimentsLatency, MetricHTTPFetchExperimentsTotal,
+		func(p auroratype.Payload, data []byte) ([]auroratype.Experiment, error) {
+			return auroratype.DecodeExperiments(data, p.Format)
+		})
+}
+
+func (f *Fetcher) setSnapshot(experiments []auroratype.Experiment) {
+	f.snapshotMu.Lock()
+	defer f.snapshotMu.Unlock()
+	f.snapshot = experiments
+}
+
+func (f *Fetcher) takeSnapshot() []auroratype.Experiment {
+	f.snapshotMu.Lock()
+	defer f.snapshotMu.Unlock()
+	experiments := f.snapshot
+	f.snapshot = nil
+	return experiments
+}
+
+func (f *Fetcher) IsStatic() bool {
+	return false
+}
+
+// FetchRaw retrieves the parameters without decoding them.
+func (f *Fetcher) FetchRaw(ctx context.Context) (auroratype.Payload, error) {
+	if f.url == "" {
+		return auroratype.Payload{}, nil
+	}
+	return fetch(ctx, f, f.url, &f.parameters, MetricHTTPFetchLatency, MetricHTTPFetchTotal, payload)
+}
+
+// FetchExperimentsRaw retrieves the experiments without decoding them.
+func (f *Fetcher) FetchExperimentsRaw(ctx context.Context) (auroratype.Payload, error) {
+	if f.experimentsURL == "" {
+		return auroratype.Payload{}, nil
+	}
+	return fetch(ctx, f, f.experimentsURL, &f.experiments, MetricHTTPFetchExperimentsLatency, MetricHTTPFetchExperimentsTotal, payload)
+}
+
+// ReadObject retrieves rawURL unconditionally, with the configured header
+// and authentication.
+func (f *Fetcher) ReadObject(ctx context.Context, rawURL string) ([]byte, error) {
+	var unconditional validators
+	return fetch(ctx, f, rawURL, &unconditional, MetricHTTPFetchLatency, MetricHTTPFetchTotal,
+		func(p auroratype.Payload, data []byte) ([]byte, error) {
+			return data, nil
+		})
+}
+
+func payload(p auroratype.Payload, data []byte) (auroratype.Payload, error) {
+	p.Data = data
+	return p, nil
+}
+
+// fetch retrieves rawURL and passes decode the response both as received
+// and decompressed.
+func fetch[T any](ctx context.Context, f *Fetcher, rawURL string, c *validators, latencyMetric, totalMetric string, decode func(p auroratype.Payload, data []byte) (T, error)) (T, error) {
+	start := time.Now()
+	defer func() {
+		duration := float64(time.Since(start).Microseconds())
+		f.recorder.Histogram(latencyMetric, duration, []string{"unit:microseconds"})
+	}()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var zero T
+	fail := func(err error) (T, error) {
+		f.recorder.Count(totalMetric, 1, []string{"status:error"})
+		return zero, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return fail(err)
+	}
+	for key, values := range f.header {
+		for _, v := range values {
+			req.Header.Add(key, v)
+		}
+	}
+	if c.etag != "" {
+		req.Header.Set("If-None-Match", c.etag)
+	}
+	if c.lastModified != "" {
+		req.Header.Set("If-Modified-Since", c.lastModified)
+	}
+	// set explicitly, so the transport leaves decompression to us
+	if req.Header.Get("Accept-Encoding") == "" {
+		req.Header.Set("Accept-Encoding", "gzip, zstd")
+	}
+	if f.auth != nil {
+		if err := f.auth(req); err != nil {
+			return fail(err)
+		}
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return fail(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified && (c.etag != "" || c.lastModified != "") {
+		io.Copy(io.Discard, resp.Body)
+		f.recorder.Count(totalMetric, 1, []string{"status:not_modified"})
+		return zero, auroratype.ErrNotModified
+	}
+	if resp.StatusCode != http.StatusOK {
+		io.Copy(io.Discard, resp.Body)
+		return fail(fmt.Errorf("GET %s: unexpected status %s", rawURL, resp.Status))
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fail(err)
+	}
+
+	p := auroratype.Payload{
+		Name:     rawURL,
+		Data:     data,
+		Encoding: resp.Header.Get("Content-Encoding"),
+	}
+	if p.Encoding == "" {
+		if u, err := url.Parse(rawURL); err == nil {
+			_, p.Encoding = auroratype.SplitCompression(u.Path)
+		}
+	}
+	content, err := auroratype.Decompress(data, p.Encoding)
+	if err != nil {
+		return fail(fmt.Errorf("GET %s: %w", rawURL, err))
+	}
+	p.Format = format(resp.Header.Get("Content-Type"), rawURL, content)
+
+	value, err := decode(p, content)
+	if err != nil {
+		return fail(err)
+	}
+
+	c.etag = resp.Header.Get("ETag")
+	c.lastModified = resp.Header.Get("Last-Modified")
+
+	f.recorder.Count(totalMetric, 1, []string{"status:success"})
+	return value, nil
+}
+
+// format returns the format of content served from rawURL with the given
+// Content-Type, falling back to the extension of the URL path. See
+// auroratype.FormatOf.
+func format(contentType, rawURL string, content []byte) string {
+	var name string
+	if u, err := url.Parse(rawURL); err == nil {
+		name = u.Path
+	}
+	return auroratype.FormatOf(name, contentType, content)
+}
@@ -0,0 +1,194 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/tuannguyensn2001/aurora-go/auroratype"
+)
+
+const parametersYAML = `
+maxConnections:
+  defaultValue: 10
+`
+
+const experimentsJSON = `{"experiments": [{"id": "exp_001", "name": "Checkout"}]}`
+
+func TestFetcherConditionalRequests(t *testing.T) {
+	var downloads atomic.Int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/parameters", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" || r.Header.Get("X-Team") != "checkout" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		downloads.Add(1)
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Content-Type", "application/yaml")
+		w.Write([]byte(parametersYAML))
+	})
+	mux.HandleFunc("/experiments", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-Modified-Since") == "Mon, 02 Jan 2006 15:04:05 GMT" {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		downloads.Add(1)
+		w.Header().Set("Last-Modified", "Mon, 02 Jan 2006 15:04:05 GMT")
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Write([]byte(experimentsJSON))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	f := NewFetcher(Options{
+		URL:            server.URL + "/parameters",
+		ExperimentsURL: server.URL + "/experiments",
+		Header:         http.Header{"X-Team": []string{"checkout"}},
+		Auth: func(req *http.Request) error {
+			req.Header.Set("Authorization", "Bearer token")
+			return nil
+		},
+	})
+
+	ctx := context.Background()
+	config, err := f.Fetch(ctx)
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	if got := config["maxConnections"].DefaultValue; got != 10 {
+		t.Errorf("maxConnections = %v, want 10", got)
+	}
+
+	experiments, err := f.FetchExperiments(ctx)
+	if err != nil {
+		t.Fatalf("FetchExperiments: %v", err)
+	}
+	if len(experiments) != 1 || experiments[0].ID != "exp_001" {
+		t.Errorf("experiments = %+v, want exp_001", experiments)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := f.Fetch(ctx); !errors.Is(err, auroratype.ErrNotModified) {
+			t.Errorf("Fetch: got %v, want ErrNotModified", err)
+		}
+		if _, err := f.FetchExperiments(ctx); !errors.Is(err, auroratype.ErrNotModified) {
+			t.Errorf("FetchExperiments: got %v, want ErrNotModified", err)
+		}
+	}
+
+	if got := downloads.Load(); got != 2 {
+		t.Errorf("downloads = %d, want 2", got)
+	}
+}
+
+func TestFetcherUnexpectedStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	f := NewFetcher(Options{URL: server.URL + "/parameters.json"})
+	if _, err := f.Fetch(context.Background()); err == nil {
+		t.Error("expected an error for a 500 response")
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		contentType string
+		url         string
+		want        string
+	}{
+		{"application/json", "https://example.com/config", "json"},
+		{"application/vnd.aurora+json", "https://example.com/config", "json"},
+		{"application/x-yaml", "https://example.com/config.json", "yaml"},
+		{"", "https://example.com/config.json?v=1", "json"},
+		{"text/plain", "https://example.com/config.yaml", "yaml"},
+		{"", "https://example.com/config", "yaml"},
+		{"application/gzip", "https://example.com/config.json.gz", "json"},
+		{"application/toml", "https://example.com/config", "toml"},
+		{"", "https://example.com/config.toml", "toml"},
+	}
+
+	for _, tt := range tests {
+		if got := format(tt.contentType, tt.url, nil); got != tt.want {
+			t.Errorf("format(%q, %q) = %q, want %q", tt.contentType, tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestFetcherDecompresses(t *testing.T) {
+	snapshot, err := auroratype.MarshalBinarySnapshot(
+		map[string]auroratype.Parameter{"maxConnections": {DefaultValue: 10}},
+		[]auroratype.Experiment{{ID: "exp_001"}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/parameters", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept-Encoding") != "gzip, zstd" {
+			t.Errorf("Accept-Encoding = %q", r.Header.Get("Accept-Encoding"))
+		}
+		data, _ := auroratype.Compress([]byte(parametersYAML), auroratype.EncodingGzip)
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Write(data)
+	})
+	mux.HandleFunc("/snapshot.aurora.zst", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"s1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		data, _ := auroratype.Compress(snapshot, auroratype.EncodingZstd)
+		w.Header().Set("ETag", `"s1"`)
+		w.Write(data)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	ctx := context.Background()
+
+	f := NewFetcher(Options{URL: server.URL + "/parameters"})
+	config, err := f.Fetch(ctx)
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	if got := config["maxConnections"].DefaultValue; got != 10 {
+		t.Errorf("maxConnections = %v, want 10", got)
+	}
+	payload, err := f.FetchRaw(ctx)
+	if err != nil {
+		t.Fatalf("FetchRaw: %v", err)
+	}
+	if string(payload.Data) != parametersYAML || payload.Encoding != "gzip" {
+		t.Errorf("payload = %q with encoding %q", payload.Data, payload.Encoding)
+	}
+
+	f = NewFetcher(Options{URL: server.URL + "/snapshot.aurora.zst"})
+	config, err = f.Fetch(ctx)
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	if got := config["maxConnections"].DefaultValue; got != 10 {
+		t.Errorf("maxConnections = %v, want 10", got)
+	}
+	experiments, err := f.FetchExperiments(ctx)
+	if err != nil || len(experiments) != 1 || experiments[0].ID != "exp_001" {
+		t.Errorf("FetchExperiments = %+v, %v", experiments, err)
+	}
+
+	if _, err := f.Fetch(ctx); !errors.Is(err, auroratype.ErrNotModified) {
+		t.Errorf("Fetch: got %v, want ErrNotModified", err)
+	}
+	if experiments, err := f.FetchExperiments(ctx); err != nil || experiments != nil {
+		t.Errorf("FetchExperiments: got %v, %v, want nil for unchanged", experiments, err)
+	}
+}
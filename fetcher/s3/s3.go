@@ -2,16 +2,15 @@ package s3
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"io"
-	"path/filepath"
-	"strings"
+	"net/http"
+	"sync"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/tuannguyensn2001/aurora-go/auroratype"
-	"gopkg.in/yaml.v2"
 )
 
 const (
@@ -26,21 +25,66 @@ type MetricsRecorder interface {
 	Histogram(metricName string, value float64, tags []string)
 }
 
-// Fetcher fetches configuration from an S3 bucket.
+// Client is the subset of *s3.Client used by the Fetcher. It can be replaced
+// by a stub in tests.
+type Client interface {
+	GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+}
+
+// Fetcher fetches configuration from an S3 bucket. Objects are requested
+// with If-None-Match using the ETag of the previous download, and an
+// unchanged object is reported as auroratype.ErrNotModified.
+//
+// Objects stored with a gzip or zstd Content-Encoding, or with a .gz or .zst
+// key, are decompressed. The parameters object may be a binary snapshot,
+// which holds the experiments as well unless ExperimentsKey is set. Both
+// objects are decoded with the codec for their Content-Type or key
+// extension, see auroratype.FormatOf.
 type Fetcher struct {
-	client         *s3.Client
+	client         Client
 	bucket         string
 	key            string
 	experimentsKey string
+	environment    string
 	recorder       MetricsRecorder
+
+	parameters  objectState
+	experiments objectState
+
+	// snapshot holds the experiments of a binary snapshot downloaded by
+	// Fetch until FetchExperiments returns them.
+	snapshotMu sync.Mutex
+	snapshot   []auroratype.Experiment
+
+	// base holds the parameters and experiments last decoded, before the
+	// environment was resolved. See ResolvedEnvironments.
+	baseMu          sync.Mutex
+	baseParameters  map[string]auroratype.Parameter
+	baseExperiments []auroratype.Experiment
+
+	// stored remembers the content type and encoding of the objects read
+	// by Load, so that writes keep them.
+	storedMu sync.Mutex
+	stored   map[string]object
+}
+
+// objectState remembers the version of the last object downloaded for a key.
+type objectState struct {
+	mu           sync.Mutex
+	etag         string
+	lastModified time.Time
 }
 
 // Options configures the S3 Fetcher.
 type Options struct {
-	Client          *s3.Client
-	Bucket          string
-	Key             string
-	ExperimentsKey  string
+	Client         Client
+	Bucket         string
+	Key            string
+	ExperimentsKey string
+	// Environment applies the environment sections of parameters and
+	// experiments, e.g. "prod". Without it, sections are left for the
+	// client to resolve.
+	Environment     string
 	MetricsRecorder MetricsRecorder
 }
 
@@ -55,6 +99,7 @@ func NewFetcher(opts Options) *Fetcher {
 		bucket:         opts.Bucket,
 		key:            opts.Key,
 		experimentsKey: opts.ExperimentsKey,
+		environment:    opts.Environment,
 		recorder:       recorder,
 	}
 }
@@ -72,38 +117,46 @@ func (f *Fetcher) Fetch(ctx context.Context) (map[string]auroratype.Parameter, e
 		f.recorder.Histogram(MetricS3FetchLatency, duration, []string{"unit:microseconds"})
 	}()
 
-	output, err := f.client.GetObject(ctx, &s3.GetObjectInput{
-		Bucket: aws.String(f.bucket),
-		Key:    aws.String(f.key),
-	})
-	if err != nil {
-		f.recorder.Count(MetricS3FetchTotal, 1, []string{"status:error"})
+	object, err := f.getObject(ctx, f.key, &f.parameters)
+	if errors.Is(err, auroratype.ErrNotModified) {
+		f.recorder.Count(MetricS3FetchTotal, 1, []string{"status:not_modified"})
 		return nil, err
 	}
-
-	data, err := io.ReadAll(output.Body)
-	output.Body.Close()
-
 	if err != nil {
 		f.recorder.Count(MetricS3FetchTotal, 1, []string{"status:error"})
 		return nil, err
 	}
 
 	var config map[string]auroratype.Parameter
-	ext := strings.ToLower(filepath.Ext(f.key))
-	if ext == ".yaml" || ext == ".yml" {
-		err = yaml.Unmarshal(data, &config)
-	} else {
-		err = json.Unmarshal(data, &config)
+	data, err := auroratype.Decompress(object.data, object.encoding)
+	if err == nil {
+		if format := object.format(f.key, data); format == auroratype.FormatBinary {
+			var experiments []auroratype.Experiment
+			config, experiments, err = auroratype.UnmarshalBinarySnapshot(data)
+			if err == nil && f.experimentsKey == "" {
+				if experiments == nil {
+					experiments = []auroratype.Experiment{}
+				}
+				f.setSnapshot(experiments)
+			}
+		} else {
+			config, err = auroratype.DecodeParameters(data, format)
+		}
 	}
-
 	if err != nil {
+		f.parameters.reset()
 		f.recorder.Count(MetricS3FetchTotal, 1, []string{"status:error"})
 		return nil, err
 	}
 
 	f.recorder.Count(MetricS3FetchTotal, 1, []string{"status:success"})
-	return config, nil
+	if f.environment == "" {
+		return config, nil
+	}
+	f.baseMu.Lock()
+	f.baseParameters = config
+	f.baseMu.Unlock()
+	return auroratype.ResolveParameters(config, f.environment), nil
 }
 
 func (f *Fetcher) IsStatic() bool {
@@ -112,7 +165,8 @@ func (f *Fetcher) IsStatic() bool {
 
 func (f *Fetcher) FetchExperiments(ctx context.Context) ([]auroratype.Experiment, error) {
 	if f.experimentsKey == "" {
-		return nil, nil
+		// nil, meaning unchanged, unless Fetch downloaded a new snapshot
+		return f.resolveExperiments(f.takeSnapshot()), nil
 	}
 
 	start := time.Now()
@@ -121,32 +175,191 @@ func (f *Fetcher) FetchExperiments(ctx context.Context) ([]auroratype.Experiment
 		f.recorder.Histogram(MetricS3FetchExperimentsLatency, duration, []string{"unit:microseconds"})
 	}()
 
-	output, err := f.client.GetObject(ctx, &s3.GetObjectInput{
-		Bucket: aws.String(f.bucket),
-		Key:    aws.String(f.experimentsKey),
-	})
+	object, err := f.getObject(ctx, f.experimentsKey, &f.experiments)
+	if errors.Is(err, auroratype.ErrNotModified) {
+		f.recorder.Count(MetricS3FetchExperimentsTotal, 1, []string{"status:not_modified"})
+		return nil, err
+	}
 	if err != nil {
 		f.recorder.Count(MetricS3FetchExperimentsTotal, 1, []string{"status:error"})
 		return nil, err
 	}
 
-	data, err := io.ReadAll(output.Body)
-	output.Body.Close()
-
+	var experiments []auroratype.Experiment
+	data, err := auroratype.Decompress(object.data, object.encoding)
+	if err == nil {
+		experiments, err = auroratype.DecodeExperiments(data, object.format(f.experimentsKey, data))
+	}
 	if err != nil {
+		f.experiments.reset()
 		f.recorder.Count(MetricS3FetchExperimentsTotal, 1, []string{"status:error"})
 		return nil, err
 	}
 
-	var config struct {
-		Experiments []auroratype.Experiment `yaml:"experiments"`
+	f.recorder.Count(MetricS3FetchExperimentsTotal, 1, []string{"status:success"})
+	return f.resolveExperiments(experiments), nil
+}
+
+// resolveExperiments resolves downloaded experiments for the environment,
+// keeping them for ResolvedEnvironments. Nil stays nil, meaning unchanged.
+func (f *Fetcher) resolveExperiments(experiments []auroratype.Experiment) []auroratype.Experiment {
+	if f.environment == "" || experiments == nil {
+		return experiments
 	}
+	f.baseMu.Lock()
+	f.baseExperiments = experiments
+	f.baseMu.Unlock()
+	return auroratype.ResolveExperiments(experiments, f.environment)
+}
 
-	if err := yaml.Unmarshal(data, &config); err != nil {
-		f.recorder.Count(MetricS3FetchExperimentsTotal, 1, []string{"status:error"})
+// ResolvedEnvironments returns the objects last downloaded resolved for
+// every environment with a section. See auroratype.EnvironmentResolver.
+func (f *Fetcher) ResolvedEnvironments() map[string]auroratype.EnvironmentContent {
+	if f.environment == "" {
+		return nil
+	}
+	f.baseMu.Lock()
+	defer f.baseMu.Unlock()
+	return auroratype.ResolveEnvironments(f.baseParameters, f.baseExperiments, nil, nil)
+}
+
+func (f *Fetcher) setSnapshot(experiments []auroratype.Experiment) {
+	f.snapshotMu.Lock()
+	defer f.snapshotMu.Unlock()
+	f.snapshot = experiments
+}
+
+func (f *Fetcher) takeSnapshot() []auroratype.Experiment {
+	f.snapshotMu.Lock()
+	defer f.snapshotMu.Unlock()
+	experiments := f.snapshot
+	f.snapshot = nil
+	return experiments
+}
+
+// object is a downloaded object, still compressed with encoding.
+type object struct {
+	data        []byte
+	encoding    string
+	contentType string
+}
+
+// format returns the format of the object at key, given its decompressed
+// content. See auroratype.FormatOf.
+func (o object) format(key string, content []byte) string {
+	return auroratype.FormatOf(key, o.contentType, content)
+}
+
+// getObject downloads key unless it still has the version recorded in
+// state, in which case it returns auroratype.ErrNotModified.
+func (f *Fetcher) getObject(ctx context.Context, key string, state *objectState) (object, error) {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	input := &s3.GetObjectInput{
+		Bucket: aws.String(f.bucket),
+		Key:    aws.String(key),
+	}
+	if state.etag != "" {
+		input.IfNoneMatch = aws.String(state.etag)
+	} else if !state.lastModified.IsZero() {
+		input.IfModifiedSince = aws.Time(state.lastModified)
+	}
+
+	output, err := f.client.GetObject(ctx, input)
+	if err != nil {
+		var respErr interface{ HTTPStatusCode() int }
+		if errors.As(err, &respErr) && respErr.HTTPStatusCode() == http.StatusNotModified {
+			return object{}, auroratype.ErrNotModified
+		}
+		return object{}, err
+	}
+
+	data, err := io.ReadAll(output.Body)
+	output.Body.Close()
+	if err != nil {
+		return object{}, err
+	}
+
+	// the Content-Encoding metadata takes precedence over the key
+	_, encoding := auroratype.SplitCompression(key)
+	if contentEncoding := aws.ToString(output.ContentEncoding); contentEncoding != "" {
+		encoding = contentEncoding
+	}
+
+	state.etag = aws.ToString(output.ETag)
+	state.lastModified = aws.ToTime(output.LastModified)
+	return object{data: data, encoding: encoding, contentType: aws.ToString(output.ContentType)}, nil
+}
+
+// reset forgets the recorded version so that an object that failed to parse
+// is downloaded again instead of being reported as not modified.
+func (s *objectState) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.etag = ""
+	s.lastModified = time.Time{}
+}
+
+// FetchRaw downloads the parameters object without decoding it. Objects are
+// requested conditionally, as in Fetch, and environment sections are left in
+// place.
+func (f *Fetcher) FetchRaw(ctx context.Context) (auroratype.Payload, error) {
+	return f.fetchRaw(ctx, f.key, &f.parameters, MetricS3FetchLatency, MetricS3FetchTotal)
+}
+
+// FetchExperimentsRaw downloads the experiments object without decoding it.
+func (f *Fetcher) FetchExperimentsRaw(ctx context.Context) (auroratype.Payload, error) {
+	if f.experimentsKey == "" {
+		return auroratype.Payload{}, nil
+	}
+	return f.fetchRaw(ctx, f.experimentsKey, &f.experiments, MetricS3FetchExperimentsLatency, MetricS3FetchExperimentsTotal)
+}
+
+// ReadObject downloads the object at key from the bucket.
+func (f *Fetcher) ReadObject(ctx context.Context, key string) ([]byte, error) {
+	output, err := f.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(f.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
 		return nil, err
 	}
+	defer output.Body.Close()
+	data, err := io.ReadAll(output.Body)
+	if err != nil {
+		return nil, err
+	}
+	_, encoding := auroratype.SplitCompression(key)
+	if contentEncoding := aws.ToString(output.ContentEncoding); contentEncoding != "" {
+		encoding = contentEncoding
+	}
+	return auroratype.Decompress(data, encoding)
+}
 
-	f.recorder.Count(MetricS3FetchExperimentsTotal, 1, []string{"status:success"})
-	return config.Experiments, nil
+func (f *Fetcher) fetchRaw(ctx context.Context, key string, state *objectState, latencyMetric, totalMetric string) (auroratype.Payload, error) {
+	start := time.Now()
+	defer func() {
+		duration := float64(time.Since(start).Microseconds())
+		f.recorder.Histogram(latencyMetric, duration, []string{"unit:microseconds"})
+	}()
+
+	object, err := f.getObject(ctx, key, state)
+	if errors.Is(err, auroratype.ErrNotModified) {
+		f.recorder.Count(totalMetric, 1, []string{"status:not_modified"})
+		return auroratype.Payload{}, err
+	}
+	if err != nil {
+		f.recorder.Count(totalMetric, 1, []string{"status:error"})
+		return auroratype.Payload{}, err
+	}
+
+	data, err := auroratype.Decompress(object.data, object.encoding)
+	if err != nil {
+		state.reset()
+		f.recorder.Count(totalMetric, 1, []string{"status:error"})
+		return auroratype.Payload{}, err
+	}
+	f.recorder.Count(totalMetric, 1, []string{"status:success"})
+	return auroratype.Payload{Name: key, Data: data, Format: object.format(key, data), Encoding: object.encoding}, nil
 }
@@ -0,0 +1,249 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/tuannguyensn2001/aurora-go/auroratype"
+)
+
+type stubClient struct {
+	objects      map[string]string
+	etags        map[string]string
+	encodings    map[string]string
+	contentTypes map[string]string
+	downloads    int
+}
+
+func responseError(statusCode int, message string) error {
+	return &smithyhttp.ResponseError{
+		Response: &smithyhttp.Response{Response: &http.Response{StatusCode: statusCode}},
+		Err:      errors.New(message),
+	}
+}
+
+func (c *stubClient) GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	key := aws.ToString(params.Key)
+	if _, ok := c.objects[key]; !ok {
+		return nil, responseError(http.StatusNotFound, "NoSuchKey")
+	}
+	etag := c.etags[key]
+	if aws.ToString(params.IfNoneMatch) == etag {
+		return nil, responseError(http.StatusNotModified, "NotModified")
+	}
+
+	c.downloads++
+	return &s3.GetObjectOutput{
+		Body: io.NopCloser(strings.NewReader(c.objects[key])),
+		ETag: aws.String(etag),
+		// nil when unset, as for objects stored without one
+		ContentEncoding: optional(c.encodings[key]),
+		ContentType:     optional(c.contentTypes[key]),
+	}, nil
+}
+
+func TestFetcherConditionalGet(t *testing.T) {
+	client := &stubClient{
+		objects: map[string]string{
+			"parameters.yaml":  "maxConnections:\n  defaultValue: 10\n",
+			"experiments.yaml": "experiments:\n  - id: exp_001\n",
+		},
+		etags: map[string]string{"parameters.yaml": `"p1"`, "experiments.yaml": `"e1"`},
+	}
+	f := NewFetcher(Options{Client: client, Bucket: "config", Key: "parameters.yaml", ExperimentsKey: "experiments.yaml"})
+	ctx := context.Background()
+
+	config, err := f.Fetch(ctx)
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	if got := config["maxConnections"].DefaultValue; got != 10 {
+		t.Errorf("maxConnections = %v, want 10", got)
+	}
+	if _, err := f.FetchExperiments(ctx); err != nil {
+		t.Fatalf("FetchExperiments: %v", err)
+	}
+
+	if _, err := f.Fetch(ctx); !errors.Is(err, auroratype.ErrNotModified) {
+		t.Errorf("Fetch: got %v, want ErrNotModified", err)
+	}
+	if _, err := f.FetchExperiments(ctx); !errors.Is(err, auroratype.ErrNotModified) {
+		t.Errorf("FetchExperiments: got %v, want ErrNotModified", err)
+	}
+
+	client.objects["parameters.yaml"] = "maxConnections:\n  defaultValue: 20\n"
+	client.etags["parameters.yaml"] = `"p2"`
+
+	config, err = f.Fetch(ctx)
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	if got := config["maxConnections"].DefaultValue; got != 20 {
+		t.Errorf("maxConnections = %v, want 20", got)
+	}
+	if client.downloads != 3 {
+		t.Errorf("downloads = %d, want 3", client.downloads)
+	}
+}
+
+func TestFetcherEnvironment(t *testing.T) {
+	client := &stubClient{
+		objects: map[string]string{
+			"parameters.yaml":  "maxConnections:\n  defaultValue: 10\n  environments:\n    prod:\n      defaultValue: 100\n",
+			"experiments.yaml": "experiments:\n  - id: exp_001\n    environments:\n      prod:\n        disabled: true\n",
+		},
+		etags: map[string]string{"parameters.yaml": `"p1"`, "experiments.yaml": `"e1"`},
+	}
+	f := NewFetcher(Options{Client: client, Bucket: "config", Key: "parameters.yaml", ExperimentsKey: "experiments.yaml", Environment: "prod"})
+	ctx := context.Background()
+
+	config, err := f.Fetch(ctx)
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	if got := config["maxConnections"].DefaultValue; got != 100 {
+		t.Errorf("maxConnections = %v, want 100", got)
+	}
+	experiments, err := f.FetchExperiments(ctx)
+	if err != nil {
+		t.Fatalf("FetchExperiments: %v", err)
+	}
+	if len(experiments) != 0 {
+		t.Errorf("experiments = %v, want exp_001 disabled", experiments)
+	}
+
+	resolved := f.ResolvedEnvironments()
+	if got := resolved["prod"]; got.Parameters["maxConnections"].DefaultValue != 100 || len(got.Experiments) != 0 {
+		t.Errorf("resolved prod = %+v", got)
+	}
+
+	// without an environment the sections are left for the client
+	f = NewFetcher(Options{Client: client, Bucket: "config", Key: "parameters.yaml", ExperimentsKey: "experiments.yaml"})
+	config, err = f.Fetch(ctx)
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	if got := config["maxConnections"].Environments; got["prod"].DefaultValue != 100 {
+		t.Errorf("environments = %v, want the prod section kept", got)
+	}
+	if got := f.ResolvedEnvironments(); got != nil {
+		t.Errorf("resolved = %v, want none", got)
+	}
+}
+
+func optional(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return aws.String(s)
+}
+
+func compress(t *testing.T, data []byte, encoding string) string {
+	t.Helper()
+	compressed, err := auroratype.Compress(data, encoding)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(compressed)
+}
+
+func TestFetcherDecompresses(t *testing.T) {
+	client := &stubClient{
+		objects: map[string]string{
+			"parameters.json":     compress(t, []byte(`{"maxConnections": {"defaultValue": 10}}`), auroratype.EncodingGzip),
+			"experiments.yaml.gz": compress(t, []byte("experiments:\n  - id: exp_001\n"), auroratype.EncodingGzip),
+		},
+		etags:     map[string]string{"parameters.json": `"p1"`, "experiments.yaml.gz": `"e1"`},
+		encodings: map[string]string{"parameters.json": "gzip"},
+	}
+	f := NewFetcher(Options{Client: client, Bucket: "config", Key: "parameters.json", ExperimentsKey: "experiments.yaml.gz"})
+	ctx := context.Background()
+
+	config, err := f.Fetch(ctx)
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	if got := config["maxConnections"].DefaultValue; got != float64(10) {
+		t.Errorf("maxConnections = %v, want 10", got)
+	}
+	experiments, err := f.FetchExperiments(ctx)
+	if err != nil {
+		t.Fatalf("FetchExperiments: %v", err)
+	}
+	if len(experiments) != 1 || experiments[0].ID != "exp_001" {
+		t.Errorf("experiments = %+v", experiments)
+	}
+}
+
+func TestFetcherBinarySnapshot(t *testing.T) {
+	snapshot, err := auroratype.MarshalBinarySnapshot(
+		map[string]auroratype.Parameter{"maxConnections": {DefaultValue: 10}},
+		[]auroratype.Experiment{{ID: "exp_001"}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &stubClient{
+		objects: map[string]string{"snapshot.aurora.zst": compress(t, snapshot, auroratype.EncodingZstd)},
+		etags:   map[string]string{"snapshot.aurora.zst": `"s1"`},
+	}
+	f := NewFetcher(Options{Client: client, Bucket: "config", Key: "snapshot.aurora.zst"})
+	ctx := context.Background()
+
+	config, err := f.Fetch(ctx)
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	if got := config["maxConnections"].DefaultValue; got != 10 {
+		t.Errorf("maxConnections = %v, want 10", got)
+	}
+	experiments, err := f.FetchExperiments(ctx)
+	if err != nil {
+		t.Fatalf("FetchExperiments: %v", err)
+	}
+	if len(experiments) != 1 || experiments[0].ID != "exp_001" {
+		t.Errorf("experiments = %+v", experiments)
+	}
+
+	if _, err := f.Fetch(ctx); !errors.Is(err, auroratype.ErrNotModified) {
+		t.Errorf("Fetch: got %v, want ErrNotModified", err)
+	}
+	if experiments, err := f.FetchExperiments(ctx); err != nil || experiments != nil {
+		t.Errorf("FetchExperiments: got %v, %v, want nil for unchanged", experiments, err)
+	}
+}
+
+func TestFetcherDecodesFormats(t *testing.T) {
+	client := &stubClient{
+		objects: map[string]string{
+			"config/parameters": "[maxConnections]\ndefaultValue = 10\n",
+			"experiments.json":  `{"experiments": [{"id": "exp_001"}]}`,
+		},
+		etags:        map[string]string{"config/parameters": `"p1"`, "experiments.json": `"e1"`},
+		contentTypes: map[string]string{"config/parameters": "application/toml"},
+	}
+	f := NewFetcher(Options{Client: client, Bucket: "config", Key: "config/parameters", ExperimentsKey: "experiments.json"})
+	ctx := context.Background()
+
+	config, err := f.Fetch(ctx)
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	if got := config["maxConnections"].DefaultValue; got != 10 {
+		t.Errorf("maxConnections = %v, want 10", got)
+	}
+	experiments, err := f.FetchExperiments(ctx)
+	if err != nil {
+		t.Fatalf("FetchExperiments: %v", err)
+	}
+	if len(experiments) != 1 || experiments[0].ID != "exp_001" {
+		t.Errorf("experiments = %+v", experiments)
+	}
+}
@@ -0,0 +1,184 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/tuannguyensn2001/aurora-go/auroratype"
+)
+
+// WriteClient is implemented by clients that can also upload objects, such
+// as *s3.Client. The Fetcher is a writable source when its Client is one.
+type WriteClient interface {
+	Client
+	PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+}
+
+// Load downloads the parameters and experiments objects as stored, without
+// applying environment sections. Revisions are the objects' ETags, and
+// objects keep their content type and encoding when written back.
+func (f *Fetcher) Load(ctx context.Context) (auroratype.SourceContent, error) {
+	if _, err := f.writeClient(); err != nil {
+		return auroratype.SourceContent{}, err
+	}
+
+	var content auroratype.SourceContent
+	data, format, etag, err := f.loadObject(ctx, f.key)
+	if err != nil {
+		return auroratype.SourceContent{}, err
+	}
+	if format == auroratype.FormatBinary {
+		return auroratype.SourceContent{}, errors.New("s3: binary snapshots are read-only")
+	}
+	if content.Parameters, err = auroratype.DecodeParameters(data, format); err != nil {
+		return auroratype.SourceContent{}, fmt.Errorf("%s: %w", f.key, err)
+	}
+	content.ParametersRevision = etag
+
+	if f.experimentsKey == "" {
+		return content, nil
+	}
+	data, format, etag, err = f.loadObject(ctx, f.experimentsKey)
+	if err != nil {
+		return auroratype.SourceContent{}, err
+	}
+	if content.Experiments, err = auroratype.DecodeExperiments(data, format); err != nil {
+		return auroratype.SourceContent{}, fmt.Errorf("%s: %w", f.experimentsKey, err)
+	}
+	content.ExperimentsRevision = etag
+	return content, nil
+}
+
+// StoreParameters uploads the parameters object with If-Match, or with
+// If-None-Match when revision is empty, so that concurrent writers cannot
+// overwrite each other's changes.
+func (f *Fetcher) StoreParameters(ctx context.Context, parameters map[string]auroratype.Parameter, revision string) (string, error) {
+	return f.putObject(ctx, f.key, revision, func(format string) ([]byte, error) {
+		return auroratype.Encode(parameters, format)
+	})
+}
+
+// StoreExperiments uploads the experiments object like StoreParameters. It
+// requires an ExperimentsKey.
+func (f *Fetcher) StoreExperiments(ctx context.Context, experiments []auroratype.Experiment, revision string) (string, error) {
+	if f.experimentsKey == "" {
+		return "", errors.New("s3: no experiments key to write")
+	}
+	return f.putObject(ctx, f.experimentsKey, revision, func(format string) ([]byte, error) {
+		return auroratype.EncodeExperiments(experiments, format)
+	})
+}
+
+func (f *Fetcher) writeClient() (WriteClient, error) {
+	client, ok := f.client.(WriteClient)
+	if !ok {
+		return nil, errors.New("s3: the client cannot upload objects")
+	}
+	return client, nil
+}
+
+// loadObject downloads key unconditionally. A missing object has no
+// content and an empty ETag.
+func (f *Fetcher) loadObject(ctx context.Context, key string) ([]byte, string, string, error) {
+	output, err := f.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(f.bucket),
+		Key:    aws.String(key),
+	})
+	if statusCode(err) == http.StatusNotFound {
+		return nil, auroratype.FormatOf(key, "", nil), "", nil
+	}
+	if err != nil {
+		return nil, "", "", err
+	}
+	defer output.Body.Close()
+	stored, err := io.ReadAll(output.Body)
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	o := object{data: stored, contentType: aws.ToString(output.ContentType)}
+	_, o.encoding = auroratype.SplitCompression(key)
+	if contentEncoding := aws.ToString(output.ContentEncoding); contentEncoding != "" {
+		o.encoding = contentEncoding
+	}
+	data, err := auroratype.Decompress(stored, o.encoding)
+	if err != nil {
+		return nil, "", "", fmt.Errorf("%s: %w", key, err)
+	}
+
+	f.storedMu.Lock()
+	defer f.storedMu.Unlock()
+	if f.stored == nil {
+		f.stored = make(map[string]object)
+	}
+	f.stored[key] = object{encoding: o.encoding, contentType: o.contentType}
+	return data, o.format(key, data), aws.ToString(output.ETag), nil
+}
+
+// putObject encodes and uploads key with the content type and encoding it
+// had when loaded, or else those of its extension, defaulting to YAML.
+func (f *Fetcher) putObject(ctx context.Context, key, revision string, encode func(format string) ([]byte, error)) (string, error) {
+	client, err := f.writeClient()
+	if err != nil {
+		return "", err
+	}
+
+	f.storedMu.Lock()
+	o, ok := f.stored[key]
+	f.storedMu.Unlock()
+	if !ok {
+		_, o.encoding = auroratype.SplitCompression(key)
+	}
+
+	format := o.format(key, nil)
+	data, err := encode(format)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", key, err)
+	}
+	if data, err = auroratype.Compress(data, o.encoding); err != nil {
+		return "", err
+	}
+
+	input := &s3.PutObjectInput{
+		Bucket: aws.String(f.bucket),
+		Key:    aws.String(key),
+		Body:   bytes.NewReader(data),
+	}
+	if o.contentType != "" {
+		input.ContentType = aws.String(o.contentType)
+	} else if codec, ok := auroratype.CodecByName(format); ok && len(codec.MediaTypes) > 0 {
+		input.ContentType = aws.String(codec.MediaTypes[0])
+	}
+	if o.encoding != "" {
+		input.ContentEncoding = aws.String(o.encoding)
+	}
+	if revision != "" {
+		input.IfMatch = aws.String(revision)
+	} else {
+		input.IfNoneMatch = aws.String("*")
+	}
+
+	output, err := client.PutObject(ctx, input)
+	if code := statusCode(err); code == http.StatusPreconditionFailed || code == http.StatusConflict {
+		return "", fmt.Errorf("%s: %w", key, auroratype.ErrConflict)
+	}
+	if err != nil {
+		return "", err
+	}
+	return aws.ToString(output.ETag), nil
+}
+
+// statusCode returns the HTTP status code of an S3 error, or 0.
+func statusCode(err error) int {
+	var respErr interface{ HTTPStatusCode() int }
+	if errors.As(err, &respErr) {
+		return respErr.HTTPStatusCode()
+	}
+	return 0
+}
@@ -0,0 +1,106 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/tuannguyensn2001/aurora-go/auroratype"
+)
+
+// writeStub is a stubClient that accepts conditional uploads.
+type writeStub struct {
+	stubClient
+	uploads []*s3.PutObjectInput
+}
+
+func (c *writeStub) PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	key := aws.ToString(params.Key)
+	_, exists := c.objects[key]
+	if params.IfMatch != nil && (!exists || aws.ToString(params.IfMatch) != c.etags[key]) ||
+		params.IfNoneMatch != nil && exists {
+		return nil, responseError(http.StatusPreconditionFailed, "PreconditionFailed")
+	}
+
+	data, err := io.ReadAll(params.Body)
+	if err != nil {
+		return nil, err
+	}
+	c.uploads = append(c.uploads, params)
+	c.objects[key] = string(data)
+	c.etags[key] = fmt.Sprintf(`"v%d"`, len(c.uploads))
+	c.encodings[key] = aws.ToString(params.ContentEncoding)
+	c.contentTypes[key] = aws.ToString(params.ContentType)
+	return &s3.PutObjectOutput{ETag: aws.String(c.etags[key])}, nil
+}
+
+func TestStore(t *testing.T) {
+	client := &writeStub{stubClient: stubClient{
+		objects:      map[string]string{"parameters": compress(t, []byte(`{"checkout": {"defaultValue": 10}}`), auroratype.EncodingGzip)},
+		etags:        map[string]string{"parameters": `"p1"`},
+		encodings:    map[string]string{"parameters": "gzip"},
+		contentTypes: map[string]string{"parameters": "application/json"},
+	}}
+	f := NewFetcher(Options{Client: client, Bucket: "config", Key: "parameters", ExperimentsKey: "experiments.yaml"})
+	ctx := context.Background()
+
+	content, err := f.Load(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content.Parameters["checkout"].DefaultValue != float64(10) || content.ParametersRevision != `"p1"` || content.ExperimentsRevision != "" {
+		t.Fatalf("content = %+v", content)
+	}
+
+	content.Parameters["killSwitch"] = auroratype.Parameter{DefaultValue: true}
+	revision, err := f.StoreParameters(ctx, content.Parameters, content.ParametersRevision)
+	if err != nil {
+		t.Fatal(err)
+	}
+	upload := client.uploads[0]
+	if aws.ToString(upload.IfMatch) != `"p1"` || aws.ToString(upload.ContentType) != "application/json" || aws.ToString(upload.ContentEncoding) != "gzip" {
+		t.Errorf("upload = %+v, want If-Match and the stored content type and encoding", upload)
+	}
+
+	config, err := f.Fetch(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config["killSwitch"].DefaultValue != true {
+		t.Errorf("config = %+v", config)
+	}
+
+	if _, err := f.StoreParameters(ctx, content.Parameters, content.ParametersRevision); !errors.Is(err, auroratype.ErrConflict) {
+		t.Errorf("stale revision: err = %v, want ErrConflict", err)
+	}
+	if _, err := f.StoreParameters(ctx, content.Parameters, revision); err != nil {
+		t.Errorf("current revision: %v", err)
+	}
+
+	experiments := []auroratype.Experiment{{ID: "exp-1", Status: auroratype.StatusAborted}}
+	if _, err := f.StoreExperiments(ctx, experiments, ""); err != nil {
+		t.Fatal(err)
+	}
+	if upload := client.uploads[len(client.uploads)-1]; aws.ToString(upload.IfNoneMatch) != "*" {
+		t.Errorf("new object: IfNoneMatch = %v, want *", upload.IfNoneMatch)
+	}
+	if _, err := f.StoreExperiments(ctx, experiments, ""); !errors.Is(err, auroratype.ErrConflict) {
+		t.Errorf("existing object without revision: err = %v, want ErrConflict", err)
+	}
+	fetched, err := f.FetchExperiments(ctx)
+	if err != nil || len(fetched) != 1 || fetched[0].Status != auroratype.StatusAborted {
+		t.Errorf("FetchExperiments = %+v, %v", fetched, err)
+	}
+}
+
+func TestStoreReadOnlyClient(t *testing.T) {
+	f := NewFetcher(Options{Client: &stubClient{}, Bucket: "config", Key: "parameters.yaml"})
+	if _, err := f.Load(context.Background()); err == nil {
+		t.Error("Load succeeded without PutObject")
+	}
+}
@@ -0,0 +1,346 @@
+package signed
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"github.com/tuannguyensn2001/aurora-go/auroratype"
+)
+
+const (
+	MetricSignatureVerificationTotal = "signature_verification_total"
+)
+
+var (
+	ErrUnsigned         = errors.New("signed: payload is not signed")
+	ErrInvalidSignature = errors.New("signed: no valid signature from a trusted key")
+)
+
+type MetricsRecorder interface {
+	Count(metricName string, count int, tags []string)
+	Histogram(metricName string, value float64, tags []string)
+}
+
+// RawFetcher is a fetcher that can return its payloads undecoded. The file,
+// S3 and HTTP fetchers implement it.
+type RawFetcher interface {
+	auroratype.RawFetcher
+	IsStatic() bool
+}
+
+// Mode selects where signatures are read from.
+type Mode int
+
+const (
+	// ModeSidecar reads signatures from a separate object named after the
+	// payload with SignatureSuffix appended, e.g. parameters.yaml.sig. The
+	// object holds one base64 signature per line.
+	ModeSidecar Mode = iota
+	// ModeEnvelope expects every payload to be a JSON Envelope.
+	ModeEnvelope
+)
+
+// Envelope carries a payload together with its signatures.
+type Envelope struct {
+	Payload []byte `json:"payload"`
+	// Format of Payload, the name of a registered codec such as "yaml" or
+	// "json". Defaults to "yaml". Binary snapshots are recognized by their
+	// content.
+	Format     string   `json:"format,omitempty"`
+	Signatures [][]byte `json:"signatures"`
+}
+
+// Options configures the signed Fetcher.
+type Options struct {
+	Fetcher RawFetcher
+	// TrustedKeys verify the signatures. A payload is accepted if any of
+	// its signatures verifies with any trusted key, so keys can be rotated
+	// by trusting the new key, re-signing, then removing the old key.
+	TrustedKeys []ed25519.PublicKey
+	Mode        Mode
+	// SignatureSuffix names sidecar signature objects. Defaults to ".sig".
+	SignatureSuffix string
+	// Logger defaults to slog.Default().
+	Logger          *slog.Logger
+	MetricsRecorder MetricsRecorder
+}
+
+// Fetcher verifies the Ed25519 signature of every payload of the wrapped
+// fetcher before decoding it. Unsigned or tampered payloads are rejected
+// with an error, so the storage keeps serving the last good snapshot, and
+// stay rejected while the source reports them as not modified. In sidecar
+// mode their signature is read again meanwhile, so a payload uploaded before
+// its signature is accepted once the signature arrives.
+type Fetcher struct {
+	fetcher  RawFetcher
+	keys     []ed25519.PublicKey
+	mode     Mode
+	suffix   string
+	logger   *slog.Logger
+	recorder MetricsRecorder
+
+	parameters  verification
+	experiments verification
+
+	// snapshot holds the experiments of a binary snapshot verified by Fetch
+	// until FetchExperiments returns them.
+	snapshotMu sync.Mutex
+	snapshot   []auroratype.Experiment
+}
+
+// verification remembers why the last payload of a kind was rejected.
+type verification struct {
+	mu  sync.Mutex
+	err error
+	// unsigned holds a payload rejected for its sidecar signature. Since
+	// the signature may be uploaded after the payload, it is verified again
+	// while the source reports the payload as not modified.
+	unsigned auroratype.Payload
+}
+
+// NewFetcher creates a Fetcher that verifies the payloads of opts.Fetcher.
+func NewFetcher(opts Options) *Fetcher {
+	suffix := opts.SignatureSuffix
+	if suffix == "" {
+		suffix = ".sig"
+	}
+	logger := opts.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	recorder := opts.MetricsRecorder
+	if recorder == nil {
+		recorder = &noopRecorder{}
+	}
+	return &Fetcher{
+		fetcher:  opts.Fetcher,
+		keys:     opts.TrustedKeys,
+		mode:     opts.Mode,
+		suffix:   suffix,
+		logger:   logger,
+		recorder: recorder,
+	}
+}
+
+type noopRecorder struct{}
+
+func (n *noopRecorder) Count(metricName string, count int, tags []string)         {}
+func (n *noopRecorder) Histogram(metricName string, value float64, tags []string) {}
+
+// Fetch verifies and decodes the parameters.
+func (f *Fetcher) Fetch(ctx context.Context) (map[string]auroratype.Parameter, error) {
+	payload, err := f.fetcher.FetchRaw(ctx)
+	payload, err = f.verified(ctx, "parameters", &f.parameters, payload, err)
+	if err != nil {
+		return nil, err
+	}
+
+	if payload.Data == nil {
+		return make(map[string]auroratype.Parameter), nil
+	}
+	format := formatOf(payload)
+	if format == auroratype.FormatBinary {
+		config, experiments, err := auroratype.UnmarshalBinarySnapshot(payload.Data)
+		if err != nil {
+			return nil, f.rejectContent(&f.parameters, payload, err)
+		}
+		if experiments == nil {
+			experiments = []auroratype.Experiment{}
+		}
+		f.setSnapshot(experiments)
+		return config, nil
+	}
+	config, err := auroratype.DecodeParameters(payload.Data, format)
+	if err != nil {
+		return nil, f.rejectContent(&f.parameters, payload, err)
+	}
+	if config == nil {
+		config = make(map[string]auroratype.Parameter)
+	}
+	return config, nil
+}
+
+// FetchExperiments verifies and decodes the experiments. Without an
+// experiments payload, the experiments of a binary snapshot verified by
+// Fetch are returned.
+func (f *Fetcher) FetchExperiments(ctx context.Context) ([]auroratype.Experiment, error) {
+	payload, err := f.fetcher.FetchExperimentsRaw(ctx)
+	payload, err = f.verified(ctx, "experiments", &f.experiments, payload, err)
+	if err != nil {
+		return nil, err
+	}
+	if payload.Data == nil {
+		return f.takeSnapshot(), nil
+	}
+	format := formatOf(payload)
+	experiments, err := auroratype.DecodeExperiments(payload.Data, format)
+	if err != nil {
+		return nil, f.rejectContent(&f.experiments, payload, err)
+	}
+	return experiments, nil
+}
+
+func (f *Fetcher) IsStatic() bool {
+	return f.fetcher.IsStatic()
+}
+
+func (f *Fetcher) setSnapshot(experiments []auroratype.Experiment) {
+	f.snapshotMu.Lock()
+	defer f.snapshotMu.Unlock()
+	f.snapshot = experiments
+}
+
+func (f *Fetcher) takeSnapshot() []auroratype.Experiment {
+	f.snapshotMu.Lock()
+	defer f.snapshotMu.Unlock()
+	experiments := f.snapshot
+	f.snapshot = nil
+	return experiments
+}
+
+// Watch forwards to the wrapped fetcher if it can watch its source, and
+// returns immediately otherwise.
+func (f *Fetcher) Watch(ctx context.Context, notify func()) error {
+	if w, ok := f.fetcher.(interface {
+		Watch(ctx context.Context, notify func()) error
+	}); ok {
+		return w.Watch(ctx, notify)
+	}
+	return nil
+}
+
+// verified returns the signed content of payload, recording the outcome in
+// state. err is the error of fetching payload.
+func (f *Fetcher) verified(ctx context.Context, kind string, state *verification, payload auroratype.Payload, err error) (auroratype.Payload, error) {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	if errors.Is(err, auroratype.ErrNotModified) && state.err != nil {
+		if state.unsigned.Data == nil {
+			return auroratype.Payload{}, state.err
+		}
+		payload, err = state.unsigned, nil
+	}
+	if err != nil {
+		return auroratype.Payload{}, err
+	}
+	state.unsigned = auroratype.Payload{}
+	if payload.Data == nil {
+		state.err = nil
+		return payload, nil
+	}
+
+	raw := payload
+	payload, err = f.verify(ctx, payload)
+	if err != nil {
+		if f.mode == ModeSidecar {
+			state.unsigned = raw
+		}
+		status := "invalid"
+		if errors.Is(err, ErrUnsigned) {
+			status = "unsigned"
+		}
+		f.logger.Error("Rejected unverified configuration, keeping previous configuration", "kind", kind, "error", err)
+		f.recorder.Count(MetricSignatureVerificationTotal, 1, []string{"kind:" + kind, "status:" + status})
+		state.err = err
+		return auroratype.Payload{}, err
+	}
+
+	f.recorder.Count(MetricSignatureVerificationTotal, 1, []string{"kind:" + kind, "status:valid"})
+	state.err = nil
+	return payload, nil
+}
+
+func (f *Fetcher) verify(ctx context.Context, payload auroratype.Payload) (auroratype.Payload, error) {
+	var signatures [][]byte
+	if f.mode == ModeEnvelope {
+		var envelope Envelope
+		if err := json.Unmarshal(payload.Data, &envelope); err != nil || envelope.Payload == nil {
+			return auroratype.Payload{}, fmt.Errorf("%w: %s is not an envelope", ErrUnsigned, payload.Name)
+		}
+		format := envelope.Format
+		if format == "" {
+			format = "yaml"
+		}
+		payload = auroratype.Payload{Name: payload.Name, Data: envelope.Payload, Format: format}
+		signatures = envelope.Signatures
+	} else {
+		sidecar, err := f.fetcher.ReadObject(ctx, payload.Name+f.suffix)
+		if err != nil {
+			return auroratype.Payload{}, fmt.Errorf("%w: reading signature of %s: %v", ErrUnsigned, payload.Name, err)
+		}
+		signatures, err = parseSignatures(sidecar)
+		if err != nil {
+			return auroratype.Payload{}, fmt.Errorf("%w: signature of %s: %v", ErrInvalidSignature, payload.Name, err)
+		}
+	}
+
+	if len(signatures) == 0 {
+		return auroratype.Payload{}, fmt.Errorf("%w: %s", ErrUnsigned, payload.Name)
+	}
+	for _, signature := range signatures {
+		for _, key := range f.keys {
+			if ed25519.Verify(key, payload.Data, signature) {
+				return payload, nil
+			}
+		}
+	}
+	return auroratype.Payload{}, fmt.Errorf("%w: %s", ErrInvalidSignature, payload.Name)
+}
+
+// formatOf returns the format of verified content. Binary snapshots are
+// recognized by their content, also inside envelopes.
+func formatOf(payload auroratype.Payload) string {
+	if auroratype.IsBinarySnapshot(payload.Data) || payload.Format == "" {
+		return auroratype.FormatOf(payload.Name, "", payload.Data)
+	}
+	return payload.Format
+}
+
+// rejectContent records that the content of payload could not be decoded.
+// It stays rejected while it is not modified, like an invalid signature.
+func (f *Fetcher) rejectContent(state *verification, payload auroratype.Payload, err error) error {
+	err = fmt.Errorf("%s: %w", payload.Name, err)
+	state.mu.Lock()
+	state.err = err
+	state.unsigned = auroratype.Payload{}
+	state.mu.Unlock()
+	return err
+}
+
+func parseSignatures(data []byte) ([][]byte, error) {
+	var signatures [][]byte
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		signature, err := base64.StdEncoding.DecodeString(string(line))
+		if err != nil {
+			return nil, err
+		}
+		signatures = append(signatures, signature)
+	}
+	return signatures, nil
+}
+
+// Sign returns the contents of a sidecar signature object for payload.
+func Sign(key ed25519.PrivateKey, payload []byte) []byte {
+	return []byte(base64.StdEncoding.EncodeToString(ed25519.Sign(key, payload)) + "\n")
+}
+
+// Seal returns payload wrapped in an Envelope signed by every key.
+func Seal(payload []byte, format string, keys ...ed25519.PrivateKey) ([]byte, error) {
+	envelope := Envelope{Payload: payload, Format: format}
+	for _, key := range keys {
+		envelope.Signatures = append(envelope.Signatures, ed25519.Sign(key, payload))
+	}
+	return json.Marshal(envelope)
+}
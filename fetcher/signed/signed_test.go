@@ -0,0 +1,185 @@
+package signed
+
+import (
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"io/fs"
+	"testing"
+
+	"github.com/tuannguyensn2001/aurora-go/auroratype"
+)
+
+type stubFetcher struct {
+	objects     map[string][]byte
+	notModified bool
+}
+
+func (s *stubFetcher) FetchRaw(ctx context.Context) (auroratype.Payload, error) {
+	if s.notModified {
+		return auroratype.Payload{}, auroratype.ErrNotModified
+	}
+	return auroratype.Payload{Name: "parameters.yaml", Data: s.objects["parameters.yaml"], Format: "yaml"}, nil
+}
+
+func (s *stubFetcher) FetchExperimentsRaw(ctx context.Context) (auroratype.Payload, error) {
+	return auroratype.Payload{}, nil
+}
+
+func (s *stubFetcher) ReadObject(ctx context.Context, name string) ([]byte, error) {
+	data, ok := s.objects[name]
+	if !ok {
+		return nil, fs.ErrNotExist
+	}
+	return data, nil
+}
+
+func (s *stubFetcher) IsStatic() bool {
+	return false
+}
+
+type countingRecorder struct {
+	counts map[string]int
+}
+
+func (r *countingRecorder) Count(metricName string, count int, tags []string) {
+	r.counts[tags[len(tags)-1]] += count
+}
+
+func (r *countingRecorder) Histogram(metricName string, value float64, tags []string) {}
+
+func newKey(t *testing.T) (ed25519.PublicKey, ed25519.PrivateKey) {
+	t.Helper()
+	public, private, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return public, private
+}
+
+const payload = "limit:\n  defaultValue: 10\n"
+
+func TestSidecarSignature(t *testing.T) {
+	oldPublic, oldPrivate := newKey(t)
+	newPublic, newPrivate := newKey(t)
+	inner := &stubFetcher{objects: map[string][]byte{
+		"parameters.yaml":     []byte(payload),
+		"parameters.yaml.sig": Sign(oldPrivate, []byte(payload)),
+	}}
+	recorder := &countingRecorder{counts: make(map[string]int)}
+	f := NewFetcher(Options{Fetcher: inner, TrustedKeys: []ed25519.PublicKey{oldPublic, newPublic}, MetricsRecorder: recorder})
+	ctx := context.Background()
+
+	config, err := f.Fetch(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := config["limit"].DefaultValue; got != 10 {
+		t.Errorf("limit = %v, want 10", got)
+	}
+
+	// rotated to the new key
+	inner.objects["parameters.yaml.sig"] = Sign(newPrivate, []byte(payload))
+	if _, err := f.Fetch(ctx); err != nil {
+		t.Errorf("Fetch after rotation: %v", err)
+	}
+
+	// a new payload uploaded before its signature is rejected, and stays
+	// rejected while the stale signature does not match it
+	updated := "limit:\n  defaultValue: 1000\n"
+	inner.objects["parameters.yaml"] = []byte(updated)
+	if _, err := f.Fetch(ctx); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Fetch before signature: got %v, want ErrInvalidSignature", err)
+	}
+	inner.notModified = true
+	if _, err := f.Fetch(ctx); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Fetch not modified: got %v, want ErrInvalidSignature", err)
+	}
+
+	// and is accepted once its signature is uploaded, although the payload
+	// itself is not modified
+	inner.objects["parameters.yaml.sig"] = Sign(newPrivate, []byte(updated))
+	config, err = f.Fetch(ctx)
+	if err != nil {
+		t.Fatalf("Fetch after signature upload: %v", err)
+	}
+	if got := config["limit"].DefaultValue; got != 1000 {
+		t.Errorf("limit = %v, want 1000", got)
+	}
+	if _, err := f.Fetch(ctx); !errors.Is(err, auroratype.ErrNotModified) {
+		t.Errorf("Fetch after acceptance: got %v, want ErrNotModified", err)
+	}
+
+	inner.notModified = false
+	delete(inner.objects, "parameters.yaml.sig")
+	if _, err := f.Fetch(ctx); !errors.Is(err, ErrUnsigned) {
+		t.Errorf("Fetch unsigned: got %v, want ErrUnsigned", err)
+	}
+
+	if recorder.counts["status:valid"] != 3 || recorder.counts["status:invalid"] != 2 || recorder.counts["status:unsigned"] != 1 {
+		t.Errorf("metrics = %v", recorder.counts)
+	}
+}
+
+func TestEnvelopeSignature(t *testing.T) {
+	public, private := newKey(t)
+	_, untrusted := newKey(t)
+
+	sealed, err := Seal([]byte(`{"limit": {"defaultValue": 10}}`), "json", private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inner := &stubFetcher{objects: map[string][]byte{"parameters.yaml": sealed}}
+	f := NewFetcher(Options{Fetcher: inner, TrustedKeys: []ed25519.PublicKey{public}, Mode: ModeEnvelope})
+	ctx := context.Background()
+
+	config, err := f.Fetch(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := config["limit"].DefaultValue; got != float64(10) {
+		t.Errorf("limit = %v, want 10", got)
+	}
+
+	inner.objects["parameters.yaml"], _ = Seal([]byte(payload), "yaml", untrusted)
+	if _, err := f.Fetch(ctx); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Fetch untrusted: got %v, want ErrInvalidSignature", err)
+	}
+
+	inner.objects["parameters.yaml"] = []byte(payload)
+	if _, err := f.Fetch(ctx); !errors.Is(err, ErrUnsigned) {
+		t.Errorf("Fetch plain: got %v, want ErrUnsigned", err)
+	}
+}
+
+func TestSignedBinarySnapshot(t *testing.T) {
+	public, private := newKey(t)
+	snapshot, err := auroratype.MarshalBinarySnapshot(
+		map[string]auroratype.Parameter{"limit": {DefaultValue: 10}},
+		[]auroratype.Experiment{{ID: "exp-1"}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inner := &stubFetcher{objects: map[string][]byte{
+		"parameters.yaml":     snapshot,
+		"parameters.yaml.sig": Sign(private, snapshot),
+	}}
+	f := NewFetcher(Options{Fetcher: inner, TrustedKeys: []ed25519.PublicKey{public}})
+	ctx := context.Background()
+
+	config, err := f.Fetch(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := config["limit"].DefaultValue; got != 10 {
+		t.Errorf("limit = %v, want 10", got)
+	}
+	experiments, err := f.FetchExperiments(ctx)
+	if err != nil || len(experiments) != 1 || experiments[0].ID != "exp-1" {
+		t.Errorf("FetchExperiments = %+v, %v", experiments, err)
+	}
+	if experiments, err := f.FetchExperiments(ctx); err != nil || experiments != nil {
+		t.Errorf("FetchExperiments again = %+v, %v, want nil for unchanged", experiments, err)
+	}
+}
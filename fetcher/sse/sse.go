@@ -0,0 +1,668 @@
+// Package sse streams configuration from a server over Server-Sent Events,
+// so that changes such as kill switches apply within moments instead of on
+// the next poll.
+//
+// The stream carries two kinds of events. A "snapshot" event (or an event
+// without a type) holds the whole configuration:
+//
+//	event: snapshot
+//	id: 41
+//	data: {"parameters": {"checkout": {"defaultValue": true}}, "experiments": []}
+//
+// A "patch" event replaces or, with null, removes individual parameters and
+// experiments, keyed by parameter name and experiment ID. Experiments keep
+// their order, and new ones are appended in ID order:
+//
+//	event: patch
+//	id: 42
+//	data: {"parameters": {"checkout": {"defaultValue": false}, "legacy": null}}
+//
+// Event data may be JSON or YAML. When reconnecting, the ID of the last
+// event is sent as Last-Event-ID so that the server can resume with patches;
+// a server that cannot should start with a snapshot.
+package sse
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"math/rand/v2"
+	"mime"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tuannguyensn2001/aurora-go/auroratype"
+)
+
+const (
+	MetricSSEConnectionTotal = "sse_connection_total"
+	MetricSSEEventTotal      = "sse_event_total"
+	MetricSSEPollTotal       = "sse_poll_total"
+)
+
+type MetricsRecorder interface {
+	Count(metricName string, count int, tags []string)
+	Histogram(metricName string, value float64, tags []string)
+}
+
+// Options configures the SSE Fetcher.
+type Options struct {
+	// URL serves the event stream.
+	URL string
+	// SnapshotURL serves the whole configuration, in the format of a
+	// snapshot event or as a binary snapshot, and is polled while the
+	// stream is down. Without it, polling reads the first snapshot of a
+	// new stream connection.
+	SnapshotURL string
+	// Client defaults to http.DefaultClient. It must not have a Timeout,
+	// which would end the stream.
+	Client *http.Client
+	// Header is added to every request.
+	Header http.Header
+	// Auth is called for every request before it is sent.
+	Auth func(req *http.Request) error
+	// Environment applies the environment sections of parameters and
+	// experiments, e.g. "prod".
+	Environment string
+	// MinBackoff and MaxBackoff bound the delay before reconnecting, which
+	// doubles after every failed attempt. They default to 500ms and 30s. A
+	// retry field sent by the server replaces MinBackoff.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// Logger defaults to slog.Default().
+	Logger          *slog.Logger
+	MetricsRecorder MetricsRecorder
+}
+
+// Fetcher keeps a stream open while it is watched and serves the
+// configuration received over it. Fetch does not touch the network while
+// the stream is connected, and reports auroratype.ErrNotModified when
+// nothing arrived since the previous Fetch; each event that changes the
+// configuration notifies the storage, which syncs right away. While the
+// stream is down, Fetch polls instead, so the storage's regular polling
+// takes over until the stream reconnects.
+type Fetcher struct {
+	client      *http.Client
+	url         string
+	snapshotURL string
+	header      http.Header
+	auth        func(req *http.Request) error
+	environment string
+	minBackoff  time.Duration
+	maxBackoff  time.Duration
+	logger      *slog.Logger
+	recorder    MetricsRecorder
+
+	mu    sync.Mutex
+	state state
+	// pollMu serializes polls
+	pollMu sync.Mutex
+}
+
+// state is the configuration received so far. Maps and slices are never
+// modified once stored, so they can be returned without copying.
+type state struct {
+	loaded      bool
+	parameters  map[string]auroratype.Parameter
+	experiments []auroratype.Experiment
+
+	// revisions of the parameters and experiments, and the revisions last
+	// returned by Fetch and FetchExperiments
+	parametersRev         uint64
+	experimentsRev        uint64
+	fetchedParametersRev  uint64
+	fetchedExperimentsRev uint64
+
+	connected   bool
+	lastEventID string
+	retry       time.Duration
+	// etag is the ETag of the last response of SnapshotURL
+	etag string
+}
+
+// document is the data of a snapshot event and the body of SnapshotURL.
+type document struct {
+	Parameters  map[string]auroratype.Parameter `yaml:"parameters" json:"parameters"`
+	Experiments []auroratype.Experiment         `yaml:"experiments" json:"experiments"`
+}
+
+// patch is the data of a patch event. A nil entry removes the parameter or
+// experiment.
+type patch struct {
+	Parameters  map[string]*auroratype.Parameter  `yaml:"parameters" json:"parameters"`
+	Experiments map[string]*auroratype.Experiment `yaml:"experiments" json:"experiments"`
+}
+
+// NewFetcher creates a new SSE-based Fetcher.
+func NewFetcher(opts Options) *Fetcher {
+	client := opts.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	minBackoff := opts.MinBackoff
+	if minBackoff <= 0 {
+		minBackoff = 500 * time.Millisecond
+	}
+	maxBackoff := opts.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = 30 * time.Second
+	}
+	logger := opts.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	recorder := opts.MetricsRecorder
+	if recorder == nil {
+		recorder = &noopRecorder{}
+	}
+	return &Fetcher{
+		client:      client,
+		url:         opts.URL,
+		snapshotURL: opts.SnapshotURL,
+		header:      opts.Header,
+		auth:        opts.Auth,
+		environment: opts.Environment,
+		minBackoff:  minBackoff,
+		maxBackoff:  max(maxBackoff, minBackoff),
+		logger:      logger,
+		recorder:    recorder,
+	}
+}
+
+type noopRecorder struct{}
+
+func (n *noopRecorder) Count(metricName string, count int, tags []string)         {}
+func (n *noopRecorder) Histogram(metricName string, value float64, tags []string) {}
+
+// Fetch returns the parameters received since the previous Fetch.
+func (f *Fetcher) Fetch(ctx context.Context) (map[string]auroratype.Parameter, error) {
+	if err := f.pollIfDisconnected(ctx); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.state.parametersRev == f.state.fetchedParametersRev {
+		return nil, auroratype.ErrNotModified
+	}
+	f.state.fetchedParametersRev = f.state.parametersRev
+	return auroratype.ResolveParameters(f.state.parameters, f.environment), nil
+}
+
+// FetchExperiments returns the experiments received since the previous
+// FetchExperiments. It does not poll: Fetch, which the storage calls
+// first, already did.
+func (f *Fetcher) FetchExperiments(ctx context.Context) ([]auroratype.Experiment, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.state.experimentsRev == f.state.fetchedExperimentsRev {
+		return nil, auroratype.ErrNotModified
+	}
+	f.state.fetchedExperimentsRev = f.state.experimentsRev
+	return auroratype.ResolveExperiments(f.state.experiments, f.environment), nil
+}
+
+func (f *Fetcher) IsStatic() bool {
+	return false
+}
+
+// Connected reports whether the stream is currently connected.
+func (f *Fetcher) Connected() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.state.connected
+}
+
+// Watch keeps the stream connected until ctx is done, reconnecting with
+// backoff, and calls notify whenever an event changes the configuration.
+func (f *Fetcher) Watch(ctx context.Context, notify func()) error {
+	attempt := 0
+	for {
+		received, err := f.stream(ctx, notify)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if received {
+			attempt = 0
+		}
+		attempt++
+
+		delay := f.backoff(attempt)
+		f.logger.Warn("Event stream disconnected, reconnecting", "error", err, "delay", delay)
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
+	}
+}
+
+// stream reads events until the connection ends, reporting whether any
+// event was applied.
+func (f *Fetcher) stream(ctx context.Context, notify func()) (received bool, err error) {
+	resp, err := f.connect(ctx, f.resumeID())
+	if err != nil {
+		f.recorder.Count(MetricSSEConnectionTotal, 1, []string{"status:error"})
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	f.recorder.Count(MetricSSEConnectionTotal, 1, []string{"status:connected"})
+	f.setConnected(true)
+	defer f.setConnected(false)
+
+	events := newEventReader(resp.Body)
+	for {
+		ev, err := events.next()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				err = errors.New("stream closed by server")
+			}
+			return received, err
+		}
+
+		changed, err := f.apply(ev)
+		if err != nil {
+			// drop the connection; without a Last-Event-ID the server
+			// starts over with a snapshot
+			return received, err
+		}
+		received = true
+		if changed {
+			notify()
+		}
+	}
+}
+
+func (f *Fetcher) backoff(attempt int) time.Duration {
+	f.mu.Lock()
+	delay := f.minBackoff
+	if f.state.retry > 0 {
+		delay = f.state.retry
+	}
+	f.mu.Unlock()
+
+	for i := 1; i < attempt && delay < f.maxBackoff; i++ {
+		delay *= 2
+	}
+	delay = min(delay, f.maxBackoff)
+	// spread reconnections of many clients after a server restart
+	return delay/2 + rand.N(delay/2+1)
+}
+
+func (f *Fetcher) connect(ctx context.Context, lastEventID string) (*http.Response, error) {
+	req, err := f.newRequest(ctx, f.url)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("Cache-Control", "no-cache")
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if resp.StatusCode != http.StatusOK || mediaType != "text/event-stream" {
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		return nil, fmt.Errorf("GET %s: unexpected response %s, %q", f.url, resp.Status, resp.Header.Get("Content-Type"))
+	}
+	return resp, nil
+}
+
+func (f *Fetcher) newRequest(ctx context.Context, rawURL string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range f.header {
+		for _, v := range values {
+			req.Header.Add(key, v)
+		}
+	}
+	if f.auth != nil {
+		if err := f.auth(req); err != nil {
+			return nil, err
+		}
+	}
+	return req, nil
+}
+
+// apply applies an event, reporting whether it changed the configuration.
+func (f *Fetcher) apply(ev event) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if ev.retry > 0 {
+		f.state.retry = ev.retry
+	}
+
+	kind := ev.typ
+	var changed bool
+	var err error
+	switch ev.typ {
+	case "", "message", "snapshot":
+		kind = "snapshot"
+		var doc document
+		if err = decode(ev.data, &doc); err == nil {
+			changed = f.state.replace(doc.Parameters, doc.Experiments)
+		}
+	case "patch":
+		var p patch
+		if !f.state.loaded {
+			err = errors.New("patch before any snapshot")
+		} else if err = decode(ev.data, &p); err == nil {
+			changed = f.state.patch(p)
+		}
+	default:
+		// unknown events are ignored, but still move the stream on
+		kind = "unknown"
+	}
+
+	if err != nil {
+		f.state.lastEventID = ""
+		f.recorder.Count(MetricSSEEventTotal, 1, []string{"type:" + kind, "status:invalid"})
+		return false, fmt.Errorf("event %q: %w", ev.id, err)
+	}
+	if ev.hasID {
+		f.state.lastEventID = ev.id
+	}
+	f.recorder.Count(MetricSSEEventTotal, 1, []string{"type:" + kind, "status:applied"})
+	return changed, nil
+}
+
+func decode(data string, v any) error {
+	return auroratype.Decode([]byte(data), auroratype.FormatOf("", "", []byte(data)), v)
+}
+
+// replace stores a whole configuration. Revisions only move for the parts
+// that changed, so that repeated snapshots do not trigger syncs.
+func (s *state) replace(parameters map[string]auroratype.Parameter, experiments []auroratype.Experiment) bool {
+	if parameters == nil {
+		parameters = make(map[string]auroratype.Parameter)
+	}
+	if experiments == nil {
+		experiments = []auroratype.Experiment{}
+	}
+
+	changed := false
+	if !s.loaded || auroratype.ContentVersion(parameters, nil) != auroratype.ContentVersion(s.parameters, nil) {
+		s.parameters = parameters
+		s.parametersRev++
+		changed = true
+	}
+	if !s.loaded || auroratype.ContentVersion(nil, experiments) != auroratype.ContentVersion(nil, s.experiments) {
+		s.experiments = experiments
+		s.experimentsRev++
+		changed = true
+	}
+	s.loaded = true
+	return changed
+}
+
+func (s *state) patch(p patch) bool {
+	changed := false
+	if len(p.Parameters) > 0 {
+		parameters := make(map[string]auroratype.Parameter, len(s.parameters)+len(p.Parameters))
+		for name, param := range s.parameters {
+			parameters[name] = param
+		}
+		for name, param := range p.Parameters {
+			if param == nil {
+				delete(parameters, name)
+			} else {
+				parameters[name] = *param
+			}
+		}
+		s.parameters = parameters
+		s.parametersRev++
+		changed = true
+	}
+
+	if len(p.Experiments) > 0 {
+		experiments := make([]auroratype.Experiment, 0, len(s.experiments)+len(p.Experiments))
+		for _, exp := range s.experiments {
+			update, ok := p.Experiments[exp.ID]
+			if !ok {
+				experiments = append(experiments, exp)
+			} else if update != nil {
+				// replaced in place, keeping the order
+				experiments = append(experiments, withID(*update, exp.ID))
+			}
+		}
+		// new experiments are appended in ID order, so that every client
+		// applying the patch evaluates them in the same order
+		for _, id := range slices.Sorted(maps.Keys(p.Experiments)) {
+			if exp := p.Experiments[id]; exp != nil && !containsExperiment(s.experiments, id) {
+				experiments = append(experiments, withID(*exp, id))
+			}
+		}
+		s.experiments = experiments
+		s.experimentsRev++
+		changed = true
+	}
+	return changed
+}
+
+func withID(exp auroratype.Experiment, id string) auroratype.Experiment {
+	exp.ID = id
+	return exp
+}
+
+func containsExperiment(experiments []auroratype.Experiment, id string) bool {
+	for _, exp := range experiments {
+		if exp.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *Fetcher) setConnected(connected bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.state.connected = connected
+}
+
+// resumeID returns the Last-Event-ID to reconnect with, if the current
+// configuration came from the stream.
+func (f *Fetcher) resumeID() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.state.loaded {
+		return ""
+	}
+	return f.state.lastEventID
+}
+
+// pollIfDisconnected refreshes the configuration without the stream, from
+// SnapshotURL or from the first snapshot of a new connection.
+func (f *Fetcher) pollIfDisconnected(ctx context.Context) error {
+	f.pollMu.Lock()
+	defer f.pollMu.Unlock()
+
+	f.mu.Lock()
+	skip := f.state.connected && f.state.loaded
+	f.mu.Unlock()
+	if skip {
+		return nil
+	}
+
+	var err error
+	if f.snapshotURL != "" {
+		err = f.pollSnapshot(ctx)
+	} else {
+		err = f.readSnapshot(ctx)
+	}
+	if errors.Is(err, auroratype.ErrNotModified) {
+		f.recorder.Count(MetricSSEPollTotal, 1, []string{"status:not_modified"})
+		return nil
+	}
+	if err != nil {
+		f.recorder.Count(MetricSSEPollTotal, 1, []string{"status:error"})
+		return err
+	}
+	f.recorder.Count(MetricSSEPollTotal, 1, []string{"status:success"})
+	return nil
+}
+
+func (f *Fetcher) pollSnapshot(ctx context.Context) error {
+	req, err := f.newRequest(ctx, f.snapshotURL)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept-Encoding", "gzip, zstd")
+	f.mu.Lock()
+	if f.state.etag != "" && f.state.loaded {
+		req.Header.Set("If-None-Match", f.state.etag)
+	}
+	f.mu.Unlock()
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotModified {
+		return auroratype.ErrNotModified
+	}
+	if resp.StatusCode != http.StatusOK {
+		io.Copy(io.Discard, resp.Body)
+		return fmt.Errorf("GET %s: unexpected status %s", f.snapshotURL, resp.Status)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if data, err = auroratype.Decompress(data, resp.Header.Get("Content-Encoding")); err != nil {
+		return fmt.Errorf("GET %s: %w", f.snapshotURL, err)
+	}
+
+	var doc document
+	var name string
+	if u, err := url.Parse(f.snapshotURL); err == nil {
+		name = u.Path
+	}
+	format := auroratype.FormatOf(name, resp.Header.Get("Content-Type"), data)
+	if format == auroratype.FormatBinary {
+		doc.Parameters, doc.Experiments, err = auroratype.UnmarshalBinarySnapshot(data)
+	} else {
+		err = auroratype.Decode(data, format, &doc)
+	}
+	if err != nil {
+		return fmt.Errorf("GET %s: %w", f.snapshotURL, err)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.state.replace(doc.Parameters, doc.Experiments)
+	f.state.etag = resp.Header.Get("ETag")
+	// the stream must start over, as its events may predate this snapshot
+	f.state.lastEventID = ""
+	return nil
+}
+
+// readSnapshot opens a connection, applies its first snapshot and closes
+// it again. Events before the snapshot are ignored.
+func (f *Fetcher) readSnapshot(ctx context.Context) error {
+	resp, err := f.connect(ctx, "")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	events := newEventReader(resp.Body)
+	for {
+		ev, err := events.next()
+		if err != nil {
+			return fmt.Errorf("waiting for a snapshot: %w", err)
+		}
+		switch ev.typ {
+		case "", "message", "snapshot":
+			_, err := f.apply(ev)
+			return err
+		}
+	}
+}
+
+type event struct {
+	typ   string
+	data  string
+	id    string
+	hasID bool
+	retry time.Duration
+}
+
+// eventReader parses a text/event-stream body.
+type eventReader struct {
+	r *bufio.Reader
+	// retry is the reconnection delay last sent by the server
+	retry time.Duration
+}
+
+func newEventReader(r io.Reader) *eventReader {
+	return &eventReader{r: bufio.NewReader(r)}
+}
+
+// next returns the next event with data. Comments, such as keep-alives,
+// are skipped.
+func (e *eventReader) next() (event, error) {
+	var ev event
+	var data strings.Builder
+	hasData := false
+	for {
+		line, err := e.r.ReadString('\n')
+		if err != nil {
+			return event{}, err
+		}
+		line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+
+		if line == "" {
+			if hasData {
+				ev.data = strings.TrimSuffix(data.String(), "\n")
+				ev.retry = e.retry
+				return ev, nil
+			}
+			ev = event{}
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			ev.typ = value
+		case "data":
+			data.WriteString(value)
+			data.WriteByte('\n')
+			hasData = true
+		case "id":
+			if !strings.ContainsRune(value, 0) {
+				ev.id, ev.hasID = value, true
+			}
+		case "retry":
+			if ms, err := strconv.Atoi(value); err == nil && ms > 0 {
+				e.retry = time.Duration(ms) * time.Millisecond
+			}
+		}
+	}
+}
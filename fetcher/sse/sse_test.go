@@ -0,0 +1,256 @@
+package sse
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/tuannguyensn2001/aurora-go/auroratype"
+)
+
+// streamServer streams the events sent on events to the current
+// connection. An empty event closes the connection.
+type streamServer struct {
+	// connections receives the Last-Event-ID of every new connection
+	connections chan string
+	events      chan string
+	// initial is sent to connections without a Last-Event-ID
+	initial string
+}
+
+func newStreamServer(initial string) *streamServer {
+	return &streamServer{connections: make(chan string, 10), events: make(chan string), initial: initial}
+}
+
+func (s *streamServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.WriteHeader(http.StatusOK)
+	id := r.Header.Get("Last-Event-ID")
+	if id == "" {
+		io.WriteString(w, s.initial)
+	}
+	w.(http.Flusher).Flush()
+	s.connections <- id
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case ev := <-s.events:
+			if ev == "" {
+				return
+			}
+			io.WriteString(w, ev)
+			w.(http.Flusher).Flush()
+		}
+	}
+}
+
+func receive[T any](t *testing.T, ch <-chan T) T {
+	t.Helper()
+	select {
+	case v := <-ch:
+		return v
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out")
+		panic("unreachable")
+	}
+}
+
+func TestStreamSnapshotsAndPatches(t *testing.T) {
+	stream := newStreamServer("")
+	server := httptest.NewServer(stream)
+	defer server.Close()
+
+	f := NewFetcher(Options{URL: server.URL, MinBackoff: 10 * time.Millisecond, MaxBackoff: 20 * time.Millisecond})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	notified := make(chan struct{}, 10)
+	go f.Watch(ctx, func() { notified <- struct{}{} })
+
+	if id := receive(t, stream.connections); id != "" {
+		t.Errorf("first connection Last-Event-ID = %q, want none", id)
+	}
+	stream.events <- ": keep-alive\n\n" +
+		"event: snapshot\nid: 1\n" +
+		`data: {"parameters": {"checkout": {"defaultValue": true}, "legacy": {"defaultValue": 1}},` + "\n" +
+		`data:  "experiments": [{"id": "exp-1"}]}` + "\n\n"
+	receive(t, notified)
+
+	config, err := f.Fetch(ctx)
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	if config["checkout"].DefaultValue != true || len(config) != 2 {
+		t.Errorf("config = %+v", config)
+	}
+	experiments, err := f.FetchExperiments(ctx)
+	if err != nil || len(experiments) != 1 {
+		t.Errorf("FetchExperiments = %+v, %v", experiments, err)
+	}
+	if _, err := f.Fetch(ctx); !errors.Is(err, auroratype.ErrNotModified) {
+		t.Errorf("Fetch: got %v, want ErrNotModified", err)
+	}
+
+	stream.events <- "event: patch\nid: 2\n" +
+		`data: {"parameters": {"checkout": {"defaultValue": false}, "legacy": null}}` + "\n\n"
+	receive(t, notified)
+
+	config, err = f.Fetch(ctx)
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	if config["checkout"].DefaultValue != false || len(config) != 1 {
+		t.Errorf("config after patch = %+v", config)
+	}
+	if _, err := f.FetchExperiments(ctx); !errors.Is(err, auroratype.ErrNotModified) {
+		t.Errorf("FetchExperiments: got %v, want ErrNotModified", err)
+	}
+
+	// the server drops the connection and the fetcher resumes
+	stream.events <- ""
+	if id := receive(t, stream.connections); id != "2" {
+		t.Errorf("reconnection Last-Event-ID = %q, want 2", id)
+	}
+	stream.events <- "event: patch\nid: 3\n" +
+		`data: {"experiments": {"exp-4": {"name": "Search"}, "exp-2": {"name": "Pricing"}, "exp-3": {"name": "Onboarding"}}}` + "\n\n"
+	receive(t, notified)
+
+	experiments, err = f.FetchExperiments(ctx)
+	if err != nil || len(experiments) != 4 || experiments[1].ID != "exp-2" || experiments[2].ID != "exp-3" || experiments[3].ID != "exp-4" {
+		t.Errorf("FetchExperiments after patch = %+v, %v, want new experiments in ID order", experiments, err)
+	}
+}
+
+func TestInvalidEventRestartsStream(t *testing.T) {
+	stream := newStreamServer("")
+	server := httptest.NewServer(stream)
+	defer server.Close()
+
+	f := NewFetcher(Options{URL: server.URL, MinBackoff: 10 * time.Millisecond, MaxBackoff: 20 * time.Millisecond})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go f.Watch(ctx, func() {})
+
+	receive(t, stream.connections)
+	stream.events <- "id: 1\ndata: {\"parameters\": {}}\n\n"
+	stream.events <- "event: patch\nid: 2\ndata: {not json\n\n"
+
+	// the fetcher cannot trust its state, so it asks for a snapshot
+	if id := receive(t, stream.connections); id != "" {
+		t.Errorf("Last-Event-ID after an invalid event = %q, want none", id)
+	}
+}
+
+func TestInitialFetchReadsStream(t *testing.T) {
+	stream := newStreamServer("id: 7\ndata: parameters: {checkout: {defaultValue: 10}}\n\n")
+	server := httptest.NewServer(stream)
+	defer server.Close()
+
+	f := NewFetcher(Options{URL: server.URL, MinBackoff: 10 * time.Millisecond})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	config, err := f.Fetch(ctx)
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	if got := config["checkout"].DefaultValue; got != 10 {
+		t.Errorf("checkout = %v, want 10", got)
+	}
+	receive(t, stream.connections)
+
+	go f.Watch(ctx, func() {})
+	if id := receive(t, stream.connections); id != "7" {
+		t.Errorf("Last-Event-ID = %q, want 7", id)
+	}
+}
+
+func TestFallsBackToPolling(t *testing.T) {
+	var version atomic.Int32
+	snapshots := []string{
+		`{"parameters": {"checkout": {"defaultValue": true}}}`,
+		`{"parameters": {"checkout": {"defaultValue": false}}}`,
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/stream", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	mux.HandleFunc("/snapshot", func(w http.ResponseWriter, r *http.Request) {
+		v := version.Load()
+		etag := fmt.Sprintf(`"v%d"`, v)
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, snapshots[v])
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	f := NewFetcher(Options{URL: server.URL + "/stream", SnapshotURL: server.URL + "/snapshot"})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go f.Watch(ctx, func() {})
+
+	config, err := f.Fetch(ctx)
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	if config["checkout"].DefaultValue != true {
+		t.Errorf("config = %+v", config)
+	}
+	if f.Connected() {
+		t.Error("Connected = true for a failing stream")
+	}
+	if _, err := f.Fetch(ctx); !errors.Is(err, auroratype.ErrNotModified) {
+		t.Errorf("Fetch: got %v, want ErrNotModified", err)
+	}
+
+	version.Store(1)
+	config, err = f.Fetch(ctx)
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	if config["checkout"].DefaultValue != false {
+		t.Errorf("config = %+v", config)
+	}
+}
+
+func TestEventReader(t *testing.T) {
+	events := newEventReader(strings.NewReader(
+		": comment\r\nretry: 2500\r\n\r\n" +
+			"event: patch\r\nid: 5\r\ndata: line one\r\ndata:line two\r\n\r\n" +
+			"data: no type\n\n" +
+			"event: dangling\n"))
+
+	ev, err := events.next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ev.typ != "patch" || ev.id != "5" || !ev.hasID || ev.data != "line one\nline two" || ev.retry != 2500*time.Millisecond {
+		t.Errorf("first event = %+v", ev)
+	}
+
+	ev, err = events.next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ev.typ != "" || ev.hasID || ev.data != "no type" {
+		t.Errorf("second event = %+v", ev)
+	}
+
+	if _, err := events.next(); !errors.Is(err, io.EOF) {
+		t.Errorf("incomplete event: got %v, want io.EOF", err)
+	}
+}
@@ -5,14 +5,17 @@ go 1.25.5
 require (
 	github.com/spaolacci/murmur3 v1.1.0
 	github.com/tuannguyensn2001/aurora-go/auroratype v0.0.0-00010101000000-000000000000
+	gopkg.in/yaml.v2 v2.4.0
 )
 
 require (
+	github.com/BurntSushi/toml v1.6.0 // indirect
 	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/klauspost/compress v1.18.0 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	github.com/stretchr/objx v0.5.2 // indirect
 	github.com/stretchr/testify v1.11.1
-	gopkg.in/yaml.v3 v3.0.1 // indirect
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 replace github.com/tuannguyensn2001/aurora-go/auroratype => ./auroratype
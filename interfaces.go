@@ -6,15 +6,42 @@ import (
 	"github.com/tuannguyensn2001/aurora-go/auroratype"
 )
 
+// Fetcher loads configuration from a source. Fetch and FetchExperiments may
+// return auroratype.ErrNotModified when the source is unchanged since their
+// previous successful call.
 type Fetcher interface {
 	Fetch(ctx context.Context) (map[string]auroratype.Parameter, error)
 	FetchExperiments(ctx context.Context) ([]auroratype.Experiment, error)
 	IsStatic() bool
 }
 
+// Watcher is implemented by fetchers that can tell when their source has
+// changed. Watch blocks until ctx is done, calling notify on every change,
+// and the storage syncs on each notification in addition to polling. When
+// Watch returns, the storage falls back to polling alone.
+type Watcher interface {
+	Watch(ctx context.Context, notify func()) error
+}
+
+// SourceLocator is implemented by fetchers that know where each parameter
+// was defined, such as the file it was read from. ParameterSources is
+// called after every successful Fetch and reports on that fetch.
+type SourceLocator interface {
+	ParameterSources() map[string]string
+}
+
 type Storage interface {
 	Save(ctx context.Context, config map[string]auroratype.Parameter) error
 	Get(ctx context.Context, parameterName string) (auroratype.Parameter, error)
 	SaveExperiments(ctx context.Context, experiments []auroratype.Experiment) error
 	GetExperiments(ctx context.Context) ([]auroratype.Experiment, error)
 }
+
+// SnapshotStorage is implemented by storages that can replace parameters and
+// experiments atomically. Readers of LoadSnapshot never observe parameters
+// from one sync combined with experiments from another.
+type SnapshotStorage interface {
+	Storage
+	SaveSnapshot(ctx context.Context, snapshot *auroratype.Snapshot) error
+	LoadSnapshot(ctx context.Context) (*auroratype.Snapshot, error)
+}
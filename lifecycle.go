@@ -0,0 +1,100 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var ErrClosed = errors.New("aurora: client is closed")
+
+// SyncResult describes the outcome of the most recent sync.
+type SyncResult struct {
+	Time time.Time
+	// Version is the content version of the fetched snapshot. It is empty
+	// when the fetch itself failed.
+	Version string
+	Err     error
+	// Rejected is set when the snapshot was fetched but failed validation.
+	// The previous snapshot keeps being served.
+	Rejected bool
+	// NotModified is set when the fetcher reported that the source has not
+	// changed since the previous sync.
+	NotModified bool
+}
+
+// Status summarizes the health of the configuration pipeline. It is cheap
+// to compute and suitable for readiness probes.
+type Status struct {
+	// Ready is set once a snapshot is being served and the client is not
+	// closed. A client serving a cached snapshot is ready but Stale.
+	Ready  bool
+	Stale  bool
+	Closed bool
+	// Version is the content version of the snapshot being served.
+	Version string
+
+	LastSuccess         time.Time
+	LastFailure         time.Time
+	LastError           error
+	ConsecutiveFailures int
+}
+
+// syncCall is a sync shared by every caller that asks for one while it is
+// running.
+type syncCall struct {
+	done chan struct{}
+	err  error
+}
+
+// waitContext runs wait and returns once it does or ctx is done.
+func waitContext(ctx context.Context, wait func()) error {
+	done := make(chan struct{})
+	go func() {
+		wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Refresh syncs the configuration now instead of waiting for the next poll.
+// Concurrent calls share a single fetch and all receive its result.
+func (c *Client) Refresh(ctx context.Context) error {
+	return c.storage.refresh(ctx)
+}
+
+// Status reports the current health of the client.
+func (c *Client) Status() Status {
+	return c.storage.Status()
+}
+
+// Close stops polling and background maintenance, flushes pending bandit
+// updates and change notifications, and waits for background goroutines to
+// exit or ctx to be done. Watch channels are closed. The client keeps
+// serving the last snapshot after Close.
+func (c *Client) Close(ctx context.Context) error {
+	c.closeOnce.Do(func() {
+		close(c.closed)
+	})
+
+	if err := c.storage.Close(ctx); err != nil {
+		return err
+	}
+	if err := waitContext(ctx, c.wg.Wait); err != nil {
+		return err
+	}
+
+	if experiments, err := c.storage.GetExperiments(ctx); err == nil {
+		if err := c.bandit.Update(ctx, experiments); err != nil {
+			c.logger.Error("Failed to flush bandit weights", "error", err)
+		}
+	}
+
+	return waitContext(ctx, c.storage.notifier.wait)
+}
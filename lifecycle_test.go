@@ -0,0 +1,141 @@
+package core
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tuannguyensn2001/aurora-go/auroratype"
+)
+
+type countingFetcher struct {
+	calls   atomic.Int32
+	release chan struct{}
+	static  bool
+
+	mu  sync.Mutex
+	err error
+}
+
+func (f *countingFetcher) Fetch(ctx context.Context) (map[string]auroratype.Parameter, error) {
+	f.calls.Add(1)
+	if f.release != nil {
+		<-f.release
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return nil, f.err
+	}
+	return map[string]auroratype.Parameter{"limit": {DefaultValue: 1}}, nil
+}
+
+func (f *countingFetcher) FetchExperiments(ctx context.Context) ([]auroratype.Experiment, error) {
+	return nil, nil
+}
+
+func (f *countingFetcher) IsStatic() bool {
+	return f.static
+}
+
+func (f *countingFetcher) setErr(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.err = err
+}
+
+func TestClientRefreshCoalescesConcurrentCalls(t *testing.T) {
+	ctx := context.Background()
+	fetcher := &countingFetcher{static: true}
+	client := NewClient(NewFetcherStorage(fetcher), ClientOptions{})
+	require.NoError(t, client.Start(ctx))
+	assert.EqualValues(t, 1, fetcher.calls.Load())
+
+	fetcher.release = make(chan struct{})
+	var wg sync.WaitGroup
+	errs := make([]error, 5)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = client.Refresh(ctx)
+		}(i)
+	}
+
+	assert.Eventually(t, func() bool { return fetcher.calls.Load() == 2 }, time.Second, time.Millisecond)
+	// give the other callers time to join the running sync
+	time.Sleep(20 * time.Millisecond)
+	close(fetcher.release)
+	wg.Wait()
+
+	assert.EqualValues(t, 2, fetcher.calls.Load())
+	for _, err := range errs {
+		assert.NoError(t, err)
+	}
+}
+
+func TestClientStatus(t *testing.T) {
+	ctx := context.Background()
+	fetcher := &countingFetcher{static: true}
+	client := NewClient(NewFetcherStorage(fetcher), ClientOptions{})
+
+	assert.False(t, client.Status().Ready)
+
+	require.NoError(t, client.Start(ctx))
+	status := client.Status()
+	assert.True(t, status.Ready)
+	assert.NotEmpty(t, status.Version)
+	assert.False(t, status.LastSuccess.IsZero())
+	assert.Zero(t, status.ConsecutiveFailures)
+
+	fetcher.setErr(assert.AnError)
+	assert.Error(t, client.Refresh(ctx))
+	assert.Error(t, client.Refresh(ctx))
+
+	status = client.Status()
+	assert.True(t, status.Ready, "the previous snapshot is still served")
+	assert.Equal(t, 2, status.ConsecutiveFailures)
+	assert.ErrorIs(t, status.LastError, assert.AnError)
+	assert.False(t, status.LastFailure.IsZero())
+
+	fetcher.setErr(nil)
+	require.NoError(t, client.Refresh(ctx))
+	assert.Zero(t, client.Status().ConsecutiveFailures)
+}
+
+func TestClientClose(t *testing.T) {
+	ctx := context.Background()
+	fetcher := &countingFetcher{}
+	client := NewClient(NewFetcherStorage(fetcher, WithInterval(time.Millisecond)), ClientOptions{
+		BanditUpdateInterval: time.Millisecond,
+	})
+	require.NoError(t, client.Start(ctx))
+
+	values := client.Watch(ctx, "limit", NewAttribute())
+	<-values
+
+	assert.Eventually(t, func() bool { return fetcher.calls.Load() > 1 }, time.Second, time.Millisecond)
+
+	closeCtx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	require.NoError(t, client.Close(closeCtx))
+
+	calls := fetcher.calls.Load()
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, calls, fetcher.calls.Load(), "polling stops after Close")
+
+	_, open := <-values
+	assert.False(t, open)
+
+	assert.ErrorIs(t, client.Refresh(ctx), ErrClosed)
+	status := client.Status()
+	assert.True(t, status.Closed)
+	assert.False(t, status.Ready)
+
+	assert.Equal(t, 1, client.GetParameter(ctx, "limit", NewAttribute()).Value(), "the last snapshot is still served")
+}
@@ -0,0 +1,640 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tuannguyensn2001/aurora-go/auroratype"
+)
+
+var (
+	// ErrNotFound is returned by a Manager for a parameter or experiment
+	// that does not exist.
+	ErrNotFound = errors.New("aurora: not found")
+	// ErrExists is returned by a Manager when creating a parameter or
+	// experiment that already exists.
+	ErrExists = errors.New("aurora: already exists")
+	// ErrReadOnly is returned by a Manager without a writable source.
+	ErrReadOnly = errors.New("aurora: the configuration source is not writable")
+)
+
+const (
+	kindParameter  = "parameter"
+	kindExperiment = "experiment"
+)
+
+// ManagerOptions configures a Manager.
+type ManagerOptions struct {
+	// Source is where changes are written. It must be the source the
+	// client fetches from, so that a change is served once written.
+	// Defaults to the client's fetcher if it implements
+	// auroratype.WritableSource, as the file and S3 fetchers do.
+	Source auroratype.WritableSource
+	// AuditLog records every change. Defaults to none.
+	AuditLog AuditLog
+}
+
+// Manager changes parameters and experiments at runtime. Every change is
+// made to the configuration as stored in the source, validated like a
+// fetched snapshot, written back, recorded in the audit log and synced into
+// the client right away. A change the audit log fails to record is still
+// stored but returns an error. Writes are optimistic: a source changed by
+// someone else since it was loaded fails with auroratype.ErrConflict.
+//
+// Changes apply to the base definitions; environment sections are kept as
+// they are.
+type Manager struct {
+	client *Client
+	source auroratype.WritableSource
+	audit  AuditLog
+
+	// mu serializes changes, so that concurrent changes through the same
+	// Manager do not conflict.
+	mu sync.Mutex
+}
+
+func NewManager(client *Client, opts ManagerOptions) *Manager {
+	source := opts.Source
+	if source == nil {
+		source, _ = client.storage.fetcher.(auroratype.WritableSource)
+	}
+	audit := opts.AuditLog
+	if audit == nil {
+		audit = noopAuditLog{}
+	}
+	return &Manager{client: client, source: source, audit: audit}
+}
+
+// Load returns the configuration as stored in the source.
+func (m *Manager) Load(ctx context.Context) (auroratype.SourceContent, error) {
+	if m.source == nil {
+		return auroratype.SourceContent{}, ErrReadOnly
+	}
+	return m.source.Load(ctx)
+}
+
+// CreateParameter adds a parameter, failing with ErrExists if there is
+// one named name already.
+func (m *Manager) CreateParameter(ctx context.Context, actor, name string, param auroratype.Parameter) (AuditEntry, error) {
+	return m.changeParameter(ctx, actor, AuditCreate, name, func(current *auroratype.Parameter) (*auroratype.Parameter, error) {
+		if current != nil {
+			return nil, fmt.Errorf("parameter %q: %w", name, ErrExists)
+		}
+		return &param, nil
+	})
+}
+
+// UpdateParameter replaces the definition of a parameter.
+func (m *Manager) UpdateParameter(ctx context.Context, actor, name string, param auroratype.Parameter) (AuditEntry, error) {
+	return m.changeParameter(ctx, actor, AuditUpdate, name, func(current *auroratype.Parameter) (*auroratype.Parameter, error) {
+		if current == nil {
+			return nil, fmt.Errorf("parameter %q: %w", name, ErrNotFound)
+		}
+		return &param, nil
+	})
+}
+
+// DeleteParameter removes a parameter.
+func (m *Manager) DeleteParameter(ctx context.Context, actor, name string) (AuditEntry, error) {
+	return m.changeParameter(ctx, actor, AuditDelete, name, func(current *auroratype.Parameter) (*auroratype.Parameter, error) {
+		if current == nil {
+			return nil, fmt.Errorf("parameter %q: %w", name, ErrNotFound)
+		}
+		return nil, nil
+	})
+}
+
+// ToggleParameter sets the default value of a boolean parameter, such as a
+// kill switch, to enabled. Rules are kept, so attributes matching a rule
+// still get the rule's value.
+func (m *Manager) ToggleParameter(ctx context.Context, actor, name string, enabled bool) (AuditEntry, error) {
+	return m.changeParameter(ctx, actor, AuditToggle, name, func(current *auroratype.Parameter) (*auroratype.Parameter, error) {
+		if current == nil {
+			return nil, fmt.Errorf("parameter %q: %w", name, ErrNotFound)
+		}
+		if _, ok := current.DefaultValue.(bool); !ok {
+			return nil, auroratype.ValidationErrors{Errors: []auroratype.ValidationError{{
+				Parameter: name,
+				RuleIndex: -1,
+				Field:     "defaultValue",
+				Message:   fmt.Sprintf("cannot toggle a parameter with default value %v, want a boolean", current.DefaultValue),
+			}}}
+		}
+		param := *current
+		param.DefaultValue = enabled
+		return &param, nil
+	})
+}
+
+// CreateExperiment adds an experiment, failing with ErrExists if there is
+// one with the same ID already.
+func (m *Manager) CreateExperiment(ctx context.Context, actor string, exp auroratype.Experiment) (AuditEntry, error) {
+	return m.changeExperiment(ctx, actor, AuditCreate, exp.ID, func(current *auroratype.Experiment) (*auroratype.Experiment, error) {
+		if current != nil {
+			return nil, fmt.Errorf("experiment %q: %w", exp.ID, ErrExists)
+		}
+		return &exp, nil
+	})
+}
+
+// UpdateExperiment replaces the definition of the experiment with the ID
+// of exp.
+func (m *Manager) UpdateExperiment(ctx context.Context, actor string, exp auroratype.Experiment) (AuditEntry, error) {
+	return m.changeExperiment(ctx, actor, AuditUpdate, exp.ID, func(current *auroratype.Experiment) (*auroratype.Experiment, error) {
+		if current == nil {
+			return nil, fmt.Errorf("experiment %q: %w", exp.ID, ErrNotFound)
+		}
+		return &exp, nil
+	})
+}
+
+// DeleteExperiment removes an experiment.
+func (m *Manager) DeleteExperiment(ctx context.Context, actor, id string) (AuditEntry, error) {
+	return m.changeExperiment(ctx, actor, AuditDelete, id, func(current *auroratype.Experiment) (*auroratype.Experiment, error) {
+		if current == nil {
+			return nil, fmt.Errorf("experiment %q: %w", id, ErrNotFound)
+		}
+		return nil, nil
+	})
+}
+
+// SetExperimentStatus changes the status of an experiment, e.g. to
+// auroratype.StatusAborted to stop it.
+func (m *Manager) SetExperimentStatus(ctx context.Context, actor, id string, status auroratype.ExperimentStatus) (AuditEntry, error) {
+	return m.changeExperiment(ctx, actor, AuditStatus, id, func(current *auroratype.Experiment) (*auroratype.Experiment, error) {
+		if current == nil {
+			return nil, fmt.Errorf("experiment %q: %w", id, ErrNotFound)
+		}
+		exp := *current
+		exp.Status = status
+		return &exp, nil
+	})
+}
+
+// changeParameter replaces the parameter named name with the result of
+// change, which receives nil for a parameter that does not exist and
+// returns nil to delete it.
+func (m *Manager) changeParameter(ctx context.Context, actor string, action AuditAction, name string, change func(*auroratype.Parameter) (*auroratype.Parameter, error)) (AuditEntry, error) {
+	return m.change(ctx, actor, action, kindParameter, name, func(content *auroratype.SourceContent) (any, any, error) {
+		var before, after any
+		var current *auroratype.Parameter
+		if param, ok := content.Parameters[name]; ok {
+			current = &param
+			before = param
+		}
+		next, err := change(current)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		parameters := maps.Clone(content.Parameters)
+		if parameters == nil {
+			parameters = make(map[string]auroratype.Parameter)
+		}
+		if next == nil {
+			delete(parameters, name)
+		} else {
+			parameters[name] = *next
+			after = *next
+		}
+		content.Parameters = parameters
+		return before, after, nil
+	})
+}
+
+// changeExperiment is changeParameter for the experiment with ID id.
+func (m *Manager) changeExperiment(ctx context.Context, actor string, action AuditAction, id string, change func(*auroratype.Experiment) (*auroratype.Experiment, error)) (AuditEntry, error) {
+	if id == "" {
+		return AuditEntry{}, errors.New("aurora: experiment ID is required")
+	}
+	return m.change(ctx, actor, action, kindExperiment, id, func(content *auroratype.SourceContent) (any, any, error) {
+		var before, after any
+		var current *auroratype.Experiment
+		i := slices.IndexFunc(content.Experiments, func(exp auroratype.Experiment) bool { return exp.ID == id })
+		if i >= 0 {
+			exp := content.Experiments[i]
+			current = &exp
+			before = exp
+		}
+		next, err := change(current)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		experiments := slices.Clone(content.Experiments)
+		switch {
+		case next == nil:
+			experiments = slices.Delete(experiments, i, i+1)
+		case i >= 0:
+			experiments[i] = *next
+			after = *next
+		default:
+			experiments = append(experiments, *next)
+			after = *next
+		}
+		content.Experiments = experiments
+		return before, after, nil
+	})
+}
+
+// change loads the source, applies mutate, and validates, stores, records
+// and syncs the result. mutate returns the definitions before and after
+// the change, nil if absent. A change that leaves the definition as it was
+// is not written. A change that is stored but cannot be recorded in the
+// audit log returns its entry with an error, so that it is never missing
+// silently.
+func (m *Manager) change(ctx context.Context, actor string, action AuditAction, kind, name string, mutate func(*auroratype.SourceContent) (any, any, error)) (entry AuditEntry, err error) {
+	defer func() {
+		status := "success"
+		switch {
+		case err == nil:
+		case isValidationError(err):
+			status = "rejected"
+		case errors.Is(err, auroratype.ErrConflict):
+			status = "conflict"
+		default:
+			status = "error"
+		}
+		m.client.recorder.Count(MetricManagementChangeTotal, 1, []string{"action:" + string(action), "kind:" + kind, "status:" + status})
+	}()
+
+	if actor == "" {
+		return AuditEntry{}, errors.New("aurora: actor is required")
+	}
+	if m.source == nil {
+		return AuditEntry{}, ErrReadOnly
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	content, err := m.source.Load(ctx)
+	if err != nil {
+		return AuditEntry{}, err
+	}
+	before, after, err := mutate(&content)
+	if err != nil {
+		return AuditEntry{}, err
+	}
+	changes, err := diffDefinitions(before, after)
+	if err != nil {
+		return AuditEntry{}, err
+	}
+	entry = AuditEntry{Actor: actor, Action: action, Kind: kind, Name: name, Changes: changes}
+	if len(changes) == 0 {
+		return entry, nil
+	}
+	if err := m.validate(ctx, content); err != nil {
+		return AuditEntry{}, err
+	}
+
+	if kind == kindParameter {
+		entry.Revision, err = m.source.StoreParameters(ctx, content.Parameters, content.ParametersRevision)
+	} else {
+		entry.Revision, err = m.source.StoreExperiments(ctx, content.Experiments, content.ExperimentsRevision)
+	}
+	if err != nil {
+		return AuditEntry{}, err
+	}
+
+	entry.Time = time.Now()
+	m.client.logger.Info("Configuration changed through the manager", "actor", actor, "action", action, "kind", kind, "name", name, "revision", entry.Revision)
+	var errs []error
+	if err := m.audit.Record(ctx, entry); err != nil {
+		m.client.logger.Error("Failed to record audit entry", "actor", actor, "action", action, "kind", kind, "name", name, "revision", entry.Revision, "error", err)
+		errs = append(errs, fmt.Errorf("aurora: change stored but not audited: %w", err))
+	}
+
+	// the source holds the change either way, so it is served
+	if err := m.client.storage.resync(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("aurora: change stored but not applied: %w", err))
+	}
+	return entry, errors.Join(errs...)
+}
+
+// validate checks content like the storage checks a fetched snapshot,
+// including every environment and the registered operators.
+func (m *Manager) validate(ctx context.Context, content auroratype.SourceContent) error {
+	storage := m.client.storage
+	plain, err := storage.decrypt(ctx, &auroratype.Snapshot{Parameters: content.Parameters, Experiments: content.Experiments})
+	if err != nil {
+		return err
+	}
+	return validateSnapshot(plain, storage.validateTypes, storage.parameterOperators, storage.experimentOperators)
+}
+
+// ManagementOptions configures the handler returned by
+// NewManagementHandler.
+type ManagementOptions struct {
+	// Prefix is the path the handler is mounted at. Defaults to
+	// "/aurora".
+	Prefix string
+	// Auth is called for every request, as in AdminOptions, and is
+	// required. To rely on authentication done by a middleware instead,
+	// set it to a function that returns nil.
+	Auth func(r *http.Request) error
+	// Actor identifies who made a request, for the audit log. Defaults to
+	// the X-Aurora-Actor header, which any client that passes Auth can set
+	// to anything; derive the actor from the authenticated identity to
+	// keep the audit log trustworthy. Changes without an actor are
+	// rejected.
+	Actor func(r *http.Request) string
+}
+
+// NewManagementHandler returns a handler exposing m over HTTP, to be
+// mounted at opts.Prefix:
+//
+//	GET    /parameters                stored parameter definitions
+//	GET    /parameters/{name}         one parameter definition
+//	POST   /parameters/{name}         create a parameter
+//	PUT    /parameters/{name}         replace a parameter
+//	DELETE /parameters/{name}         delete a parameter
+//	POST   /parameters/{name}/toggle  set a boolean default value, {"enabled": true}
+//	GET    /experiments               stored experiment definitions
+//	GET    /experiments/{id}          one experiment definition
+//	POST   /experiments               create an experiment
+//	PUT    /experiments/{id}          replace an experiment
+//	DELETE /experiments/{id}          delete an experiment
+//	POST   /experiments/{id}/status   set the status, {"status": "aborted"}
+//
+// Definitions are sent in JSON or YAML, following the Content-Type, with
+// the field names of configuration files. Changes respond with their audit
+// entry. Invalid changes are rejected with 422 Unprocessable Entity and
+// conflicting ones with 409 Conflict. It fails without opts.Auth.
+func NewManagementHandler(m *Manager, opts ManagementOptions) (http.Handler, error) {
+	if opts.Auth == nil {
+		return nil, errors.New("aurora: the management handler requires Auth")
+	}
+	prefix := strings.TrimSuffix(opts.Prefix, "/")
+	if prefix == "" {
+		prefix = "/aurora"
+	}
+	actor := opts.Actor
+	if actor == nil {
+		actor = func(r *http.Request) string { return r.Header.Get("X-Aurora-Actor") }
+	}
+	h := &managementHandler{manager: m, actor: actor}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+prefix+"/parameters", h.serveParameters)
+	mux.HandleFunc("GET "+prefix+"/parameters/{name}", h.serveParameter)
+	mux.HandleFunc("POST "+prefix+"/parameters/{name}", h.changeParameter(m.CreateParameter))
+	mux.HandleFunc("PUT "+prefix+"/parameters/{name}", h.changeParameter(m.UpdateParameter))
+	mux.HandleFunc("DELETE "+prefix+"/parameters/{name}", h.deleteParameter)
+	mux.HandleFunc("POST "+prefix+"/parameters/{name}/toggle", h.toggleParameter)
+	mux.HandleFunc("GET "+prefix+"/experiments", h.serveExperiments)
+	mux.HandleFunc("GET "+prefix+"/experiments/{id}", h.serveExperiment)
+	mux.HandleFunc("POST "+prefix+"/experiments", h.changeExperiment(m.CreateExperiment))
+	mux.HandleFunc("PUT "+prefix+"/experiments/{id}", h.changeExperiment(m.UpdateExperiment))
+	mux.HandleFunc("DELETE "+prefix+"/experiments/{id}", h.deleteExperiment)
+	mux.HandleFunc("POST "+prefix+"/experiments/{id}/status", h.setExperimentStatus)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := opts.Auth(r); err != nil {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}), nil
+}
+
+type managementHandler struct {
+	manager *Manager
+	actor   func(r *http.Request) string
+}
+
+func (h *managementHandler) serveParameters(w http.ResponseWriter, r *http.Request) {
+	content, err := h.manager.Load(r.Context())
+	if err != nil {
+		writeManagementError(w, err)
+		return
+	}
+	definitions := make(map[string]any, len(content.Parameters))
+	for name, param := range content.Parameters {
+		if definitions[name], err = definitionOf(param); err != nil {
+			writeManagementError(w, err)
+			return
+		}
+	}
+	writeAdminJSON(w, http.StatusOK, map[string]any{"revision": content.ParametersRevision, "parameters": definitions})
+}
+
+func (h *managementHandler) serveParameter(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	content, err := h.manager.Load(r.Context())
+	if err != nil {
+		writeManagementError(w, err)
+		return
+	}
+	param, ok := content.Parameters[name]
+	if !ok {
+		writeManagementError(w, fmt.Errorf("parameter %q: %w", name, ErrNotFound))
+		return
+	}
+	h.writeDefinition(w, param)
+}
+
+func (h *managementHandler) serveExperiments(w http.ResponseWriter, r *http.Request) {
+	content, err := h.manager.Load(r.Context())
+	if err != nil {
+		writeManagementError(w, err)
+		return
+	}
+	definitions := make([]any, 0, len(content.Experiments))
+	for _, exp := range content.Experiments {
+		definition, err := definitionOf(exp)
+		if err != nil {
+			writeManagementError(w, err)
+			return
+		}
+		definitions = append(definitions, definition)
+	}
+	writeAdminJSON(w, http.StatusOK, map[string]any{"revision": content.ExperimentsRevision, "experiments": definitions})
+}
+
+func (h *managementHandler) serveExperiment(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	content, err := h.manager.Load(r.Context())
+	if err != nil {
+		writeManagementError(w, err)
+		return
+	}
+	i := slices.IndexFunc(content.Experiments, func(exp auroratype.Experiment) bool { return exp.ID == id })
+	if i < 0 {
+		writeManagementError(w, fmt.Errorf("experiment %q: %w", id, ErrNotFound))
+		return
+	}
+	h.writeDefinition(w, content.Experiments[i])
+}
+
+func (h *managementHandler) writeDefinition(w http.ResponseWriter, v any) {
+	definition, err := definitionOf(v)
+	if err != nil {
+		writeManagementError(w, err)
+		return
+	}
+	writeAdminJSON(w, http.StatusOK, definition)
+}
+
+func (h *managementHandler) changeParameter(change func(ctx context.Context, actor, name string, param auroratype.Parameter) (AuditEntry, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var param auroratype.Parameter
+		if !decodeBody(w, r, &param) {
+			return
+		}
+		actor, ok := h.actorOf(w, r)
+		if !ok {
+			return
+		}
+		entry, err := change(r.Context(), actor, r.PathValue("name"), param)
+		writeChange(w, entry, err)
+	}
+}
+
+func (h *managementHandler) deleteParameter(w http.ResponseWriter, r *http.Request) {
+	actor, ok := h.actorOf(w, r)
+	if !ok {
+		return
+	}
+	entry, err := h.manager.DeleteParameter(r.Context(), actor, r.PathValue("name"))
+	writeChange(w, entry, err)
+}
+
+func (h *managementHandler) toggleParameter(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Enabled *bool `yaml:"enabled" json:"enabled"`
+	}
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	if req.Enabled == nil {
+		http.Error(w, "enabled is required", http.StatusBadRequest)
+		return
+	}
+	actor, ok := h.actorOf(w, r)
+	if !ok {
+		return
+	}
+	entry, err := h.manager.ToggleParameter(r.Context(), actor, r.PathValue("name"), *req.Enabled)
+	writeChange(w, entry, err)
+}
+
+func (h *managementHandler) changeExperiment(change func(ctx context.Context, actor string, exp auroratype.Experiment) (AuditEntry, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var exp auroratype.Experiment
+		if !decodeBody(w, r, &exp) {
+			return
+		}
+		if id := r.PathValue("id"); id != "" {
+			if exp.ID != "" && exp.ID != id {
+				http.Error(w, fmt.Sprintf("experiment ID %q does not match the path", exp.ID), http.StatusBadRequest)
+				return
+			}
+			exp.ID = id
+		}
+		if exp.ID == "" {
+			http.Error(w, "id is required", http.StatusBadRequest)
+			return
+		}
+		actor, ok := h.actorOf(w, r)
+		if !ok {
+			return
+		}
+		entry, err := change(r.Context(), actor, exp)
+		writeChange(w, entry, err)
+	}
+}
+
+func (h *managementHandler) deleteExperiment(w http.ResponseWriter, r *http.Request) {
+	actor, ok := h.actorOf(w, r)
+	if !ok {
+		return
+	}
+	entry, err := h.manager.DeleteExperiment(r.Context(), actor, r.PathValue("id"))
+	writeChange(w, entry, err)
+}
+
+func (h *managementHandler) setExperimentStatus(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Status auroratype.ExperimentStatus `yaml:"status" json:"status"`
+	}
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	if req.Status == "" {
+		http.Error(w, "status is required", http.StatusBadRequest)
+		return
+	}
+	actor, ok := h.actorOf(w, r)
+	if !ok {
+		return
+	}
+	entry, err := h.manager.SetExperimentStatus(r.Context(), actor, r.PathValue("id"), req.Status)
+	writeChange(w, entry, err)
+}
+
+// actorOf returns who made r, writing an error response if it is unknown.
+func (h *managementHandler) actorOf(w http.ResponseWriter, r *http.Request) (string, bool) {
+	actor := h.actor(r)
+	if actor == "" {
+		http.Error(w, "actor is required", http.StatusBadRequest)
+		return "", false
+	}
+	return actor, true
+}
+
+// decodeBody decodes the request body into v with the codec for its
+// Content-Type, writing an error response if it cannot.
+func decodeBody(w http.ResponseWriter, r *http.Request, v any) bool {
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, 1<<20))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+	if len(strings.TrimSpace(string(data))) == 0 {
+		http.Error(w, "request body is required", http.StatusBadRequest)
+		return false
+	}
+	format := auroratype.FormatOf("", r.Header.Get("Content-Type"), data)
+	if err := auroratype.Decode(data, format, v); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+func writeChange(w http.ResponseWriter, entry AuditEntry, err error) {
+	if err != nil {
+		writeManagementError(w, err)
+		return
+	}
+	status := http.StatusOK
+	if entry.Action == AuditCreate {
+		status = http.StatusCreated
+	}
+	writeAdminJSON(w, status, entry)
+}
+
+func writeManagementError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case isValidationError(err):
+		status = http.StatusUnprocessableEntity
+	case errors.Is(err, ErrNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, ErrExists), errors.Is(err, auroratype.ErrConflict):
+		status = http.StatusConflict
+	case errors.Is(err, ErrReadOnly):
+		status = http.StatusNotImplemented
+	}
+	http.Error(w, err.Error(), status)
+}
@@ -0,0 +1,406 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tuannguyensn2001/aurora-go/auroratype"
+	"github.com/tuannguyensn2001/aurora-go/experiment"
+)
+
+// memorySource is a Fetcher and WritableSource holding the configuration
+// in memory. Revisions count the writes of each part.
+type memorySource struct {
+	mu                  sync.Mutex
+	parameters          map[string]auroratype.Parameter
+	experiments         []auroratype.Experiment
+	parametersRevision  int
+	experimentsRevision int
+}
+
+func (s *memorySource) Fetch(ctx context.Context) (map[string]auroratype.Parameter, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return maps.Clone(s.parameters), nil
+}
+
+func (s *memorySource) FetchExperiments(ctx context.Context) ([]auroratype.Experiment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]auroratype.Experiment{}, s.experiments...), nil
+}
+
+func (s *memorySource) IsStatic() bool {
+	return true
+}
+
+func (s *memorySource) Load(ctx context.Context) (auroratype.SourceContent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return auroratype.SourceContent{
+		Parameters:          maps.Clone(s.parameters),
+		Experiments:         slices.Clone(s.experiments),
+		ParametersRevision:  strconv.Itoa(s.parametersRevision),
+		ExperimentsRevision: strconv.Itoa(s.experimentsRevision),
+	}, nil
+}
+
+func (s *memorySource) StoreParameters(ctx context.Context, parameters map[string]auroratype.Parameter, revision string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if revision != strconv.Itoa(s.parametersRevision) {
+		return "", auroratype.ErrConflict
+	}
+	s.parameters = parameters
+	s.parametersRevision++
+	return strconv.Itoa(s.parametersRevision), nil
+}
+
+func (s *memorySource) StoreExperiments(ctx context.Context, experiments []auroratype.Experiment, revision string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if revision != strconv.Itoa(s.experimentsRevision) {
+		return "", auroratype.ErrConflict
+	}
+	s.experiments = experiments
+	s.experimentsRevision++
+	return strconv.Itoa(s.experimentsRevision), nil
+}
+
+func newManagedClient(t *testing.T) (*Client, *memorySource) {
+	t.Helper()
+	source := &memorySource{
+		parameters: map[string]auroratype.Parameter{
+			"checkoutEnabled": {DefaultValue: true},
+			"limit": {
+				DefaultValue: 10,
+				Rules: []auroratype.Rule{{
+					RolloutValue: 100,
+					Constraints:  []auroratype.Constraint{{Field: "plan", Operator: "equal", Value: "pro"}},
+				}},
+			},
+			"theme": {DefaultValue: "light"},
+		},
+		experiments: []auroratype.Experiment{{
+			ID:             "exp-1",
+			Name:           "Theme",
+			Parameters:     []string{"theme"},
+			Status:         auroratype.StatusRunning,
+			HashAttribute:  "user_id",
+			PopulationSize: 100,
+			Variants: []auroratype.Variant{{
+				Key:     "dark",
+				Rollout: 100,
+				Values:  map[string]interface{}{"theme": "dark"},
+			}},
+		}},
+	}
+	client := NewClient(NewFetcherStorage(source, WithTypeValidation(true)), ClientOptions{})
+	require.NoError(t, client.Start(context.Background()))
+	return client, source
+}
+
+func readAudit(t *testing.T, log *bytes.Buffer) []AuditEntry {
+	t.Helper()
+	var entries []AuditEntry
+	scanner := bufio.NewScanner(log)
+	for scanner.Scan() {
+		var entry AuditEntry
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &entry))
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestManagerParameters(t *testing.T) {
+	ctx := context.Background()
+	client, source := newManagedClient(t)
+	var log bytes.Buffer
+	manager := NewManager(client, ManagerOptions{AuditLog: NewJSONLAuditLog(&log)})
+
+	entry, err := manager.ToggleParameter(ctx, "alice", "checkoutEnabled", false)
+	require.NoError(t, err)
+	assert.Equal(t, "1", entry.Revision)
+	assert.Equal(t, []FieldChange{{Path: "defaultValue", Before: true, After: false}}, entry.Changes)
+	assert.Equal(t, false, client.GetParameter(ctx, "checkoutEnabled", NewAttribute()).Value(), "a toggle must be served right away")
+	assert.Equal(t, false, source.parameters["checkoutEnabled"].DefaultValue)
+
+	_, err = manager.CreateParameter(ctx, "bob", "maxItems", auroratype.Parameter{DefaultValue: 5})
+	require.NoError(t, err)
+	assert.Equal(t, 5, client.GetParameter(ctx, "maxItems", NewAttribute()).Value())
+
+	entry, err = manager.UpdateParameter(ctx, "bob", "limit", auroratype.Parameter{
+		DefaultValue: 10,
+		Rules: []auroratype.Rule{{
+			RolloutValue: 200,
+			Constraints:  []auroratype.Constraint{{Field: "plan", Operator: "equal", Value: "pro"}},
+		}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []FieldChange{{Path: "rules[0].rolloutValue", Before: 100, After: 200}}, entry.Changes)
+	pro := NewAttribute()
+	pro.Set("plan", "pro")
+	assert.Equal(t, 200, client.GetParameter(ctx, "limit", pro).Int(0))
+
+	_, err = manager.DeleteParameter(ctx, "carol", "maxItems")
+	require.NoError(t, err)
+	assert.Equal(t, ReasonNotFound, client.GetParameter(ctx, "maxItems", NewAttribute()).Details().Reason)
+
+	// an unchanged definition is neither written nor audited
+	entry, err = manager.ToggleParameter(ctx, "carol", "checkoutEnabled", false)
+	require.NoError(t, err)
+	assert.Empty(t, entry.Changes)
+	assert.Equal(t, 4, source.parametersRevision)
+
+	entries := readAudit(t, &log)
+	require.Len(t, entries, 4)
+	assert.Equal(t, "alice", entries[0].Actor)
+	assert.Equal(t, AuditToggle, entries[0].Action)
+	assert.Equal(t, "parameter", entries[0].Kind)
+	assert.Equal(t, "checkoutEnabled", entries[0].Name)
+	assert.False(t, entries[0].Time.IsZero())
+	assert.Equal(t, AuditCreate, entries[1].Action)
+	assert.Equal(t, map[string]any{"defaultValue": float64(5), "rules": []any{}}, entries[1].Changes[0].After)
+	assert.Equal(t, AuditDelete, entries[3].Action)
+	assert.Nil(t, entries[3].Changes[0].After)
+}
+
+func TestManagerRejectsChanges(t *testing.T) {
+	ctx := context.Background()
+	client, source := newManagedClient(t)
+	var log bytes.Buffer
+	manager := NewManager(client, ManagerOptions{AuditLog: NewJSONLAuditLog(&log)})
+
+	_, err := manager.UpdateParameter(ctx, "alice", "limit", auroratype.Parameter{
+		DefaultValue: 10,
+		Rules:        []auroratype.Rule{{RolloutValue: 1, Constraints: []auroratype.Constraint{{Field: "plan", Operator: "resembles", Value: "pro"}}}},
+	})
+	var validationErrors auroratype.ValidationErrors
+	require.ErrorAs(t, err, &validationErrors)
+	assert.Contains(t, err.Error(), `unknown operator "resembles"`)
+
+	_, err = manager.ToggleParameter(ctx, "alice", "limit", true)
+	assert.ErrorAs(t, err, &validationErrors)
+
+	// the variants of the experiment serve strings
+	_, err = manager.UpdateParameter(ctx, "alice", "theme", auroratype.Parameter{DefaultValue: 5})
+	var expErrors experiment.ValidationErrors
+	assert.ErrorAs(t, err, &expErrors)
+
+	_, err = manager.CreateParameter(ctx, "alice", "limit", auroratype.Parameter{DefaultValue: 1})
+	assert.ErrorIs(t, err, ErrExists)
+	_, err = manager.UpdateParameter(ctx, "alice", "missing", auroratype.Parameter{DefaultValue: 1})
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = manager.ToggleParameter(ctx, "", "checkoutEnabled", false)
+	assert.Error(t, err)
+
+	assert.Equal(t, 0, source.parametersRevision)
+	assert.Empty(t, log.String())
+	pro := NewAttribute()
+	pro.Set("plan", "pro")
+	assert.Equal(t, 100, client.GetParameter(ctx, "limit", pro).Int(0))
+}
+
+func TestManagerConflict(t *testing.T) {
+	ctx := context.Background()
+	client, source := newManagedClient(t)
+	manager := NewManager(client, ManagerOptions{Source: &conflictingSource{memorySource: source}})
+
+	_, err := manager.ToggleParameter(ctx, "alice", "checkoutEnabled", false)
+	assert.ErrorIs(t, err, auroratype.ErrConflict)
+	assert.Equal(t, true, client.GetParameter(ctx, "checkoutEnabled", NewAttribute()).Value())
+}
+
+// conflictingSource is changed by someone else between every Load and
+// write.
+type conflictingSource struct {
+	*memorySource
+}
+
+func (s *conflictingSource) Load(ctx context.Context) (auroratype.SourceContent, error) {
+	content, err := s.memorySource.Load(ctx)
+	s.mu.Lock()
+	s.parametersRevision++
+	s.mu.Unlock()
+	return content, err
+}
+
+// failingAuditLog rejects every entry.
+type failingAuditLog struct{}
+
+func (failingAuditLog) Record(ctx context.Context, entry AuditEntry) error {
+	return assert.AnError
+}
+
+func TestManagerAuditFailure(t *testing.T) {
+	ctx := context.Background()
+	client, source := newManagedClient(t)
+	manager := NewManager(client, ManagerOptions{AuditLog: failingAuditLog{}})
+
+	entry, err := manager.ToggleParameter(ctx, "alice", "checkoutEnabled", false)
+	require.ErrorIs(t, err, assert.AnError, "a change missing from the audit log must not succeed silently")
+	assert.Contains(t, err.Error(), "not audited")
+	assert.Equal(t, "1", entry.Revision)
+	assert.Equal(t, 1, source.parametersRevision)
+	assert.Equal(t, false, client.GetParameter(ctx, "checkoutEnabled", NewAttribute()).Value(), "the stored change is served")
+
+	handler, err := NewManagementHandler(manager, ManagementOptions{Auth: AdminBearerToken("secret")})
+	require.NoError(t, err)
+	rec := sendManagement(t, handler, http.MethodPost, "/aurora/parameters/checkoutEnabled/toggle", "", `{"enabled": true}`)
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+}
+
+func TestManagerExperiments(t *testing.T) {
+	ctx := context.Background()
+	client, _ := newManagedClient(t)
+	manager := NewManager(client, ManagerOptions{})
+	user := NewAttribute()
+	user.Set("user_id", 7)
+	require.Equal(t, "dark", client.GetParameter(ctx, "theme", user).String(""))
+
+	entry, err := manager.SetExperimentStatus(ctx, "alice", "exp-1", auroratype.StatusAborted)
+	require.NoError(t, err)
+	assert.Equal(t, "experiment", entry.Kind)
+	assert.Equal(t, []FieldChange{{Path: "status", Before: "running", After: "aborted"}}, entry.Changes)
+	assert.Equal(t, "light", client.GetParameter(ctx, "theme", user).Value(), "an aborted experiment must stop assigning right away")
+
+	invalid := auroratype.Experiment{
+		ID:             "exp-2",
+		Name:           "Limit",
+		Parameters:     []string{"limit"},
+		Status:         auroratype.StatusRunning,
+		HashAttribute:  "user_id",
+		PopulationSize: 100,
+		Variants:       []auroratype.Variant{{Key: "many", Rollout: 100, Values: map[string]interface{}{"limit": "many"}}},
+	}
+	_, err = manager.CreateExperiment(ctx, "alice", invalid)
+	var expErrors experiment.ValidationErrors
+	require.ErrorAs(t, err, &expErrors)
+	assert.Contains(t, err.Error(), "variants[0].values.limit")
+	invalid.Variants[0].Values["limit"] = 50
+	_, err = manager.CreateExperiment(ctx, "alice", invalid)
+	require.NoError(t, err)
+	_, err = manager.CreateExperiment(ctx, "alice", invalid)
+	assert.ErrorIs(t, err, ErrExists)
+
+	_, err = manager.DeleteExperiment(ctx, "alice", "exp-1")
+	require.NoError(t, err)
+	experiments, err := client.storage.GetExperiments(ctx)
+	require.NoError(t, err)
+	require.Len(t, experiments, 1)
+	assert.Equal(t, "exp-2", experiments[0].ID)
+
+	_, err = manager.SetExperimentStatus(ctx, "alice", "exp-1", auroratype.StatusRunning)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestManagerReadOnly(t *testing.T) {
+	client := newAdminClient(t)
+	_, err := NewManager(client, ManagerOptions{}).ToggleParameter(context.Background(), "alice", "limit", true)
+	assert.ErrorIs(t, err, ErrReadOnly)
+}
+
+func sendManagement(t *testing.T, handler http.Handler, method, path, contentType, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer secret")
+	req.Header.Set("X-Aurora-Actor", "alice")
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestManagementHandler(t *testing.T) {
+	ctx := context.Background()
+	client, _ := newManagedClient(t)
+	manager := NewManager(client, ManagerOptions{})
+	_, err := NewManagementHandler(manager, ManagementOptions{})
+	assert.Error(t, err, "the handler must not be built without Auth")
+	handler, err := NewManagementHandler(manager, ManagementOptions{Auth: AdminBearerToken("secret")})
+	require.NoError(t, err)
+
+	rec := sendManagement(t, handler, http.MethodPost, "/aurora/parameters/banner", "application/yaml", "defaultValue: hello\n")
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	var entry AuditEntry
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &entry))
+	assert.Equal(t, "alice", entry.Actor)
+	assert.Equal(t, "hello", client.GetParameter(ctx, "banner", NewAttribute()).Value())
+
+	rec = sendManagement(t, handler, http.MethodPost, "/aurora/parameters/checkoutEnabled/toggle", "", `{"enabled": false}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, false, client.GetParameter(ctx, "checkoutEnabled", NewAttribute()).Value())
+
+	rec = sendManagement(t, handler, http.MethodGet, "/aurora/parameters/checkoutEnabled", "", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"defaultValue": false, "rules": []}`, rec.Body.String())
+
+	rec = sendManagement(t, handler, http.MethodPut, "/aurora/parameters/limit", "", `{"defaultValue": 10, "rules": [{"rolloutValue": 1, "constraints": [{"field": "plan", "operator": "resembles", "value": "pro"}]}]}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	rec = sendManagement(t, handler, http.MethodPost, "/aurora/parameters/limit", "", `{"defaultValue": 1}`)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	rec = sendManagement(t, handler, http.MethodDelete, "/aurora/parameters/missing", "", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = sendManagement(t, handler, http.MethodPost, "/aurora/experiments/exp-1/status", "", `{"status": "aborted"}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	rec = sendManagement(t, handler, http.MethodGet, "/aurora/experiments", "", "")
+	var experiments struct {
+		Revision    string           `json:"revision"`
+		Experiments []map[string]any `json:"experiments"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &experiments))
+	assert.Equal(t, "1", experiments.Revision)
+	assert.Equal(t, "aborted", experiments.Experiments[0]["status"])
+
+	rec = sendManagement(t, handler, http.MethodPut, "/aurora/experiments/exp-1", "", `{"id": "exp-2"}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	req := httptest.NewRequest(http.MethodDelete, "/aurora/parameters/banner", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusBadRequest, rec.Code, "changes without an actor must be rejected")
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/aurora/parameters", nil))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestDiffDefinitions(t *testing.T) {
+	before := auroratype.Parameter{
+		DefaultValue: 1,
+		Rules:        []auroratype.Rule{{RolloutValue: 2}, {RolloutValue: 3}},
+	}
+	after := auroratype.Parameter{
+		DefaultValue: 1,
+		Rules:        []auroratype.Rule{{RolloutValue: 5}},
+	}
+	changes, err := diffDefinitions(before, after)
+	require.NoError(t, err)
+	assert.Equal(t, []FieldChange{
+		{Path: "rules[0].rolloutValue", Before: 2, After: 5},
+		{Path: "rules[1]", Before: map[string]any{"rolloutValue": 3, "constraints": []any{}}},
+	}, changes)
+
+	changes, err = diffDefinitions(nil, after)
+	require.NoError(t, err)
+	require.Len(t, changes, 1)
+	assert.Empty(t, changes[0].Path)
+	assert.Nil(t, changes[0].Before)
+}
@@ -58,4 +58,23 @@ const (
 	MetricStorageSaveLatency = "storage_save_latency"
 	MetricStorageGetLatency  = "storage_get_latency"
 	MetricStorageGetTotal    = "storage_get_total"
+
+	MetricSnapshotRejectedTotal = "snapshot_rejected_total"
+
+	MetricFetchRetryTotal               = "fetch_retry_total"
+	MetricFetchTimeoutTotal             = "fetch_timeout_total"
+	MetricCircuitBreakerTransitionTotal = "circuit_breaker_transition_total"
+	MetricCircuitBreakerRejectedTotal   = "circuit_breaker_rejected_total"
+
+	MetricCacheAge        = "cache_age_seconds"
+	MetricCacheLoadTotal  = "cache_load_total"
+	MetricCacheWriteTotal = "cache_write_total"
+
+	MetricEventEmitTotal    = "event_emit_total"
+	MetricBanditUpdateTotal = "bandit_update_total"
+
+	MetricExperimentTransitionTotal = "experiment_transition_total"
+	MetricExperimentMissingValue    = "experiment_missing_value"
+
+	MetricManagementChangeTotal = "management_change_total"
 )
@@ -0,0 +1,190 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+var ErrCircuitOpen = errors.New("aurora: circuit breaker is open")
+
+const defaultJitter = 0.1
+
+type RetryOptions struct {
+	// MaxAttempts is the number of attempts made by the initial sync in
+	// Start. Defaults to 1, which disables retries.
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry. Defaults to 100ms.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the exponentially growing delay. Defaults to 10s.
+	MaxBackoff time.Duration
+}
+
+type CircuitBreakerOptions struct {
+	// FailureThreshold opens the breaker after this many consecutive failed
+	// fetches. Zero disables the breaker.
+	FailureThreshold int
+	// OpenDuration is how long the breaker rejects fetches before letting a
+	// single trial fetch through. Defaults to 30s.
+	OpenDuration time.Duration
+}
+
+// WithRetry retries the initial sync with exponential backoff and jitter.
+func WithRetry(retry RetryOptions) func(opts *fetcherStorage) {
+	return func(opts *fetcherStorage) {
+		opts.retry = retry
+	}
+}
+
+// WithJitter randomizes every poll interval by up to fraction of the
+// interval in either direction, so that a fleet started together does not
+// poll in lockstep. Defaults to 0.1; zero disables jitter.
+func WithJitter(fraction float64) func(opts *fetcherStorage) {
+	return func(opts *fetcherStorage) {
+		opts.jitter = fraction
+	}
+}
+
+// WithCircuitBreaker stops calling the fetcher after repeated failures and
+// only probes it again once the open duration has passed.
+func WithCircuitBreaker(breaker CircuitBreakerOptions) func(opts *fetcherStorage) {
+	return func(opts *fetcherStorage) {
+		opts.breaker = newCircuitBreaker(breaker)
+	}
+}
+
+// WithFetchTimeout bounds each call to Fetch and FetchExperiments.
+func WithFetchTimeout(timeout time.Duration) func(opts *fetcherStorage) {
+	return func(opts *fetcherStorage) {
+		opts.fetchTimeout = timeout
+	}
+}
+
+// jittered returns d randomized uniformly within ±fraction of d.
+func jittered(d time.Duration, fraction float64) time.Duration {
+	if fraction <= 0 || d <= 0 {
+		return d
+	}
+	if fraction > 1 {
+		fraction = 1
+	}
+	delta := (rand.Float64()*2 - 1) * fraction * float64(d)
+	return d + time.Duration(delta)
+}
+
+// backoff returns the delay before retry number attempt, starting at 1. The
+// delay doubles every attempt up to the maximum and is drawn from the upper
+// half of that range.
+func (r RetryOptions) backoff(attempt int) time.Duration {
+	initial := r.InitialBackoff
+	if initial <= 0 {
+		initial = 100 * time.Millisecond
+	}
+	maximum := r.MaxBackoff
+	if maximum <= 0 {
+		maximum = 10 * time.Second
+	}
+
+	d := initial
+	for i := 1; i < attempt && d < maximum; i++ {
+		d *= 2
+	}
+	if d > maximum {
+		d = maximum
+	}
+	return d/2 + time.Duration(rand.Int64N(int64(d/2)+1))
+}
+
+// callWithTimeout runs fn with a context bounded by timeout, if any.
+func callWithTimeout[T any](ctx context.Context, timeout time.Duration, fn func(context.Context) (T, error)) (T, error) {
+	if timeout <= 0 {
+		return fn(ctx)
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	return fn(ctx)
+}
+
+type breakerState string
+
+const (
+	breakerClosed   breakerState = "closed"
+	breakerOpen     breakerState = "open"
+	breakerHalfOpen breakerState = "half_open"
+)
+
+type circuitBreaker struct {
+	threshold int
+	openFor   time.Duration
+
+	mu        sync.Mutex
+	state     breakerState
+	failures  int
+	openUntil time.Time
+}
+
+func newCircuitBreaker(opts CircuitBreakerOptions) *circuitBreaker {
+	if opts.FailureThreshold <= 0 {
+		return nil
+	}
+	openFor := opts.OpenDuration
+	if openFor <= 0 {
+		openFor = 30 * time.Second
+	}
+	return &circuitBreaker{
+		threshold: opts.FailureThreshold,
+		openFor:   openFor,
+		state:     breakerClosed,
+	}
+}
+
+// allow reports whether a fetch may be attempted at now. An open breaker
+// whose open duration has passed becomes half-open and lets one fetch
+// through.
+func (b *circuitBreaker) allow(now time.Time) (bool, breakerState) {
+	if b == nil {
+		return true, ""
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state != breakerOpen {
+		return true, ""
+	}
+	if now.Before(b.openUntil) {
+		return false, ""
+	}
+	b.state = breakerHalfOpen
+	return true, breakerHalfOpen
+}
+
+// record updates the breaker with the outcome of a fetch and returns the new
+// state if it changed.
+func (b *circuitBreaker) record(err error, now time.Time) breakerState {
+	if b == nil {
+		return ""
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err == nil {
+		b.failures = 0
+		if b.state != breakerClosed {
+			b.state = breakerClosed
+			return breakerClosed
+		}
+		return ""
+	}
+
+	b.failures++
+	if b.state == breakerHalfOpen || b.failures >= b.threshold {
+		b.openUntil = now.Add(b.openFor)
+		if b.state != breakerOpen {
+			b.state = breakerOpen
+			return breakerOpen
+		}
+	}
+	return ""
+}
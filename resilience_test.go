@@ -0,0 +1,122 @@
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tuannguyensn2001/aurora-go/auroratype"
+	mocks "github.com/tuannguyensn2001/aurora-go/mocks"
+)
+
+func TestJittered(t *testing.T) {
+	for i := 0; i < 1000; i++ {
+		d := jittered(time.Minute, 0.1)
+		assert.GreaterOrEqual(t, d, 54*time.Second)
+		assert.LessOrEqual(t, d, 66*time.Second)
+	}
+	assert.Equal(t, time.Minute, jittered(time.Minute, 0))
+}
+
+func TestRetryBackoff(t *testing.T) {
+	retry := RetryOptions{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+
+	for i := 0; i < 100; i++ {
+		d := retry.backoff(1)
+		assert.GreaterOrEqual(t, d, 50*time.Millisecond)
+		assert.LessOrEqual(t, d, 100*time.Millisecond)
+
+		d = retry.backoff(3)
+		assert.GreaterOrEqual(t, d, 200*time.Millisecond)
+		assert.LessOrEqual(t, d, 400*time.Millisecond)
+
+		d = retry.backoff(20)
+		assert.GreaterOrEqual(t, d, 500*time.Millisecond)
+		assert.LessOrEqual(t, d, time.Second)
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Now()
+	b := newCircuitBreaker(CircuitBreakerOptions{FailureThreshold: 2, OpenDuration: time.Minute})
+
+	allowed, _ := b.allow(now)
+	assert.True(t, allowed)
+	assert.Empty(t, b.record(assert.AnError, now))
+	assert.Equal(t, breakerOpen, b.record(assert.AnError, now))
+
+	allowed, _ = b.allow(now.Add(30 * time.Second))
+	assert.False(t, allowed)
+
+	allowed, state := b.allow(now.Add(time.Minute))
+	assert.True(t, allowed)
+	assert.Equal(t, breakerHalfOpen, state)
+	assert.Equal(t, breakerOpen, b.record(assert.AnError, now.Add(time.Minute)), "a failed trial reopens the breaker")
+
+	allowed, _ = b.allow(now.Add(90 * time.Second))
+	assert.False(t, allowed)
+
+	allowed, _ = b.allow(now.Add(2 * time.Minute))
+	assert.True(t, allowed)
+	assert.Equal(t, breakerClosed, b.record(nil, now.Add(2*time.Minute)))
+
+	assert.Nil(t, newCircuitBreaker(CircuitBreakerOptions{}))
+}
+
+func TestFetcherStorageRetriesInitialSync(t *testing.T) {
+	ctx := context.Background()
+	config := map[string]auroratype.Parameter{"limit": {DefaultValue: 1}}
+
+	mockFetcher := new(mocks.MockFetcher)
+	mockFetcher.On("IsStatic").Return(true)
+	mockFetcher.On("Fetch", ctx).Return(nil, assert.AnError).Twice()
+	mockFetcher.On("Fetch", ctx).Return(config, nil).Once()
+	mockFetcher.On("FetchExperiments", ctx).Return(nil, nil)
+
+	s := NewFetcherStorage(mockFetcher, WithRetry(RetryOptions{MaxAttempts: 3, InitialBackoff: time.Millisecond}))
+	require.NoError(t, s.Start(ctx))
+	mockFetcher.AssertNumberOfCalls(t, "Fetch", 3)
+
+	param, err := s.Get(ctx, "limit")
+	require.NoError(t, err)
+	assert.Equal(t, 1, param.DefaultValue)
+}
+
+func TestFetcherStorageCircuitBreaker(t *testing.T) {
+	ctx := context.Background()
+
+	mockFetcher := new(mocks.MockFetcher)
+	mockFetcher.On("IsStatic").Return(true)
+	mockFetcher.On("Fetch", ctx).Return(nil, assert.AnError)
+
+	s := NewFetcherStorage(mockFetcher,
+		WithValidation(false),
+		WithRetry(RetryOptions{MaxAttempts: 5, InitialBackoff: time.Millisecond}),
+		WithCircuitBreaker(CircuitBreakerOptions{FailureThreshold: 2, OpenDuration: time.Hour}),
+	)
+	require.NoError(t, s.Start(ctx))
+	mockFetcher.AssertNumberOfCalls(t, "Fetch", 2)
+
+	assert.ErrorIs(t, s.refresh(ctx), ErrCircuitOpen)
+	mockFetcher.AssertNumberOfCalls(t, "Fetch", 2)
+	assert.Equal(t, 4, s.Status().ConsecutiveFailures, "rejected attempts count as failures")
+}
+
+func TestFetcherStorageFetchTimeout(t *testing.T) {
+	ctx := context.Background()
+
+	mockFetcher := new(mocks.MockFetcher)
+	mockFetcher.On("IsStatic").Return(true)
+	mockFetcher.On("Fetch", mock.Anything).Return(nil, context.DeadlineExceeded).Run(func(args mock.Arguments) {
+		<-args.Get(0).(context.Context).Done()
+	})
+
+	s := NewFetcherStorage(mockFetcher, WithFetchTimeout(10*time.Millisecond))
+	start := time.Now()
+	assert.ErrorIs(t, s.Start(ctx), context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), time.Second)
+}
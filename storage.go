@@ -2,7 +2,13 @@ package core
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log/slog"
+	"maps"
+	"slices"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/tuannguyensn2001/aurora-go/auroratype"
@@ -10,12 +16,50 @@ import (
 )
 
 type fetcherStorage struct {
+	snapshot atomic.Pointer[auroratype.Snapshot]
+	// fetchedSnapshot is the served snapshot as fetched, with encrypted
+	// values still encrypted.
+	fetchedSnapshot atomic.Pointer[auroratype.Snapshot]
 	fetcher         Fetcher
 	interval        time.Duration
 	strategy        Storage
 	recorder        MetricsRecorder
 	validateOnStart bool
 	logger          *slog.Logger
+	cache           CacheOptions
+	stale           atomic.Bool
+	notifier        *changeNotifier
+
+	retry        RetryOptions
+	jitter       float64
+	breaker      *circuitBreaker
+	fetchTimeout time.Duration
+
+	validateSnapshots   bool
+	validateTypes       bool
+	parameterOperators  operatorSet
+	experimentOperators operatorSet
+	// environment selects the environment sections applied to each
+	// snapshot. Set by NewClient.
+	environment string
+	keyProvider KeyProvider
+
+	mu       sync.Mutex
+	inflight *syncCall
+	cancel   context.CancelFunc
+	closed   bool
+	wg       sync.WaitGroup
+
+	// fetched is only accessed by sync, which refresh serializes, and by
+	// loadCache before polling starts.
+	fetched *fetchedContent
+
+	statusMu    sync.Mutex
+	lastSync    SyncResult
+	lastSuccess time.Time
+	lastFailure time.Time
+	lastErr     error
+	failures    int
 }
 
 func WithStorage(strategy Storage) func(s *fetcherStorage) {
@@ -42,20 +86,49 @@ func WithValidation(enabled bool) func(opts *fetcherStorage) {
 	}
 }
 
+// WithSnapshotValidation controls whether every fetched snapshot is
+// validated before it is applied. Enabled by default.
+func WithSnapshotValidation(enabled bool) func(opts *fetcherStorage) {
+	return func(opts *fetcherStorage) {
+		opts.validateSnapshots = enabled
+	}
+}
+
+// WithTypeValidation makes snapshot validation also reject experiments whose
+// variant values differ in kind from the default value of the parameter they
+// override. Disabled by default, since such values are served as is.
+func WithTypeValidation(enabled bool) func(opts *fetcherStorage) {
+	return func(opts *fetcherStorage) {
+		opts.validateTypes = enabled
+	}
+}
+
 func WithLogger(logger *slog.Logger) func(opts *fetcherStorage) {
 	return func(opts *fetcherStorage) {
 		opts.logger = logger
 	}
 }
 
+// WithCache persists every successful snapshot to a local file and serves
+// it when the fetcher is unreachable at startup. The storage is stale until
+// a fresh sync succeeds.
+func WithCache(cache CacheOptions) func(opts *fetcherStorage) {
+	return func(opts *fetcherStorage) {
+		opts.cache = cache
+	}
+}
+
 func NewFetcherStorage(fetcher Fetcher, opts ...func(opts *fetcherStorage)) *fetcherStorage {
 	storage := &fetcherStorage{
-		fetcher:         fetcher,
-		interval:        1 * time.Minute,
-		strategy:        memory.NewStorage(),
-		recorder:        NewNoopRecorder(),
-		validateOnStart: true,
-		logger:          slog.Default(),
+		fetcher:           fetcher,
+		interval:          1 * time.Minute,
+		strategy:          memory.NewStorage(),
+		recorder:          NewNoopRecorder(),
+		validateOnStart:   true,
+		validateSnapshots: true,
+		jitter:            defaultJitter,
+		logger:            slog.Default(),
+		notifier:          newChangeNotifier(),
 	}
 
 	for _, opt := range opts {
@@ -66,63 +139,529 @@ func NewFetcherStorage(fetcher Fetcher, opts ...func(opts *fetcherStorage)) *fet
 }
 
 func (w *fetcherStorage) Start(ctx context.Context) error {
-	if err := w.sync(ctx); err != nil {
-		if w.validateOnStart {
+	if err := w.initialSync(ctx); err != nil {
+		if errors.Is(err, ErrClosed) {
 			return err
 		}
-		w.logger.Warn("Initial sync failed, continuing", "error", err)
+		if w.loadCache(ctx) {
+			w.logger.Warn("Initial sync failed, serving cached snapshot", "error", err, "path", w.cache.Path)
+		} else if w.validateOnStart {
+			return err
+		} else {
+			w.logger.Warn("Initial sync failed, continuing", "error", err)
+		}
 	}
 
-	if w.fetcher.IsStatic() {
+	// a static fetcher is only polled until it replaces a cached snapshot
+	watcher, watching := w.fetcher.(Watcher)
+	if w.fetcher.IsStatic() && !w.stale.Load() && !watching {
 		return nil
 	}
 
-	go w.poll(ctx)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return ErrClosed
+	}
+	ctx, w.cancel = context.WithCancel(ctx)
+
+	var changes chan struct{}
+	if watching {
+		changes = make(chan struct{}, 1)
+		w.wg.Add(1)
+		go func() {
+			defer w.wg.Done()
+			defer close(changes)
+			w.watch(ctx, watcher, changes)
+		}()
+	}
+
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		w.poll(ctx, changes)
+	}()
 
 	return nil
 }
 
+// watch runs the fetcher's Watch and forwards its notifications to the poll
+// loop. Notifications arriving while a sync is pending are merged.
+func (w *fetcherStorage) watch(ctx context.Context, watcher Watcher, changes chan<- struct{}) {
+	err := watcher.Watch(ctx, func() {
+		select {
+		case changes <- struct{}{}:
+		default:
+		}
+	})
+	if err != nil && ctx.Err() == nil {
+		w.logger.Error("Watching the fetcher failed, falling back to polling", "error", err)
+	}
+}
+
+// Close stops polling and waits for the poll goroutine to exit or ctx to
+// be done. The last snapshot keeps being served.
+func (w *fetcherStorage) Close(ctx context.Context) error {
+	w.mu.Lock()
+	w.closed = true
+	if w.cancel != nil {
+		w.cancel()
+	}
+	w.mu.Unlock()
+
+	return waitContext(ctx, w.wg.Wait)
+}
+
+// initialSync syncs, retrying fetch failures with backoff as configured by
+// WithRetry. Rejected snapshots are not retried.
+func (w *fetcherStorage) initialSync(ctx context.Context) error {
+	attempts := max(w.retry.MaxAttempts, 1)
+	for attempt := 1; ; attempt++ {
+		err := w.refresh(ctx)
+		if err == nil || attempt >= attempts || errors.Is(err, ErrClosed) || errors.Is(err, ErrCircuitOpen) || isValidationError(err) {
+			return err
+		}
+
+		delay := w.retry.backoff(attempt)
+		w.logger.Warn("Initial sync failed, retrying", "attempt", attempt, "delay", delay, "error", err)
+		w.recorder.Count(MetricFetchRetryTotal, 1, nil)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// refresh runs a sync, or waits for the one already running and returns
+// its result.
+func (w *fetcherStorage) refresh(ctx context.Context) error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return ErrClosed
+	}
+	if call := w.inflight; call != nil {
+		w.mu.Unlock()
+		select {
+		case <-call.done:
+			return call.err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	call := &syncCall{done: make(chan struct{})}
+	w.inflight = call
+	w.mu.Unlock()
+
+	call.err = w.sync(ctx)
+
+	w.mu.Lock()
+	w.inflight = nil
+	w.mu.Unlock()
+	close(call.done)
+
+	return call.err
+}
+
+// resync waits for a running sync, which may have fetched before a change
+// to the source, and then syncs again.
+func (w *fetcherStorage) resync(ctx context.Context) error {
+	w.mu.Lock()
+	call := w.inflight
+	w.mu.Unlock()
+	if call != nil {
+		select {
+		case <-call.done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return w.refresh(ctx)
+}
+
+// sync fetches parameters and experiments and only then replaces the stored
+// snapshot, so a failed fetch leaves the previous configuration untouched.
 func (w *fetcherStorage) sync(ctx context.Context) error {
-	config, err := w.fetcher.Fetch(ctx)
+	allowed, state := w.breaker.allow(time.Now())
+	if !allowed {
+		w.recorder.Count(MetricCircuitBreakerRejectedTotal, 1, nil)
+		w.recordSync(SyncResult{Err: ErrCircuitOpen})
+		return ErrCircuitOpen
+	}
+	w.breakerChanged(state)
+
+	content, unchanged, err := w.fetch(ctx)
+	w.breakerChanged(w.breaker.record(err, time.Now()))
 	if err != nil {
-		w.recorder.Count(MetricStorageSyncTotal, 1, []string{"status:error"})
+		w.recordSync(SyncResult{Err: err})
 		return err
 	}
 
-	err = w.strategy.Save(ctx, config)
+	if unchanged {
+		return w.unchanged(ctx)
+	}
+
+	// validation sees every environment, the storage only the active one.
+	// The version and the cache cover the content as fetched, so that
+	// decrypted values never leave memory.
+	snapshot := auroratype.NewSnapshot(
+		auroratype.ResolveParameters(content.parameters, w.environment),
+		auroratype.ResolveExperiments(content.experiments, w.environment),
+		fmt.Sprintf("%T", w.fetcher), time.Now())
+	snapshot.Sources = content.sources
+	content.version = snapshot.Version
+	plain, err := w.decrypt(ctx, &auroratype.Snapshot{Parameters: content.parameters, Experiments: content.experiments, Sources: content.sources})
+	if err == nil {
+		err = w.validate(plain)
+	}
+	if err == nil {
+		err = w.validateEnvironments(ctx, content.environments)
+	}
 	if err != nil {
-		w.recorder.Count(MetricStorageSyncTotal, 1, []string{"status:error"})
+		content.err = err
+		w.fetched = &content
+		w.logger.Error("Rejected invalid snapshot, keeping previous configuration", "version", snapshot.Version, "error", err)
+		w.recorder.Count(MetricSnapshotRejectedTotal, 1, nil)
+		w.recordSync(SyncResult{Version: snapshot.Version, Err: err, Rejected: true})
 		return err
 	}
 
-	experiments, err := w.fetcher.FetchExperiments(ctx)
-	if err != nil {
-		w.recorder.Count(MetricStorageSyncTotal, 1, []string{"status:error"})
+	served := snapshot
+	if len(plain.Encrypted) > 0 {
+		decrypted := *snapshot
+		decrypted.Parameters = auroratype.ResolveParameters(plain.Parameters, w.environment)
+		decrypted.Experiments = auroratype.ResolveExperiments(plain.Experiments, w.environment)
+		decrypted.Encrypted = plain.Encrypted
+		served = &decrypted
+	}
+
+	if err := w.apply(ctx, served); err != nil {
+		w.recordSync(SyncResult{Version: snapshot.Version, Err: err})
 		return err
 	}
+	w.fetched = &content
+	w.fetchedSnapshot.Store(snapshot)
+
+	w.stale.Store(false)
+	w.writeCache(snapshot)
+
+	w.recordSync(SyncResult{Version: snapshot.Version})
+	return nil
+}
+
+// unchanged records a sync in which the source reported no changes. Nothing
+// is saved and no change set is published; a rejected snapshot stays
+// rejected.
+func (w *fetcherStorage) unchanged(ctx context.Context) error {
+	content := w.previousContent(ctx)
+	if content.err != nil {
+		w.recordSync(SyncResult{Version: content.version, Err: content.err, Rejected: true, NotModified: true})
+		return content.err
+	}
+
+	w.stale.Store(false)
+	w.recordSync(SyncResult{Version: content.version, NotModified: true})
+	return nil
+}
+
+// fetchedContent is what the source served most recently, whether or not
+// it was applied.
+type fetchedContent struct {
+	parameters  map[string]auroratype.Parameter
+	experiments []auroratype.Experiment
+	sources     map[string]string
+	// environments is the content resolved for every environment by a
+	// fetcher that resolves its environment itself.
+	environments map[string]auroratype.EnvironmentContent
+	// version is set once both parts have been fetched and validated.
+	version string
+	// err is the validation error if the content was rejected.
+	err error
+}
+
+// previousContent returns the last fetched content, or the snapshot being
+// served if nothing has been fetched yet.
+func (w *fetcherStorage) previousContent(ctx context.Context) fetchedContent {
+	if w.fetched != nil {
+		return *w.fetched
+	}
+	if snapshot, err := w.LoadSnapshot(ctx); err == nil && snapshot != nil {
+		return fetchedContent{parameters: snapshot.Parameters, experiments: snapshot.Experiments, sources: snapshot.Sources, version: snapshot.Version}
+	}
+	return fetchedContent{}
+}
+
+// fetch returns the parameters and experiments of the source. A part that
+// the fetcher reports as not modified, or nil experiments, is taken from
+// the previous fetch; unchanged is set when neither part changed since a
+// fully fetched snapshot. Each freshly fetched part is remembered right
+// away, since a fetcher will not return it again once it has reported it.
+// The returned content has no version yet.
+func (w *fetcherStorage) fetch(ctx context.Context) (content fetchedContent, unchanged bool, err error) {
+	previous := w.previousContent(ctx)
+
+	config, err := callWithTimeout(ctx, w.fetchTimeout, w.fetcher.Fetch)
+	parametersNotModified := errors.Is(err, auroratype.ErrNotModified)
+	if err != nil && !parametersNotModified {
+		w.recordTimeout(ctx, err)
+		return fetchedContent{}, false, err
+	}
+	if parametersNotModified {
+		content = fetchedContent{parameters: previous.parameters, sources: previous.sources}
+	} else {
+		content = fetchedContent{parameters: config}
+		if locator, ok := w.fetcher.(SourceLocator); ok {
+			content.sources = locator.ParameterSources()
+		}
+		w.fetched = &fetchedContent{parameters: config, experiments: previous.experiments, sources: content.sources}
+	}
+
+	experiments, err := callWithTimeout(ctx, w.fetchTimeout, w.fetcher.FetchExperiments)
+	experimentsNotModified := errors.Is(err, auroratype.ErrNotModified) || (err == nil && experiments == nil)
+	if err != nil && !experimentsNotModified {
+		w.recordTimeout(ctx, err)
+		return fetchedContent{}, false, err
+	}
+	content.experiments = experiments
+	if experimentsNotModified {
+		content.experiments = previous.experiments
+	}
+
+	if resolver, ok := w.fetcher.(auroratype.EnvironmentResolver); ok {
+		content.environments = resolver.ResolvedEnvironments()
+	}
+
+	unchanged = parametersNotModified && experimentsNotModified && previous.version != ""
+	return content, unchanged, nil
+}
+
+func (w *fetcherStorage) recordTimeout(ctx context.Context, err error) {
+	// a cancelled parent context is not a fetch timeout
+	if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+		w.recorder.Count(MetricFetchTimeoutTotal, 1, nil)
+	}
+}
+
+func (w *fetcherStorage) breakerChanged(state breakerState) {
+	if state == "" {
+		return
+	}
+	if state == breakerOpen {
+		w.logger.Warn("Circuit breaker opened, pausing fetches", "duration", w.breaker.openFor)
+	} else {
+		w.logger.Info("Circuit breaker state changed", "state", state)
+	}
+	w.recorder.Count(MetricCircuitBreakerTransitionTotal, 1, []string{"state:" + string(state)})
+}
 
-	if experiments != nil {
-		err = w.strategy.SaveExperiments(ctx, experiments)
+func (w *fetcherStorage) validate(snapshot *auroratype.Snapshot) error {
+	if !w.validateSnapshots {
+		return nil
+	}
+	return validateSnapshot(snapshot, w.validateTypes, w.parameterOperators, w.experimentOperators)
+}
+
+// validateEnvironments validates the content a fetcher resolved for each of
+// its environments, since only the active one reaches the storage.
+func (w *fetcherStorage) validateEnvironments(ctx context.Context, environments map[string]auroratype.EnvironmentContent) error {
+	if !w.validateSnapshots {
+		return nil
+	}
+	var errs []error
+	for _, env := range slices.Sorted(maps.Keys(environments)) {
+		content := environments[env]
+		plain, err := w.decrypt(ctx, &auroratype.Snapshot{Parameters: content.Parameters, Experiments: content.Experiments})
+		if err == nil {
+			err = joinValidationErrors(validateContent(plain.Parameters, plain.Experiments, nil, w.validateTypes, w.parameterOperators, w.experimentOperators))
+		}
 		if err != nil {
-			w.recorder.Count(MetricStorageSyncTotal, 1, []string{"status:error"})
-			return err
+			errs = append(errs, fmt.Errorf("environment %q: %w", env, err))
 		}
 	}
+	return errors.Join(errs...)
+}
+
+func (w *fetcherStorage) recordSync(result SyncResult) {
+	result.Time = time.Now()
 
-	w.recorder.Count(MetricStorageSyncTotal, 1, []string{"status:success"})
+	w.statusMu.Lock()
+	w.lastSync = result
+	if result.Err == nil {
+		w.lastSuccess = result.Time
+		w.failures = 0
+	} else {
+		w.lastFailure = result.Time
+		w.lastErr = result.Err
+		w.failures++
+	}
+	w.statusMu.Unlock()
+
+	status := "success"
+	switch {
+	case result.Rejected:
+		status = "rejected"
+	case result.Err != nil:
+		status = "error"
+	case result.NotModified:
+		status = "not_modified"
+	}
+	w.recorder.Count(MetricStorageSyncTotal, 1, []string{"status:" + status})
+}
+
+// LastSync returns the outcome of the most recent sync. The zero value is
+// returned before the first sync.
+func (w *fetcherStorage) LastSync() SyncResult {
+	w.statusMu.Lock()
+	defer w.statusMu.Unlock()
+	return w.lastSync
+}
+
+func (w *fetcherStorage) Status() Status {
+	w.mu.Lock()
+	closed := w.closed
+	w.mu.Unlock()
+
+	var version string
+	if snapshot, err := w.LoadSnapshot(context.Background()); err == nil && snapshot != nil {
+		version = snapshot.Version
+	}
+
+	stale := w.stale.Load()
+
+	w.statusMu.Lock()
+	defer w.statusMu.Unlock()
+	return Status{
+		Ready:               (!w.lastSuccess.IsZero() || stale) && !closed,
+		Stale:               stale,
+		Closed:              closed,
+		Version:             version,
+		LastSuccess:         w.lastSuccess,
+		LastFailure:         w.lastFailure,
+		LastError:           w.lastErr,
+		ConsecutiveFailures: w.failures,
+	}
+}
+
+// apply saves snapshot and publishes the changes relative to the snapshot
+// it replaces.
+func (w *fetcherStorage) apply(ctx context.Context, snapshot *auroratype.Snapshot) error {
+	previous, _ := w.LoadSnapshot(ctx)
+
+	if err := w.SaveSnapshot(ctx, snapshot); err != nil {
+		return err
+	}
+
+	changes := diffSnapshots(previous, snapshot)
+	if !changes.Empty() {
+		w.logger.Info("Configuration changed", "version", changes.Version, "previousVersion", changes.PreviousVersion)
+		w.notifier.publish(changes)
+	}
 	return nil
 }
 
-func (w *fetcherStorage) poll(ctx context.Context) {
-	ticker := time.NewTicker(w.interval)
-	defer ticker.Stop()
+// Stale reports whether the storage serves a cached snapshot because no
+// sync has succeeded since startup.
+func (w *fetcherStorage) Stale() bool {
+	return w.stale.Load()
+}
+
+func (w *fetcherStorage) writeCache(snapshot *auroratype.Snapshot) {
+	if w.cache.Path == "" {
+		return
+	}
+	if err := writeCache(w.cache.Path, snapshot); err != nil {
+		w.logger.Error("Failed to write cache", "path", w.cache.Path, "error", err)
+		w.recorder.Count(MetricCacheWriteTotal, 1, []string{"status:error"})
+		return
+	}
+	w.recorder.Count(MetricCacheWriteTotal, 1, []string{"status:success"})
+}
+
+func (w *fetcherStorage) loadCache(ctx context.Context) bool {
+	if w.cache.Path == "" {
+		return false
+	}
+
+	snapshot, err := readCache(w.cache.Path, w.cache.MaxAge, time.Now())
+	if err != nil {
+		w.logger.Warn("Failed to load cache", "path", w.cache.Path, "error", err)
+		w.recorder.Count(MetricCacheLoadTotal, 1, []string{"status:error"})
+		return false
+	}
+
+	plain, err := w.decrypt(ctx, snapshot)
+	if err == nil {
+		err = w.validate(plain)
+	}
+	if err != nil {
+		w.logger.Warn("Cached snapshot is invalid", "path", w.cache.Path, "error", err)
+		w.recorder.Count(MetricCacheLoadTotal, 1, []string{"status:error"})
+		return false
+	}
+
+	if err := w.apply(ctx, plain); err != nil {
+		w.logger.Error("Failed to restore cached snapshot", "path", w.cache.Path, "error", err)
+		w.recorder.Count(MetricCacheLoadTotal, 1, []string{"status:error"})
+		return false
+	}
+	// the cache holds the content as fetched, which the served snapshot
+	// no longer does once decrypted
+	w.fetched = &fetchedContent{parameters: snapshot.Parameters, experiments: snapshot.Experiments, sources: snapshot.Sources, version: snapshot.Version}
+	w.fetchedSnapshot.Store(snapshot)
+
+	w.stale.Store(true)
+	w.recorder.Count(MetricCacheLoadTotal, 1, []string{"status:success"})
+	w.reportCacheAge(snapshot)
+	return true
+}
+
+func (w *fetcherStorage) reportCacheAge(snapshot *auroratype.Snapshot) {
+	metric := w.cache.AgeMetric
+	if metric == "" {
+		metric = MetricCacheAge
+	}
+	w.recorder.Histogram(metric, time.Since(snapshot.FetchedAt).Seconds(), []string{"unit:seconds"})
+}
+
+// poll syncs every interval and whenever changes receives a notification.
+// A nil or closed changes channel means the fetcher is not being watched.
+func (w *fetcherStorage) poll(ctx context.Context, changes <-chan struct{}) {
+	timer := time.NewTimer(jittered(w.interval, w.jitter))
+	defer timer.Stop()
 
 	for {
 		select {
 		case <-ctx.Done():
 			return
-		case <-ticker.C:
-			_ = w.sync(ctx)
+		case _, ok := <-changes:
+			if !ok {
+				changes = nil
+				if w.fetcher.IsStatic() && !w.stale.Load() {
+					return
+				}
+				continue
+			}
+			w.logger.Debug("Source changed, syncing")
+			w.resync(ctx)
+		case <-timer.C:
+			timer.Reset(jittered(w.interval, w.jitter))
+			if err := w.refresh(ctx); err != nil && w.stale.Load() {
+				if snapshot, err := w.LoadSnapshot(ctx); err == nil && snapshot != nil {
+					w.reportCacheAge(snapshot)
+				}
+				continue
+			}
+			if w.fetcher.IsStatic() && !w.stale.Load() {
+				if changes == nil {
+					return
+				}
+				// keep syncing on notifications only
+				timer.Stop()
+			}
 		}
 	}
 }
@@ -142,3 +681,37 @@ func (w *fetcherStorage) GetExperiments(ctx context.Context) ([]auroratype.Exper
 func (w *fetcherStorage) SaveExperiments(ctx context.Context, experiments []auroratype.Experiment) error {
 	return w.strategy.SaveExperiments(ctx, experiments)
 }
+
+// SaveSnapshot stores snapshot in the strategy. Strategies that do not
+// implement SnapshotStorage receive parameters and experiments separately.
+func (w *fetcherStorage) SaveSnapshot(ctx context.Context, snapshot *auroratype.Snapshot) error {
+	if ss, ok := w.strategy.(SnapshotStorage); ok {
+		if err := ss.SaveSnapshot(ctx, snapshot); err != nil {
+			return err
+		}
+		w.snapshot.Store(snapshot)
+		return nil
+	}
+
+	if err := w.strategy.Save(ctx, snapshot.Parameters); err != nil {
+		return err
+	}
+	if err := w.strategy.SaveExperiments(ctx, snapshot.Experiments); err != nil {
+		return err
+	}
+	w.snapshot.Store(snapshot)
+	return nil
+}
+
+func (w *fetcherStorage) LoadSnapshot(ctx context.Context) (*auroratype.Snapshot, error) {
+	if ss, ok := w.strategy.(SnapshotStorage); ok {
+		return ss.LoadSnapshot(ctx)
+	}
+	return w.snapshot.Load(), nil
+}
+
+// LoadFetchedSnapshot returns the served snapshot as fetched, or nil before
+// the first sync.
+func (w *fetcherStorage) LoadFetchedSnapshot() *auroratype.Snapshot {
+	return w.fetchedSnapshot.Load()
+}
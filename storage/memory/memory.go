@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/tuannguyensn2001/aurora-go/auroratype"
@@ -14,19 +15,20 @@ type MetricsRecorder interface {
 	Histogram(metricName string, value float64, tags []string)
 }
 
+// Storage keeps the current snapshot behind an atomic pointer. Readers never
+// block and always see parameters and experiments from the same snapshot.
 type Storage struct {
-	config      map[string]auroratype.Parameter
-	experiments []auroratype.Experiment
-	mu          sync.RWMutex
-	recorder    MetricsRecorder
+	snapshot atomic.Pointer[auroratype.Snapshot]
+	mu       sync.Mutex
+	recorder MetricsRecorder
 }
 
 func NewStorage() *Storage {
-	return &Storage{
-		config:      make(map[string]auroratype.Parameter),
-		experiments: make([]auroratype.Experiment, 0),
-		recorder:    &noopRecorder{},
+	s := &Storage{
+		recorder: &noopRecorder{},
 	}
+	s.snapshot.Store(auroratype.NewSnapshot(nil, make([]auroratype.Experiment, 0), "", time.Time{}))
+	return s
 }
 
 type noopRecorder struct{}
@@ -44,7 +46,8 @@ func (m *Storage) Save(ctx context.Context, config map[string]auroratype.Paramet
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	m.config = config
+	current := m.snapshot.Load()
+	m.snapshot.Store(auroratype.NewSnapshot(config, current.Experiments, current.Source, time.Now()))
 	return nil
 }
 
@@ -55,10 +58,7 @@ func (m *Storage) Get(ctx context.Context, parameterName string) (auroratype.Par
 		m.recorder.Histogram("storage_get_latency", duration, nil)
 	}()
 
-	m.mu.RLock()
-	defer m.mu.RUnlock()
-
-	val, ok := m.config[parameterName]
+	val, ok := m.snapshot.Load().Parameter(parameterName)
 	if !ok {
 		m.recorder.Count("storage_get_total", 1, []string{"status:miss"})
 		return auroratype.Parameter{}, errors.New("parameter not found")
@@ -72,15 +72,36 @@ func (m *Storage) SaveExperiments(ctx context.Context, experiments []auroratype.
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	m.experiments = experiments
+	current := m.snapshot.Load()
+	m.snapshot.Store(auroratype.NewSnapshot(current.Parameters, experiments, current.Source, time.Now()))
 	return nil
 }
 
 func (m *Storage) GetExperiments(ctx context.Context) ([]auroratype.Experiment, error) {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
+	return m.snapshot.Load().Experiments, nil
+}
+
+// SaveSnapshot replaces parameters and experiments in a single step.
+func (m *Storage) SaveSnapshot(ctx context.Context, snapshot *auroratype.Snapshot) error {
+	if snapshot == nil {
+		return errors.New("snapshot cannot be nil")
+	}
+
+	start := time.Now()
+	defer func() {
+		duration := float64(time.Since(start).Nanoseconds())
+		m.recorder.Histogram("storage_save_latency", duration, nil)
+	}()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.snapshot.Store(snapshot)
+	return nil
+}
 
-	return m.experiments, nil
+func (m *Storage) LoadSnapshot(ctx context.Context) (*auroratype.Snapshot, error) {
+	return m.snapshot.Load(), nil
 }
 
 func NewStrategy() *Storage {
@@ -0,0 +1,361 @@
+package core
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tuannguyensn2001/aurora-go/auroratype"
+	"github.com/tuannguyensn2001/aurora-go/experiment"
+	mocks "github.com/tuannguyensn2001/aurora-go/mocks"
+	"github.com/tuannguyensn2001/aurora-go/storage/memory"
+)
+
+func TestFetcherStorageSyncIsAtomic(t *testing.T) {
+	ctx := context.Background()
+	v1 := map[string]auroratype.Parameter{"limit": {DefaultValue: 1}}
+	v2 := map[string]auroratype.Parameter{"limit": {DefaultValue: 2}}
+	experiments := []auroratype.Experiment{{
+		ID:             "exp_001",
+		Name:           "Limit",
+		Parameters:     []string{"limit"},
+		HashAttribute:  "userID",
+		PopulationSize: 100,
+		Variants: []auroratype.Variant{
+			{Key: "control", Rollout: 100, Values: map[string]interface{}{"limit": 5}},
+		},
+	}}
+
+	mockFetcher := new(mocks.MockFetcher)
+	mockFetcher.On("IsStatic").Return(true)
+	mockFetcher.On("Fetch", ctx).Return(v1, nil).Once()
+	mockFetcher.On("FetchExperiments", ctx).Return(experiments, nil).Once()
+
+	s := NewFetcherStorage(mockFetcher)
+	require.NoError(t, s.Start(ctx))
+
+	first, err := s.LoadSnapshot(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, first.Parameters["limit"].DefaultValue)
+	assert.Len(t, first.Experiments, 1)
+	assert.NotEmpty(t, first.Version)
+	assert.False(t, first.FetchedAt.IsZero())
+
+	mockFetcher.On("Fetch", ctx).Return(v2, nil).Once()
+	mockFetcher.On("FetchExperiments", ctx).Return(nil, assert.AnError).Once()
+
+	assert.Error(t, s.sync(ctx))
+
+	current, err := s.LoadSnapshot(ctx)
+	require.NoError(t, err)
+	assert.Same(t, first, current)
+
+	param, err := s.Get(ctx, "limit")
+	require.NoError(t, err)
+	assert.Equal(t, 1, param.DefaultValue)
+
+	mockFetcher.On("Fetch", ctx).Return(v2, nil).Once()
+	mockFetcher.On("FetchExperiments", ctx).Return(nil, nil).Once()
+
+	require.NoError(t, s.sync(ctx))
+
+	current, err = s.LoadSnapshot(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, current.Parameters["limit"].DefaultValue)
+	assert.Len(t, current.Experiments, 1, "experiments are kept when the fetcher has none")
+	assert.NotEqual(t, first.Version, current.Version)
+}
+
+func TestFetcherStorageRejectsInvalidSnapshot(t *testing.T) {
+	ctx := context.Background()
+	percentage := 150
+	hashAttribute := "userID"
+	valid := map[string]auroratype.Parameter{"limit": {DefaultValue: 1}}
+	invalid := map[string]auroratype.Parameter{
+		"limit": {
+			DefaultValue: 2,
+			Rules: []auroratype.Rule{
+				{RolloutValue: 3, Percentage: &percentage, HashAttribute: &hashAttribute},
+			},
+		},
+	}
+
+	mockFetcher := new(mocks.MockFetcher)
+	mockFetcher.On("IsStatic").Return(true)
+	mockFetcher.On("Fetch", ctx).Return(valid, nil).Once()
+	mockFetcher.On("FetchExperiments", ctx).Return(nil, nil)
+
+	s := NewFetcherStorage(mockFetcher)
+	assert.Equal(t, SyncResult{}, s.LastSync())
+	require.NoError(t, s.Start(ctx))
+
+	first, err := s.LoadSnapshot(ctx)
+	require.NoError(t, err)
+	assert.NoError(t, s.LastSync().Err)
+	assert.Equal(t, first.Version, s.LastSync().Version)
+
+	mockFetcher.On("Fetch", ctx).Return(invalid, nil).Once()
+	err = s.sync(ctx)
+
+	var validationErrors auroratype.ValidationErrors
+	require.ErrorAs(t, err, &validationErrors)
+	require.Len(t, validationErrors.Errors, 1)
+	assert.Equal(t, "percentage", validationErrors.Errors[0].Field)
+
+	current, err := s.LoadSnapshot(ctx)
+	require.NoError(t, err)
+	assert.Same(t, first, current, "the previous snapshot keeps being served")
+
+	result := s.LastSync()
+	assert.True(t, result.Rejected)
+	assert.Error(t, result.Err)
+	assert.NotEqual(t, first.Version, result.Version)
+	assert.False(t, result.Time.IsZero())
+}
+
+func TestFetcherStorageTypeValidation(t *testing.T) {
+	ctx := context.Background()
+	params := map[string]auroratype.Parameter{"limit": {DefaultValue: 10}}
+	experiments := []auroratype.Experiment{{
+		ID:             "exp_001",
+		Name:           "Limit",
+		Parameters:     []string{"limit"},
+		HashAttribute:  "userID",
+		PopulationSize: 100,
+		Status:         auroratype.StatusRunning,
+		Variants:       []auroratype.Variant{{Key: "many", Rollout: 100, Values: map[string]interface{}{"limit": "many"}}},
+	}}
+
+	mockFetcher := new(mocks.MockFetcher)
+	mockFetcher.On("IsStatic").Return(true)
+	mockFetcher.On("Fetch", ctx).Return(params, nil)
+	mockFetcher.On("FetchExperiments", ctx).Return(experiments, nil)
+
+	assert.NoError(t, NewFetcherStorage(mockFetcher).Start(ctx), "variant kinds are not checked by default")
+
+	err := NewFetcherStorage(mockFetcher, WithTypeValidation(true)).Start(ctx)
+	var expErrors experiment.ValidationErrors
+	require.ErrorAs(t, err, &expErrors)
+	assert.Contains(t, err.Error(), "variants[0].values.limit")
+}
+
+func TestFetcherStorageStartFailsOnInvalidSnapshot(t *testing.T) {
+	ctx := context.Background()
+	experiments := []auroratype.Experiment{{ID: "exp_001"}}
+
+	mockFetcher := new(mocks.MockFetcher)
+	mockFetcher.On("IsStatic").Return(true)
+	mockFetcher.On("Fetch", ctx).Return(map[string]auroratype.Parameter{}, nil)
+	mockFetcher.On("FetchExperiments", ctx).Return(experiments, nil)
+
+	s := NewFetcherStorage(mockFetcher)
+	var validationErrors experiment.ValidationErrors
+	assert.ErrorAs(t, s.Start(ctx), &validationErrors)
+
+	s = NewFetcherStorage(mockFetcher, WithSnapshotValidation(false))
+	assert.NoError(t, s.Start(ctx))
+}
+
+type countingStorage struct {
+	Storage
+	saves int
+}
+
+func (s *countingStorage) Save(ctx context.Context, config map[string]auroratype.Parameter) error {
+	s.saves++
+	return s.Storage.Save(ctx, config)
+}
+
+func TestFetcherStorageSkipsUnchangedSnapshot(t *testing.T) {
+	ctx := context.Background()
+	v1 := map[string]auroratype.Parameter{"limit": {DefaultValue: 1}}
+	v2 := map[string]auroratype.Parameter{"limit": {DefaultValue: 2}}
+
+	mockFetcher := new(mocks.MockFetcher)
+	mockFetcher.On("IsStatic").Return(true)
+	mockFetcher.On("Fetch", ctx).Return(v1, nil).Once()
+	mockFetcher.On("FetchExperiments", ctx).Return(nil, nil)
+
+	strategy := &countingStorage{Storage: memory.NewStorage()}
+
+	s := NewFetcherStorage(mockFetcher, WithStorage(strategy))
+	require.NoError(t, s.Start(ctx))
+	first, err := s.LoadSnapshot(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, strategy.saves)
+	s.notifier.wait()
+
+	var changes []ChangeSet
+	s.notifier.subscribe(func(c ChangeSet) { changes = append(changes, c) })
+
+	mockFetcher.On("Fetch", ctx).Return(nil, auroratype.ErrNotModified).Once()
+	require.NoError(t, s.sync(ctx))
+	s.notifier.wait()
+
+	assert.Equal(t, 1, strategy.saves)
+	assert.Empty(t, changes)
+	current, err := s.LoadSnapshot(ctx)
+	require.NoError(t, err)
+	assert.Same(t, first, current)
+	assert.True(t, s.LastSync().NotModified)
+	assert.Equal(t, first.Version, s.LastSync().Version)
+
+	mockFetcher.On("Fetch", ctx).Return(v2, nil).Once()
+	require.NoError(t, s.sync(ctx))
+	assert.Equal(t, 2, strategy.saves)
+	assert.False(t, s.LastSync().NotModified)
+}
+
+func TestFetcherStorageKeepsRejectingUnchangedSnapshot(t *testing.T) {
+	ctx := context.Background()
+	percentage := 150
+	invalid := map[string]auroratype.Parameter{
+		"limit": {DefaultValue: 1, Rules: []auroratype.Rule{{RolloutValue: 2, Percentage: &percentage}}},
+	}
+
+	mockFetcher := new(mocks.MockFetcher)
+	mockFetcher.On("IsStatic").Return(true)
+	mockFetcher.On("Fetch", ctx).Return(map[string]auroratype.Parameter{}, nil).Once()
+	mockFetcher.On("FetchExperiments", ctx).Return(nil, nil)
+
+	s := NewFetcherStorage(mockFetcher)
+	require.NoError(t, s.Start(ctx))
+
+	mockFetcher.On("Fetch", ctx).Return(invalid, nil).Once()
+	assert.Error(t, s.sync(ctx))
+
+	mockFetcher.On("Fetch", ctx).Return(nil, auroratype.ErrNotModified).Once()
+	err := s.sync(ctx)
+	var validationErrors auroratype.ValidationErrors
+	assert.ErrorAs(t, err, &validationErrors)
+	assert.True(t, s.LastSync().Rejected)
+}
+
+type watchingFetcher struct {
+	*countingFetcher
+	notify chan func()
+}
+
+func (f *watchingFetcher) Watch(ctx context.Context, notify func()) error {
+	f.notify <- notify
+	<-ctx.Done()
+	return nil
+}
+
+func TestFetcherStorageSyncsOnWatchNotification(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	fetcher := &watchingFetcher{countingFetcher: &countingFetcher{static: true}, notify: make(chan func(), 1)}
+	s := NewFetcherStorage(fetcher, WithInterval(time.Hour))
+	require.NoError(t, s.Start(ctx))
+	assert.EqualValues(t, 1, fetcher.calls.Load())
+
+	notify := <-fetcher.notify
+	notify()
+	assert.Eventually(t, func() bool { return fetcher.calls.Load() == 2 }, time.Second, time.Millisecond)
+
+	require.NoError(t, s.Close(ctx))
+}
+
+// slowWatchingFetcher reads its value when a fetch starts, and holds the
+// fetch until released when gated.
+type slowWatchingFetcher struct {
+	value   atomic.Int32
+	calls   atomic.Int32
+	gate    atomic.Pointer[chan struct{}]
+	notify  chan func()
+	fetched chan struct{}
+}
+
+func (f *slowWatchingFetcher) Fetch(ctx context.Context) (map[string]auroratype.Parameter, error) {
+	value := f.value.Load()
+	f.calls.Add(1)
+	if gate := f.gate.Load(); gate != nil {
+		f.fetched <- struct{}{}
+		<-*gate
+	}
+	return map[string]auroratype.Parameter{"limit": {DefaultValue: int(value)}}, nil
+}
+
+func (f *slowWatchingFetcher) FetchExperiments(ctx context.Context) ([]auroratype.Experiment, error) {
+	return nil, nil
+}
+
+func (f *slowWatchingFetcher) IsStatic() bool {
+	return true
+}
+
+func (f *slowWatchingFetcher) Watch(ctx context.Context, notify func()) error {
+	f.notify <- notify
+	<-ctx.Done()
+	return nil
+}
+
+func TestFetcherStorageWatchNotificationDuringSync(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	fetcher := &slowWatchingFetcher{notify: make(chan func(), 1), fetched: make(chan struct{}, 1)}
+	fetcher.value.Store(1)
+	client := NewClient(NewFetcherStorage(fetcher, WithInterval(time.Hour)), ClientOptions{})
+	require.NoError(t, client.Start(ctx))
+	notify := <-fetcher.notify
+
+	gate := make(chan struct{})
+	fetcher.gate.Store(&gate)
+	refreshed := make(chan error, 1)
+	go func() { refreshed <- client.Refresh(ctx) }()
+	<-fetcher.fetched
+
+	// the source changes after the running sync read it
+	fetcher.value.Store(2)
+	fetcher.gate.Store(nil)
+	notify()
+	// let the notification reach the poll loop while the sync is running
+	time.Sleep(20 * time.Millisecond)
+	close(gate)
+	require.NoError(t, <-refreshed)
+
+	assert.Eventually(t, func() bool {
+		return client.GetParameter(ctx, "limit", NewAttribute()).Value() == 2
+	}, time.Second, time.Millisecond, "the change must be applied without waiting for a poll")
+	require.NoError(t, client.Close(ctx))
+}
+
+// resolvingFetcher serves the active environment and reports content
+// resolved for every environment.
+type resolvingFetcher struct {
+	*countingFetcher
+	environments map[string]auroratype.EnvironmentContent
+}
+
+func (f *resolvingFetcher) ResolvedEnvironments() map[string]auroratype.EnvironmentContent {
+	return f.environments
+}
+
+func TestFetcherStorageValidatesResolvedEnvironments(t *testing.T) {
+	ctx := context.Background()
+	fetcher := &resolvingFetcher{
+		countingFetcher: &countingFetcher{static: true},
+		environments: map[string]auroratype.EnvironmentContent{
+			"prod": {Parameters: map[string]auroratype.Parameter{"limit": {DefaultValue: 1}}},
+			"staging": {Parameters: map[string]auroratype.Parameter{"limit": {
+				DefaultValue: 1,
+				Rules:        []auroratype.Rule{{RolloutValue: 2, Constraints: []auroratype.Constraint{{Field: "plan", Operator: "resembles", Value: "pro"}}}},
+			}}},
+		},
+	}
+	client := NewClient(NewFetcherStorage(fetcher), ClientOptions{})
+
+	err := client.Start(ctx)
+	var validationErrors auroratype.ValidationErrors
+	require.ErrorAs(t, err, &validationErrors)
+	assert.Contains(t, err.Error(), `environment "staging"`)
+	assert.Contains(t, err.Error(), `unknown operator "resembles"`)
+	assert.True(t, client.LastSync().Rejected, "an inactive environment must be valid to serve the active one")
+}
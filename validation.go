@@ -0,0 +1,116 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/tuannguyensn2001/aurora-go/auroratype"
+	"github.com/tuannguyensn2001/aurora-go/core/evaluator"
+	"github.com/tuannguyensn2001/aurora-go/experiment"
+)
+
+// operatorSet reports whether a constraint operator can be evaluated.
+type operatorSet func(name evaluator.Operator) bool
+
+// validateSnapshot runs the parameter and experiment validators and checks
+// every constraint operator against the registered ones. Operators are only
+// checked once a client has attached its registry; a nil set skips the
+// check. Variant values are checked against the parameter defaults when
+// checkTypes is set. The returned error
+// joins an auroratype.ValidationErrors and an experiment.ValidationErrors,
+// whichever are non-empty, so callers can inspect either with errors.As.
+//
+// A snapshot with environment sections is also validated as resolved for
+// each environment. Errors that only occur in an environment are wrapped
+// with its name.
+func validateSnapshot(snapshot *auroratype.Snapshot, checkTypes bool, parameterOperators, experimentOperators operatorSet) error {
+	paramErrors, expErrors := validateContent(snapshot.Parameters, snapshot.Experiments, snapshot.Sources, checkTypes, parameterOperators, experimentOperators)
+	errs := []error{joinValidationErrors(paramErrors, expErrors)}
+
+	seen := make(map[string]bool)
+	for _, err := range paramErrors {
+		seen[err.Error()] = true
+	}
+	for _, err := range expErrors {
+		seen[err.Error()] = true
+	}
+	for _, env := range auroratype.Environments(snapshot.Parameters, snapshot.Experiments) {
+		envParamErrors, envExpErrors := validateContent(
+			auroratype.ResolveParameters(snapshot.Parameters, env),
+			auroratype.ResolveExperiments(snapshot.Experiments, env),
+			snapshot.Sources, checkTypes, parameterOperators, experimentOperators)
+		if err := joinValidationErrors(unseen(envParamErrors, seen), unseen(envExpErrors, seen)); err != nil {
+			errs = append(errs, fmt.Errorf("environment %q: %w", env, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func validateContent(parameters map[string]auroratype.Parameter, experiments []auroratype.Experiment, sources map[string]string, checkTypes bool, parameterOperators, experimentOperators operatorSet) ([]auroratype.ValidationError, []experiment.ValidationError) {
+	paramErrors := auroratype.ValidateConfig(parameters)
+	for name, param := range parameters {
+		for i, rule := range param.Rules {
+			for j, constraint := range rule.Constraints {
+				if parameterOperators == nil || constraint.Operator == "" || parameterOperators(evaluator.Operator(constraint.Operator)) {
+					continue
+				}
+				paramErrors = append(paramErrors, auroratype.ValidationError{
+					Parameter: name,
+					RuleIndex: i,
+					Field:     fmt.Sprintf("constraints[%d].operator", j),
+					Message:   fmt.Sprintf("unknown operator %q", constraint.Operator),
+				})
+			}
+		}
+	}
+
+	for i := range paramErrors {
+		paramErrors[i].Source = sources[paramErrors[i].Parameter]
+	}
+
+	expErrors := experiment.ValidateExperiments(experiments)
+	if checkTypes {
+		expErrors = experiment.ValidateExperimentsWithParameters(experiments, parameters)
+	}
+	for _, exp := range experiments {
+		for j, constraint := range exp.Constraints {
+			if experimentOperators == nil || constraint.Operator == "" || experimentOperators(evaluator.Operator(constraint.Operator)) {
+				continue
+			}
+			expErrors = append(expErrors, experiment.ValidationError{
+				Experiment: exp.ID,
+				Field:      fmt.Sprintf("constraints[%d].operator", j),
+				Message:    fmt.Sprintf("unknown operator %q", constraint.Operator),
+			})
+		}
+	}
+	return paramErrors, expErrors
+}
+
+// joinValidationErrors returns the non-empty error lists joined, or nil.
+func joinValidationErrors(paramErrors []auroratype.ValidationError, expErrors []experiment.ValidationError) error {
+	var errs []error
+	if len(paramErrors) > 0 {
+		errs = append(errs, auroratype.ValidationErrors{Errors: paramErrors})
+	}
+	if len(expErrors) > 0 {
+		errs = append(errs, experiment.ValidationErrors{Errors: expErrors})
+	}
+	return errors.Join(errs...)
+}
+
+func unseen[E error](errs []E, seen map[string]bool) []E {
+	var filtered []E
+	for _, err := range errs {
+		if !seen[err.Error()] {
+			filtered = append(filtered, err)
+		}
+	}
+	return filtered
+}
+
+func isValidationError(err error) bool {
+	var paramErrors auroratype.ValidationErrors
+	var expErrors experiment.ValidationErrors
+	return errors.As(err, &paramErrors) || errors.As(err, &expErrors)
+}
@@ -1,8 +1,45 @@
 package core
 
+import "log/slog"
+
+type Reason string
+
+// redacted replaces encrypted values wherever they would be shown.
+const redacted = "[REDACTED]"
+
+const (
+	ReasonExperiment Reason = "experiment"
+	// ReasonForced marks a variant that came from a forced assignment.
+	ReasonForced Reason = "forced"
+	// ReasonWinner marks the winning variant of a finished experiment.
+	ReasonWinner Reason = "winner"
+	// ReasonOverride marks a value supplied through WithOverride.
+	ReasonOverride  Reason = "override"
+	ReasonRuleMatch Reason = "rule_match"
+	ReasonDefault   Reason = "default"
+	ReasonNotFound  Reason = "not_found"
+)
+
+// EvaluationDetails explains how a value was resolved.
+type EvaluationDetails struct {
+	Reason       Reason
+	ExperimentID string
+	VariantKey   string
+	// Weights are the bandit weights in effect when the variant was assigned.
+	Weights map[string]float64
+	// Source is where the parameter was defined, when the fetcher reports
+	// it.
+	Source string
+	// Encrypted is set when the parameter holds encrypted values. The
+	// value must not be logged; a resolved value logged with slog is
+	// redacted.
+	Encrypted bool
+}
+
 type resolvedValue struct {
 	value   any
 	matched bool
+	details EvaluationDetails
 }
 
 func NewResolvedValue(value any, matched bool) *resolvedValue {
@@ -85,3 +122,20 @@ func (r *resolvedValue) Value() any {
 func (r *resolvedValue) Matched() bool {
 	return r.matched
 }
+
+func (r *resolvedValue) Details() EvaluationDetails {
+	return r.details
+}
+
+// LogValue implements slog.LogValuer, redacting encrypted values.
+func (r *resolvedValue) LogValue() slog.Value {
+	if r.details.Encrypted {
+		return slog.StringValue(redacted)
+	}
+	return slog.AnyValue(r.value)
+}
+
+func (r *resolvedValue) withDetails(details EvaluationDetails) *resolvedValue {
+	r.details = details
+	return r
+}